@@ -0,0 +1,172 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// ReadFiles downloads the named objects concurrently using at most
+// concurrency parallel requests. It returns the content of every object that
+// was read successfully and the error for every object that was not.
+//
+// Cancelling ctx aborts the whole batch; objects that were not downloaded yet
+// are reported with the context's error.
+func (f *S3FS) ReadFiles(ctx context.Context, names []string, concurrency int) (map[string][]byte, map[string]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		data = make(map[string][]byte, len(names))
+		errs = make(map[string]error)
+		jobs = make(chan string)
+	)
+
+	setErr := func(name string, err error) {
+		errs[name] = &fs.PathError{
+			Op:   "readfile",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	for i := 0; i < concurrency && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				if err := ctx.Err(); err != nil {
+					mu.Lock()
+					setErr(name, err)
+					mu.Unlock()
+					continue
+				}
+
+				b, err := f.readObject(ctx, name)
+
+				mu.Lock()
+				if err != nil {
+					setErr(name, err)
+				} else {
+					data[name] = b
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, name := range names {
+		select {
+		case jobs <- name:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, name := range names {
+		if _, ok := data[name]; ok {
+			continue
+		}
+		if _, ok := errs[name]; !ok {
+			setErr(name, ctx.Err())
+		}
+	}
+
+	return data, errs
+}
+
+// readObject downloads the whole object name with a single GetObject.
+func (f *S3FS) readObject(ctx context.Context, name string) ([]byte, error) {
+	return f.readObjectLimit(ctx, name, -1)
+}
+
+// readObjectLimit is like readObject, but fails with ErrTooLarge if the
+// object is larger than maxBytes. A negative maxBytes means no limit.
+func (f *S3FS) readObjectLimit(ctx context.Context, name string, maxBytes int64) ([]byte, error) {
+	data, _, err := f.readObjectETag(ctx, name, maxBytes)
+	return data, err
+}
+
+// readObjectETag is like readObjectLimit, but also returns the ETag of the
+// object.
+func (f *S3FS) readObjectETag(ctx context.Context, name string, maxBytes int64) ([]byte, string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, "", fs.ErrInvalid
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return nil, "", err
+	}
+
+	out, err := f.fetchObject(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	var r io.Reader = out.Body
+	if f.decryption != nil {
+		// reading more than maxBytes and the tag of the ciphertext tells
+		// that the plaintext is too large.
+		var body io.Reader = out.Body
+		lr := &io.LimitedReader{R: out.Body, N: maxBytes + gcmTagSize + 1}
+		if maxBytes >= 0 {
+			body = lr
+		}
+		data, encrypted, err := f.decryption.decrypt(ctx, name, out.Metadata, body)
+		if encrypted && maxBytes >= 0 && lr.N == 0 {
+			return nil, "", ErrTooLarge
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if encrypted {
+			r = bytes.NewReader(data)
+			out.ContentLength = int64(len(data))
+		}
+	}
+
+	if maxBytes >= 0 && out.ContentLength > maxBytes {
+		return nil, "", ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	if out.ContentLength > 0 {
+		buf.Grow(int(out.ContentLength))
+	}
+	if dec := f.decompressorFor(name, aws.ToString(out.ContentEncoding)); dec != nil {
+		dr, err := dec(r)
+		if err != nil {
+			return nil, "", err
+		}
+		defer dr.Close()
+		r = dr
+	}
+	if maxBytes >= 0 {
+		// the length may be unknown, so do not trust it alone.
+		r = io.LimitReader(r, maxBytes+1)
+	}
+
+	if _, err := io.Copy(&buf, r); err != nil {
+		return nil, "", err
+	}
+	if maxBytes >= 0 && int64(buf.Len()) > maxBytes {
+		return nil, "", ErrTooLarge
+	}
+
+	data := buf.Bytes()
+	if f.stripBOM {
+		data = data[bomLen(data):]
+	}
+	return data, aws.ToString(out.ETag), nil
+}
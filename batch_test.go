@@ -0,0 +1,61 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestReadFiles(t *testing.T) {
+	cl := newMemClient()
+	cl.put("a.txt", []byte("a"))
+	cl.put("dir/b.txt", []byte("b"))
+	cl.put("c.txt", []byte("c"))
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("mixed", func(t *testing.T) {
+		names := []string{"a.txt", "dir/b.txt", "missing.txt", "/invalid", "c.txt"}
+		data, errs := fsys.ReadFiles(context.Background(), names, 2)
+
+		want := map[string]string{"a.txt": "a", "dir/b.txt": "b", "c.txt": "c"}
+		if len(data) != len(want) {
+			t.Errorf("want %d objects; got %d", len(want), len(data))
+		}
+		for name, content := range want {
+			if string(data[name]) != content {
+				t.Errorf("%s: want %q; got %q", name, content, data[name])
+			}
+		}
+
+		if len(errs) != 2 {
+			t.Errorf("want 2 errors; got %d", len(errs))
+		}
+		if !errors.Is(errs["missing.txt"], fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, errs["missing.txt"])
+		}
+		if !errors.Is(errs["/invalid"], fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, errs["/invalid"])
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		names := []string{"a.txt", "dir/b.txt", "c.txt"}
+		data, errs := fsys.ReadFiles(ctx, names, 2)
+
+		if len(data) != 0 {
+			t.Errorf("want no objects; got %d", len(data))
+		}
+		for _, name := range names {
+			if !errors.Is(errs[name], context.Canceled) {
+				t.Errorf("%s: want %v; got %v", name, context.Canceled, errs[name])
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package s3fs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// WithStripBOM strips a leading UTF-8 or UTF-16 byte order mark from the
+// content of objects read as a whole: by ReadFile and by reading an opened
+// file from its start. Ranged reads with ReadAt are left alone.
+//
+// The offset of the file keeps counting bytes of the object, so after the
+// BOM was stripped it is the length of the BOM plus the bytes read, and
+// Stat reports the size of the object including the BOM.
+func WithStripBOM(fsys *S3FS) { fsys.stripBOM = true }
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16BE = []byte{0xfe, 0xff}
+	bomUTF16LE = []byte{0xff, 0xfe}
+)
+
+// bomLen returns the length of the byte order mark data starts with, or 0
+// if it starts with none.
+func bomLen(data []byte) int {
+	for _, bom := range [][]byte{bomUTF8, bomUTF16BE, bomUTF16LE} {
+		if bytes.HasPrefix(data, bom) {
+			return len(bom)
+		}
+	}
+	return 0
+}
+
+// skipBOM drops a byte order mark from the start of the body of the file.
+// The BOM may span several reads of the body.
+func (f *file) skipBOM() error {
+	buf := make([]byte, len(bomUTF8))
+	n, err := io.ReadFull(f.ReadCloser, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	skip := bomLen(buf[:n])
+	f.offset += int64(skip)
+	if rest := buf[skip:n]; len(rest) > 0 {
+		f.ReadCloser = prefixedBody{
+			Reader: io.MultiReader(bytes.NewReader(rest), f.ReadCloser),
+			Closer: f.ReadCloser,
+		}
+	}
+	return nil
+}
+
+// prefixedBody is a body of which some bytes were read ahead.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,76 @@
+package s3fs_test
+
+import (
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestStripBOM(t *testing.T) {
+	cl := newMemClient()
+	cl.put("utf8.txt", []byte("\xef\xbb\xbfhello"))
+	cl.put("utf16.txt", []byte("\xff\xfeh\x00i\x00"))
+	cl.put("plain.txt", []byte("hello"))
+	cl.put("short.txt", []byte("\xef\xbb"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithStripBOM)
+
+	fixtures := map[string]string{
+		"utf8.txt":  "hello",
+		"utf16.txt": "h\x00i\x00",
+		"plain.txt": "hello",
+		"short.txt": "\xef\xbb",
+	}
+
+	for name, want := range fixtures {
+		data, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("ReadFile(%s): want %q; got %q", name, want, data)
+		}
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// reads smaller than the BOM must not see parts of it.
+		data, err = io.ReadAll(iotest.OneByteReader(f))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("Open(%s): want %q; got %q", name, want, data)
+		}
+	}
+
+	t.Run("ReadAt", func(t *testing.T) {
+		f, err := s3fs.New(cl, "test", s3fs.WithStripBOM, s3fs.WithReadSeeker).Open("utf8.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		p := make([]byte, 4)
+		if _, err := f.(io.ReaderAt).ReadAt(p, 0); err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != "\xef\xbb\xbfh" {
+			t.Errorf("want the BOM kept; got %q", p)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		data, err := s3fs.New(cl, "test").ReadFile("utf8.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "\xef\xbb\xbfhello" {
+			t.Errorf("want the content untouched; got %q", data)
+		}
+	})
+}
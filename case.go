@@ -0,0 +1,158 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrAmbiguousName is returned by case-insensitive lookups when more than one
+// key matches the requested name.
+var ErrAmbiguousName = errors.New("ambiguous name")
+
+// WithCaseInsensitive makes Open and Stat fall back to a case-insensitive
+// lookup when name does not exist, so "docs/readme.txt" finds
+// "Docs/README.txt".
+//
+// The fallback lists every directory on the path to find a matching key,
+// which costs one or more ListObjectsV2 calls per path segment on each miss.
+// If two keys differ only by case, the lookup fails with ErrAmbiguousName
+// instead of picking one of them.
+func WithCaseInsensitive(fsys *S3FS) { fsys.caseInsensitive = true }
+
+func (f *S3FS) openCaseInsensitive(ctx context.Context, name string) (fs.File, error) {
+	resolved, err := f.resolveCase(ctx, name)
+	switch {
+	case err != nil:
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	case resolved == name:
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
+	}
+	return f.OpenContext(ctx, resolved)
+}
+
+func (f *S3FS) statCaseInsensitive(ctx context.Context, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, fs.ErrInvalid
+	}
+
+	resolved, err := f.resolveCase(ctx, name)
+	switch {
+	case err != nil:
+		return nil, err
+	case resolved == name:
+		return nil, fs.ErrNotExist
+	}
+	return f.stat(ctx, resolved)
+}
+
+// resolveCase finds the key matching name regardless of case by resolving
+// it segment by segment.
+func (f *S3FS) resolveCase(ctx context.Context, name string) (string, error) {
+	segs := strings.Split(name, "/")
+
+	var resolved string
+	for i, seg := range segs {
+		match, err := f.matchCase(ctx, resolved, seg, i == len(segs)-1)
+		if err != nil {
+			return "", err
+		}
+
+		resolved += match
+		if i < len(segs)-1 {
+			resolved += "/"
+		}
+	}
+	return resolved, nil
+}
+
+// matchCase lists prefix and returns the name of the single entry that is
+// equal to seg under Unicode case folding. Files are only considered if last
+// is true.
+func (f *S3FS) matchCase(ctx context.Context, prefix, seg string, last bool) (string, error) {
+	matches := make(map[string]struct{})
+
+	var (
+		token    *string
+		lastList time.Time
+	)
+	for {
+		if err := f.paceList(ctx, lastList); err != nil {
+			return "", err
+		}
+		lastList = time.Now()
+
+		out, err := f.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			RequestPayer:      f.requestPayer,
+			Delimiter:         aws.String("/"),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+		}, f.optFns...)
+		if err != nil {
+			return "", err
+		}
+		f.auditList(prefix, out)
+
+		for _, p := range out.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(p.Prefix), prefix), "/")
+			if strings.EqualFold(name, seg) {
+				matches[name] = struct{}{}
+			}
+		}
+
+		if last {
+			for _, o := range out.Contents {
+				name := strings.TrimPrefix(aws.ToString(o.Key), prefix)
+				if strings.EqualFold(name, seg) {
+					matches[name] = struct{}{}
+				}
+			}
+		}
+
+		if !out.IsTruncated {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fs.ErrNotExist
+	case 1:
+		for name := range matches {
+			return name, nil
+		}
+	}
+
+	names := make([]string, 0, len(matches))
+	for name := range matches {
+		names = append(names, prefix+name)
+	}
+	sort.Strings(names)
+	return "", &ambiguousNameError{names: names}
+}
+
+type ambiguousNameError struct {
+	names []string
+}
+
+func (e *ambiguousNameError) Error() string {
+	return ErrAmbiguousName.Error() + ": matches " + strings.Join(e.names, ", ")
+}
+
+func (e *ambiguousNameError) Unwrap() error { return ErrAmbiguousName }
@@ -0,0 +1,87 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestCaseInsensitive(t *testing.T) {
+	cl := newMemClient()
+	cl.put("Docs/README.TXT", []byte("readme"))
+	cl.put("Docs/Guides/Intro.md", []byte("intro"))
+	cl.put("dup/a.txt", []byte("a"))
+	cl.put("dup/A.txt", []byte("A"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithCaseInsensitive)
+
+	t.Run("open", func(t *testing.T) {
+		f, err := fsys.Open("docs/readme.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "readme" {
+			t.Errorf("want readme; got %s", data)
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		fi, err := fsys.Stat("docs/guides/INTRO.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Name() != "Intro.md" {
+			t.Errorf("want Intro.md; got %s", fi.Name())
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		fi, err := fsys.Stat("docs/guides")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Error("expected a directory")
+		}
+	})
+
+	t.Run("exact match does not list", func(t *testing.T) {
+		before := cl.count("ListObjectsV2")
+		if _, err := fsys.Stat("Docs/README.TXT"); err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.count("ListObjectsV2") - before; n != 0 {
+			t.Errorf("want 0 ListObjectsV2 calls; got %d", n)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := fsys.Open("dup/a.TXT")
+		if !errors.Is(err, s3fs.ErrAmbiguousName) {
+			t.Errorf("want %v; got %v", s3fs.ErrAmbiguousName, err)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.Stat("docs/missing.txt")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := s3fs.New(cl, "test").Open("docs/readme.txt")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
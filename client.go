@@ -0,0 +1,72 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// *s3.Client is the S3Client used outside of tests.
+var _ S3Client = (*s3.Client)(nil)
+
+// ClientFrom returns c as an S3Client. *s3.Client already satisfies
+// S3Client, so it can be passed to New directly; ClientFrom only makes that
+// explicit.
+func ClientFrom(c *s3.Client) S3Client { return c }
+
+// NewFromClient is like New, but accepts any client and returns an error
+// naming the methods it lacks if it does not implement S3Client. It is
+// meant for clients whose type is only known at runtime, e.g. wrappers and
+// stubs. Where New panics on a nil client or an invalid bucket name,
+// NewFromClient returns an error.
+func NewFromClient(cl interface{}, bucket string, opts ...Option) (*S3FS, error) {
+	if cl == nil {
+		return nil, errors.New("s3fs: nil client")
+	}
+	if !validBucketName(bucket) {
+		return nil, fmt.Errorf("s3fs: invalid bucket name %q", bucket)
+	}
+
+	s3cl, err := asS3Client(cl)
+	if err != nil {
+		return nil, err
+	}
+	return New(s3cl, bucket, opts...), nil
+}
+
+type headObjectAPIClient interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// asS3Client asserts that cl implements S3Client.
+func asS3Client(cl interface{}) (S3Client, error) {
+	if s3cl, ok := cl.(S3Client); ok {
+		return s3cl, nil
+	}
+
+	var missing []string
+	if _, ok := cl.(manager.ListObjectsV2APIClient); !ok {
+		missing = append(missing, "ListObjectsV2")
+	}
+	if _, ok := cl.(manager.DeleteObjectsAPIClient); !ok {
+		missing = append(missing, "DeleteObjects")
+	}
+	if _, ok := cl.(manager.DownloadAPIClient); !ok {
+		missing = append(missing, "GetObject")
+	}
+	if _, ok := cl.(manager.HeadBucketAPIClient); !ok {
+		missing = append(missing, "HeadBucket")
+	}
+	if _, ok := cl.(manager.UploadAPIClient); !ok {
+		missing = append(missing, "PutObject, UploadPart, CreateMultipartUpload, CompleteMultipartUpload and AbortMultipartUpload")
+	}
+	if _, ok := cl.(headObjectAPIClient); !ok {
+		missing = append(missing, "HeadObject")
+	}
+
+	return nil, fmt.Errorf("s3fs: %T does not implement S3Client: missing %s", cl, strings.Join(missing, "; "))
+}
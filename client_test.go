@@ -0,0 +1,110 @@
+package s3fs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readOnlyStub implements the read side of S3Client only.
+type readOnlyStub struct{}
+
+func (readOnlyStub) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+func (readOnlyStub) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{}, nil
+}
+
+func TestNewFromClient(t *testing.T) {
+	if _, err := s3fs.NewFromClient(newMemClient(), "test"); err != nil {
+		t.Errorf("expected a complete client to be accepted; got %v", err)
+	}
+
+	if _, err := s3fs.NewFromClient(s3fs.ClientFrom(s3.New(s3.Options{})), "test"); err != nil {
+		t.Errorf("expected *s3.Client to be accepted; got %v", err)
+	}
+
+	_, err := s3fs.NewFromClient(readOnlyStub{}, "test")
+	if err == nil {
+		t.Fatal("expected a partial stub to be rejected")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"s3fs_test.readOnlyStub", "DeleteObjects", "HeadBucket", "PutObject", "HeadObject"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("want error to mention %s; got %q", want, msg)
+		}
+	}
+	for _, implemented := range []string{"ListObjectsV2", "GetObject"} {
+		if strings.Contains(msg, implemented) {
+			t.Errorf("did not expect error to mention %s; got %q", implemented, msg)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cl     s3fs.S3Client
+		bucket string
+	}{
+		{"nil client", nil, "test"},
+		{"empty bucket", newMemClient(), ""},
+		{"short bucket", newMemClient(), "ab"},
+		{"long bucket", newMemClient(), strings.Repeat("a", 64)},
+		{"uppercase bucket", newMemClient(), "My-Bucket"},
+		{"underscore bucket", newMemClient(), "my_bucket"},
+		{"leading hyphen", newMemClient(), "-bucket"},
+		{"trailing hyphen", newMemClient(), "bucket-"},
+		{"leading dot", newMemClient(), ".bucket"},
+		{"trailing dot", newMemClient(), "bucket."},
+		{"adjacent dots", newMemClient(), "my..bucket"},
+		{"ip address", newMemClient(), "192.168.5.4"},
+		{"bucket with key", newMemClient(), "bucket/key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("expected New to panic")
+					}
+				}()
+				s3fs.New(tt.cl, tt.bucket)
+			}()
+
+			var cl interface{}
+			if tt.cl != nil {
+				cl = tt.cl
+			}
+			if _, err := s3fs.NewFromClient(cl, tt.bucket); err == nil {
+				t.Error("expected NewFromClient to fail")
+			}
+		})
+	}
+}
+
+func TestNewValidBucketName(t *testing.T) {
+	for _, bucket := range []string{
+		"abc",
+		strings.Repeat("a", 63),
+		"my.bucket-1",
+		"my-bucket--usw2-az1--x-s3",
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/my-access-point",
+	} {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		if _, err := s3fs.New(cl, bucket).ReadFile("file.txt"); err != nil {
+			t.Errorf("%s: %v", bucket, err)
+		}
+		if _, err := s3fs.NewFromClient(cl, bucket); err != nil {
+			t.Errorf("%s: %v", bucket, err)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	_ fs.File   = (*concatFile)(nil)
+	_ io.Seeker = (*concatFile)(nil)
+)
+
+// OpenConcat opens all the objects whose keys start with prefix as a single
+// file. This is meant for producers that split large files into parts,
+// e.g. "name.part0001", "name.part0002"; OpenConcat("name.part") then
+// reads them back as one continuous stream.
+//
+// Parts are read in lexical order of their keys. Only objects directly in
+// the prefix's directory are considered. The returned file implements
+// io.Seeker and its size is the sum of the parts' sizes.
+func (f *S3FS) OpenConcat(prefix string) (fs.File, error) {
+	if !fs.ValidPath(prefix) || prefix == "." {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(prefix); err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  err,
+		}
+	}
+
+	cf := &concatFile{
+		fsys: f,
+		name: prefix,
+	}
+
+	var (
+		token *string
+		last  time.Time
+	)
+	for {
+		if err := f.paceList(context.TODO(), last); err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: prefix,
+				Err:  err,
+			}
+		}
+		last = time.Now()
+
+		out, err := f.cl.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			RequestPayer:      f.requestPayer,
+			Delimiter:         aws.String("/"),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+		}, f.optFns...)
+		if err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: prefix,
+				Err:  err,
+			}
+		}
+		f.auditList(prefix, out)
+
+		for _, o := range out.Contents {
+			if o.Key == nil {
+				continue
+			}
+
+			cf.parts = append(cf.parts, concatPart{
+				key:  *o.Key,
+				size: o.Size,
+				eTag: aws.ToString(o.ETag),
+			})
+
+			if mt := derefTime(o.LastModified); mt.After(cf.modTime) {
+				cf.modTime = mt
+			}
+		}
+
+		if !out.IsTruncated {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+
+	if len(cf.parts) == 0 {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	sort.Slice(cf.parts, func(i, j int) bool {
+		return cf.parts[i].key < cf.parts[j].key
+	})
+
+	for i := range cf.parts {
+		cf.parts[i].offset = cf.size
+		cf.size += cf.parts[i].size
+	}
+
+	return cf, nil
+}
+
+type concatPart struct {
+	key    string
+	size   int64
+	eTag   string
+	offset int64
+}
+
+// concatFile presents several objects as a single file.
+type concatFile struct {
+	fsys    *S3FS
+	name    string
+	parts   []concatPart
+	size    int64
+	modTime time.Time
+
+	offset int64
+	body   io.ReadCloser
+	// bodyEnd is the offset at which body is exhausted.
+	bodyEnd int64
+}
+
+func (f *concatFile) Stat() (fs.FileInfo, error) {
+	return &fileInfo{
+		name:    path.Base(f.name),
+		size:    f.size,
+		modTime: f.modTime,
+	}, nil
+}
+
+func (f *concatFile) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		if f.offset >= f.size {
+			return 0, io.EOF
+		}
+		return 0, nil
+	}
+
+	for {
+		if f.offset >= f.size {
+			return 0, io.EOF
+		}
+
+		if f.body == nil {
+			if err := f.openPart(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := f.body.Read(p)
+		f.offset += int64(n)
+
+		if errors.Is(err, io.EOF) || f.offset >= f.bodyEnd {
+			if cerr := f.closeBody(); cerr != nil {
+				return n, cerr
+			}
+			err = nil
+		}
+
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// openPart opens the part containing the current offset.
+func (f *concatFile) openPart() error {
+	i := sort.Search(len(f.parts), func(i int) bool {
+		return f.parts[i].offset+f.parts[i].size > f.offset
+	})
+	part := f.parts[i]
+
+	in := &s3.GetObjectInput{
+		Bucket: &f.fsys.bucket,
+		Key:    aws.String(part.key),
+	}
+	if start := f.offset - part.offset; start > 0 {
+		in.Range = f.fsys.rangeHeader(start, -1)
+	}
+	if part.eTag != "" {
+		in.IfMatch = aws.String(part.eTag)
+	}
+
+	out, err := f.fsys.cl.GetObject(context.TODO(), f.fsys.getInput(in), f.fsys.optFns...)
+	if err != nil {
+		return fmt.Errorf("s3fs: open part %s: %w", part.key, err)
+	}
+
+	if err := f.fsys.skipIgnoredRange(out, f.offset-part.offset); err != nil {
+		return fmt.Errorf("s3fs: open part %s: %w", part.key, err)
+	}
+
+	f.body = out.Body
+	f.bodyEnd = part.offset + part.size
+	return nil
+}
+
+func (f *concatFile) Seek(offset int64, whence int) (int64, error) {
+	newOffset := f.offset
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset += offset
+	case io.SeekEnd:
+		newOffset = f.size + offset
+	default:
+		return 0, errors.New("s3fs.concatFile.Seek: invalid whence")
+	}
+
+	if newOffset < 0 {
+		return 0, errors.New("s3fs.concatFile.Seek: seeked to a negative position")
+	}
+
+	if newOffset != f.offset {
+		if err := f.closeBody(); err != nil {
+			return f.offset, err
+		}
+		f.offset = newOffset
+	}
+	return f.offset, nil
+}
+
+func (f *concatFile) closeBody() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
+
+func (f *concatFile) Close() error { return f.closeBody() }
+
+// OpenMany returns a reader of the objects names, one after the other, in
+// the given order. Each object is streamed from its own GetObject, which is
+// made once the previous object was read to its end, so nothing is
+// buffered and objects are only fetched as they are reached.
+//
+// An object that does not exist fails the Read that reaches it with a
+// *fs.PathError naming it.
+func (f *S3FS) OpenMany(names []string) (io.ReadCloser, error) {
+	for _, name := range names {
+		if !fs.ValidPath(name) || name == "." {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  fs.ErrInvalid,
+			}
+		}
+
+		if err := f.validateKey(name); err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  err,
+			}
+		}
+	}
+	return &manyReader{fsys: f, names: names}, nil
+}
+
+// manyReader reads several objects in sequence.
+type manyReader struct {
+	fsys  *S3FS
+	names []string
+	body  io.ReadCloser
+}
+
+func (r *manyReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if len(r.names) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.body.Read(p)
+		if errors.Is(err, io.EOF) {
+			if cerr := r.closeBody(); cerr != nil {
+				return n, cerr
+			}
+			err = nil
+		}
+
+		if n > 0 || err != nil || len(p) == 0 {
+			return n, err
+		}
+	}
+}
+
+// next opens the next object.
+func (r *manyReader) next() error {
+	name := r.names[0]
+
+	out, err := r.fsys.getObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: &r.fsys.bucket,
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			err = fs.ErrNotExist
+		}
+		return &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  r.fsys.bucketErr(err),
+		}
+	}
+
+	r.names = r.names[1:]
+	r.body = out.Body
+	return nil
+}
+
+func (r *manyReader) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+func (r *manyReader) Close() error {
+	r.names = nil
+	return r.closeBody()
+}
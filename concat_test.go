@@ -0,0 +1,136 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenConcat(t *testing.T) {
+	cl := newMemClient()
+	cl.put("logs/big.csv.part0002", []byte("def"))
+	cl.put("logs/big.csv.part0001", []byte("abc"))
+	cl.put("logs/big.csv.part0003", []byte("ghij"))
+	cl.put("logs/big.csv.part9/nested", []byte("nested"))
+	cl.put("logs/other.csv", []byte("other"))
+
+	fsys := s3fs.New(cl, "test")
+
+	f, err := fsys.OpenConcat("logs/big.csv.part")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 10 {
+		t.Errorf("want size 10; got %d", fi.Size())
+	}
+
+	t.Run("read all", func(t *testing.T) {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "abcdefghij" {
+			t.Errorf("want abcdefghij; got %s", data)
+		}
+	})
+
+	t.Run("seek across parts", func(t *testing.T) {
+		fixtures := []struct {
+			offset int64
+			whence int
+			n      int
+			want   string
+		}{
+			{offset: 2, whence: io.SeekStart, n: 3, want: "cde"},
+			{offset: 1, whence: io.SeekCurrent, n: 4, want: "ghij"},
+			{offset: -5, whence: io.SeekEnd, n: 2, want: "fg"},
+			{offset: 0, whence: io.SeekStart, n: 10, want: "abcdefghij"},
+		}
+
+		s := f.(io.ReadSeeker)
+		for _, fx := range fixtures {
+			if _, err := s.Seek(fx.offset, fx.whence); err != nil {
+				t.Fatal(err)
+			}
+
+			p := make([]byte, fx.n)
+			if _, err := io.ReadFull(s, p); err != nil {
+				t.Fatal(err)
+			}
+			if string(p) != fx.want {
+				t.Errorf("want %s; got %s", fx.want, p)
+			}
+		}
+
+		if _, err := s.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			t.Errorf("want io.EOF; got %v", err)
+		}
+	})
+
+	t.Run("no parts", func(t *testing.T) {
+		_, err := fsys.OpenConcat("logs/missing.part")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestOpenMany(t *testing.T) {
+	cl := newMemClient()
+	cl.put("shards/part-0", []byte("a\nb\n"))
+	cl.put("shards/part-1", []byte(""))
+	cl.put("shards/part-2", []byte("c\nd\n"))
+	cl.put("shards/part-3", []byte("e\n"))
+
+	fsys := s3fs.New(cl, "test")
+
+	r, err := fsys.OpenMany([]string{"shards/part-0", "shards/part-1", "shards/part-2", "shards/part-3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if n := cl.count("GetObject"); n != 0 {
+		t.Errorf("want no GetObject before the first Read; got %d", n)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\nc\nd\ne\n" {
+		t.Errorf("want %q; got %q", "a\nb\nc\nd\ne\n", data)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		r, err := fsys.OpenMany([]string{"shards/part-0", "shards/missing", "shards/part-2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Path != "shards/missing" || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want a not exist error for shards/missing; got %v", err)
+		}
+		if string(data) != "a\nb\n" {
+			t.Errorf("want the first object to be read; got %q", data)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := fsys.OpenMany([]string{"shards/part-0", "../x"}); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenIfNoneMatch is like Open, but only downloads the object name if its
+// ETag is not etag, which is sent with If-None-Match. If the object did
+// not change it returns an error matching ErrNotModified, so that a copy
+// cached under etag can be kept. The ETag of the opened file is reported
+// by the *ObjectInfo returned by Sys on its FileInfo.
+//
+// name must be an object; directories are not opened.
+func (f *S3FS) OpenIfNoneMatch(name, etag string) (fs.File, error) {
+	if !fs.ValidPath(name) || name == "." || etag == "" {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	fl, err := f.openFileInput(context.TODO(), &s3.GetObjectInput{
+		Bucket:      &f.bucket,
+		Key:         aws.String(name),
+		IfNoneMatch: aws.String(etag),
+	})
+	if err != nil {
+		switch {
+		case isNotModified(err):
+			err = ErrNotModified
+		case isNotFoundErr(err):
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if !f.readSeeker {
+		return fileNoSeek{fl}, nil
+	}
+	return fl, nil
+}
+
+// StatIfModifiedSince is like Stat, but sends If-Modified-Since t with the
+// HeadObject of name and reports whether the object was modified after t.
+// If it was not, S3 answers 304 and the FileInfo is nil. t has a
+// resolution of seconds, like the Last-Modified of objects.
+//
+// name must be an object; directories are not looked for.
+func (f *S3FS) StatIfModifiedSince(name string, t time.Time) (fs.FileInfo, bool, error) {
+	head, err := f.headObject(context.TODO(), name, func(in *s3.HeadObjectInput) {
+		in.IfModifiedSince = aws.Time(t)
+	})
+	if err != nil {
+		if isNotModified(err) {
+			return nil, false, nil
+		}
+		return nil, false, &fs.PathError{
+			Op:   "stat",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	fi := headFileInfo(name, head)
+	if f.statCache != nil {
+		f.statCache.put(name, fi)
+	}
+	return fi, true, nil
+}
@@ -0,0 +1,102 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenIfNoneMatch(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("v1"))
+	fsys := s3fs.New(cl, "test")
+
+	etag := func(t *testing.T, f fs.File) string {
+		t.Helper()
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := fi.Sys().(*s3fs.ObjectInfo)
+		if info == nil || info.ETag == "" {
+			t.Fatalf("expected Sys to report the ETag; got %#v", fi.Sys())
+		}
+		return info.ETag
+	}
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := etag(t, f)
+	f.Close()
+
+	if _, err := fsys.OpenIfNoneMatch("file.txt", cached); !errors.Is(err, s3fs.ErrNotModified) {
+		t.Errorf("want %v; got %v", s3fs.ErrNotModified, err)
+	}
+
+	o := cl.put("file.txt", []byte("v2"))
+	f, err = fsys.OpenIfNoneMatch("file.txt", cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "v2" {
+		t.Errorf("want v2; got %q", data)
+	}
+	if got := etag(t, f); got != o.etag {
+		t.Errorf("want the ETag %s of v2; got %s", o.etag, got)
+	}
+
+	if _, err := fsys.OpenIfNoneMatch("missing.txt", cached); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+	for _, args := range [][2]string{{".", cached}, {"file.txt", ""}, {"../up", cached}} {
+		if _, err := fsys.OpenIfNoneMatch(args[0], args[1]); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%q: want %v; got %v", args, fs.ErrInvalid, err)
+		}
+	}
+}
+
+func TestStatIfModifiedSince(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("v1"))
+	fsys := s3fs.New(cl, "test")
+
+	seen := cl.now
+	fi, changed, err := fsys.StatIfModifiedSince("file.txt", seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || fi != nil {
+		t.Errorf("want no change; got %v, %v", changed, fi)
+	}
+
+	cl.now = cl.now.Add(time.Minute)
+	cl.put("file.txt", []byte("v2!"))
+
+	fi, changed, err = fsys.StatIfModifiedSince("file.txt", seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || fi == nil {
+		t.Fatalf("want a change; got %v, %v", changed, fi)
+	}
+	if fi.Size() != 3 || !fi.ModTime().Equal(cl.now) {
+		t.Errorf("want the fresh size and modification time; got %d, %v", fi.Size(), fi.ModTime())
+	}
+
+	if _, _, err := fsys.StatIfModifiedSince("missing.txt", seen); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,221 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCopyPartSize is the largest part UploadPartCopy copies.
+const maxCopyPartSize = 5 << 30
+
+// uploadPartCopyAPIClient is implemented by clients that can copy ranges of
+// objects into multipart uploads. *s3.Client implements it.
+type uploadPartCopyAPIClient interface {
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+}
+
+var _ uploadPartCopyAPIClient = (*s3.Client)(nil)
+
+// CopyRange writes the bytes [start, end) of the object src to the object
+// dst, replacing it if it exists. The range is copied on the server with a
+// multipart upload of UploadPartCopy parts, so large objects can be
+// trimmed or split without downloading them. dst gets the content type,
+// metadata, Content-Encoding and Cache-Control of src.
+//
+// If the client does not implement UploadPartCopy, or the store answers it
+// with NotImplemented, the range is downloaded and uploaded instead. It
+// returns fs.ErrInvalid if the range is not within src.
+func (f *S3FS) CopyRange(src string, start, end int64, dst string) error {
+	if !fs.ValidPath(dst) || dst == "." {
+		return &fs.PathError{
+			Op:   "copyrange",
+			Path: dst,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(dst); err != nil {
+		return &fs.PathError{
+			Op:   "copyrange",
+			Path: dst,
+			Err:  err,
+		}
+	}
+
+	ctx := context.TODO()
+	head, err := f.headObject(ctx, src)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "copyrange",
+			Path: src,
+			Err:  err,
+		}
+	}
+
+	if start < 0 || end < start || end > head.ContentLength {
+		return &fs.PathError{
+			Op:   "copyrange",
+			Path: src,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	err = errCopyUnsupported
+	// UploadPartCopy cannot copy an empty range.
+	if end > start {
+		err = f.copyRangeParts(ctx, f.cl, src, head, start, end, f.bucket, dst)
+	}
+	if err == errCopyUnsupported {
+		err = f.copyRangeBody(ctx, src, head, start, end, dst)
+	}
+
+	if f.statCache != nil {
+		f.statCache.invalidate(dst)
+	}
+	if err != nil {
+		return &fs.PathError{
+			Op:   "copyrange",
+			Path: dst,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// errCopyUnsupported reports that the range must be copied through the
+// client.
+var errCopyUnsupported = errors.New("s3fs: UploadPartCopy is not supported")
+
+// copyRangeParts copies the range of the key src of the filesystem's
+// bucket, described by head, to the key dst of bucket with a multipart
+// upload made with cl. Unlike CopyObject, UploadPartCopy does not copy the
+// content type, metadata and headers of src, so they are taken from head.
+// It returns errCopyUnsupported if cl or the store does not implement
+// UploadPartCopy.
+func (f *S3FS) copyRangeParts(ctx context.Context, cl S3Client, src string, head *s3.HeadObjectOutput, start, end int64, bucket, dst string) error {
+	pc, ok := cl.(uploadPartCopyAPIClient)
+	if !ok {
+		return errCopyUnsupported
+	}
+
+	up, err := cl.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               &bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(dst),
+		ContentType:          head.ContentType,
+		Metadata:             head.Metadata,
+		ContentEncoding:      head.ContentEncoding,
+		CacheControl:         head.CacheControl,
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, f.optFns...)
+	if err != nil {
+		return err
+	}
+
+	var parts []types.CompletedPart
+	for off := start; off < end; off += maxCopyPartSize {
+		last := off + maxCopyPartSize
+		if last > end {
+			last = end
+		}
+
+		out, err := pc.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          &bucket,
+			RequestPayer:    f.requestPayer,
+			Key:             aws.String(dst),
+			UploadId:        up.UploadId,
+			PartNumber:      int32(len(parts) + 1),
+			CopySource:      aws.String(copySource(f.bucket, src, "")),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", off, last-1)),
+		}, f.optFns...)
+		if err != nil {
+			f.abortUpload(ctx, cl, bucket, dst, up.UploadId)
+			if errors.Is(err, errCopyUnsupported) || errorCode(err) == "NotImplemented" || httpStatusCode(err) == http.StatusNotImplemented {
+				return errCopyUnsupported
+			}
+			return err
+		}
+
+		part := types.CompletedPart{PartNumber: int32(len(parts) + 1)}
+		if out.CopyPartResult != nil {
+			part.ETag = out.CopyPartResult.ETag
+		}
+		parts = append(parts, part)
+	}
+
+	_, err = cl.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &bucket,
+		RequestPayer:    f.requestPayer,
+		Key:             aws.String(dst),
+		UploadId:        up.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	}, f.optFns...)
+	if err != nil {
+		f.abortUpload(ctx, cl, bucket, dst, up.UploadId)
+	}
+	return err
+}
+
+// abortUpload aborts the multipart upload id of key in bucket. Errors are
+// ignored: the upload is left to the bucket's lifecycle rules.
+func (f *S3FS) abortUpload(ctx context.Context, cl S3Client, bucket, key string, id *string) {
+	cl.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:       &bucket,
+		RequestPayer: f.requestPayer,
+		Key:          aws.String(key),
+		UploadId:     id,
+	}, f.optFns...)
+}
+
+// copyRangeBody downloads the range and uploads it to dst with the content
+// type, metadata and headers of head, like copyRangeParts.
+func (f *S3FS) copyRangeBody(ctx context.Context, src string, head *s3.HeadObjectOutput, start, end int64, dst string) error {
+	var data []byte
+	if end > start {
+		out, err := f.getObject(ctx, &s3.GetObjectInput{
+			Bucket: &f.bucket,
+			Key:    aws.String(src),
+			Range:  f.rangeHeader(start, end-1),
+		})
+		if err != nil {
+			return f.bucketErr(err)
+		}
+		defer out.Body.Close()
+
+		if err := f.skipIgnoredRange(out, start); err != nil {
+			return err
+		}
+		data, err = io.ReadAll(io.LimitReader(out.Body, end-start))
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != end-start {
+			return io.ErrUnexpectedEOF
+		}
+	}
+
+	return f.upload(ctx, &s3.PutObjectInput{
+		Bucket:               &f.bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(dst),
+		Body:                 bytes.NewReader(data),
+		ContentType:          head.ContentType,
+		Metadata:             head.Metadata,
+		ContentEncoding:      head.ContentEncoding,
+		CacheControl:         head.CacheControl,
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	})
+}
@@ -0,0 +1,152 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestCopyRange(t *testing.T) {
+	content := []byte("header|middle part|trailer")
+
+	check := func(t *testing.T, fsys *s3fs.S3FS) {
+		t.Helper()
+
+		if err := fsys.CopyRange("src.txt", 7, 18, "dst.txt"); err != nil {
+			t.Fatal(err)
+		}
+		data, err := fsys.ReadFile("dst.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "middle part" {
+			t.Errorf("want %q; got %q", "middle part", data)
+		}
+	}
+
+	t.Run("server side", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("src.txt", content)
+		check(t, s3fs.New(cl, "test"))
+
+		if n := cl.count("UploadPartCopy"); n != 1 {
+			t.Errorf("want 1 UploadPartCopy; got %d", n)
+		}
+		if n := cl.count("GetObject"); n != 1 {
+			t.Errorf("want only the GetObject of ReadFile; got %d", n)
+		}
+	})
+
+	t.Run("not implemented", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("src.txt", content)
+		cl.hook = func(op string, in interface{}) error {
+			if op == "UploadPartCopy" {
+				return apiError(http.StatusNotImplemented, "NotImplemented")
+			}
+			return nil
+		}
+		check(t, s3fs.New(cl, "test"))
+
+		if n := cl.count("AbortMultipartUpload"); n != 1 {
+			t.Errorf("want the multipart upload to be aborted; got %d aborts", n)
+		}
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("src.txt", content)
+		check(t, s3fs.New(struct{ s3fs.S3Client }{cl}, "test"))
+
+		if n := cl.count("CreateMultipartUpload"); n != 0 {
+			t.Errorf("want no multipart upload; got %d", n)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		for _, server := range []bool{true, false} {
+			cl := newMemClient()
+			o := cl.put("src.txt", content)
+			o.contentType = "text/csv"
+			o.metadata = map[string]string{"origin": "export"}
+			o.contentEncoding = "identity"
+			o.cacheControl = "max-age=60"
+
+			var fsys *s3fs.S3FS
+			if server {
+				fsys = s3fs.New(cl, "test")
+			} else {
+				fsys = s3fs.New(struct{ s3fs.S3Client }{cl}, "test")
+			}
+			if err := fsys.CopyRange("src.txt", 7, 18, "dst.txt"); err != nil {
+				t.Fatal(err)
+			}
+
+			var got [4]string
+			var metadata map[string]string
+			for _, in := range cl.inputs {
+				switch in := in.(type) {
+				case *s3.CreateMultipartUploadInput:
+					got = [4]string{aws.ToString(in.ContentType), aws.ToString(in.ContentEncoding), aws.ToString(in.CacheControl), aws.ToString(in.Key)}
+					metadata = in.Metadata
+				case *s3.PutObjectInput:
+					got = [4]string{aws.ToString(in.ContentType), aws.ToString(in.ContentEncoding), aws.ToString(in.CacheControl), aws.ToString(in.Key)}
+					metadata = in.Metadata
+				}
+			}
+			if want := [4]string{"text/csv", "identity", "max-age=60", "dst.txt"}; got != want {
+				t.Errorf("server side %v: want content type, encoding, cache control and key %q; got %q", server, want, got)
+			}
+			if metadata["origin"] != "export" {
+				t.Errorf("server side %v: want the metadata of src; got %v", server, metadata)
+			}
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data/src.txt", content)
+		check(t, s3fs.New(cl, "test", s3fs.WithPrefix("data")))
+
+		if _, ok := cl.get("data/dst.txt"); !ok {
+			t.Error("expected data/dst.txt to be written")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("src.txt", content)
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.CopyRange("src.txt", 5, 5, "empty.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if o, ok := cl.get("empty.txt"); !ok || len(o.data) != 0 {
+			t.Error("expected an empty object")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("src.txt", content)
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.CopyRange("missing.txt", 0, 1, "dst.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+		for _, r := range [][2]int64{{-1, 2}, {5, 4}, {0, int64(len(content) + 1)}} {
+			if err := fsys.CopyRange("src.txt", r[0], r[1], "dst.txt"); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%v: want %v; got %v", r, fs.ErrInvalid, err)
+			}
+		}
+		if err := fsys.CopyRange("src.txt", 0, 1, "../dst.txt"); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// CopyTo copies the object name to the key destName of the bucket
+// destBucket, e.g. to publish an object from a staging bucket to a
+// production one. The object is copied on the server: with a single
+// CopyObject up to 5 GiB, which is the most it copies, and with a
+// multipart upload of UploadPartCopy parts above. destName is a key of
+// destBucket as it is, without the prefix of WithPrefix.
+//
+// The client must implement CopyObject, and UploadPartCopy for objects
+// larger than 5 GiB, as *s3.Client does. If name does not exist the error
+// wraps fs.ErrNotExist.
+func (f *S3FS) CopyTo(name, destBucket, destName string) error {
+	if !fs.ValidPath(name) || name == "." || !validBucketName(destBucket) || destName == "" {
+		return &fs.PathError{
+			Op:   "copyto",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	ctx := context.TODO()
+	head, err := f.headObject(ctx, name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "copyto",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	// the destination is outside of the prefix, so the keys are made
+	// with the wrapped client.
+	cl, src := f.bucketClient(name)
+	if f.observer != nil {
+		cl = newObservedClient(cl, f.observer)
+	}
+
+	if head.ContentLength > maxCopyPartSize {
+		err = f.copyRangeParts(ctx, cl, src, head, 0, head.ContentLength, destBucket, destName)
+		if err == errCopyUnsupported {
+			err = fmt.Errorf("s3fs: %T does not implement UploadPartCopy, which copies objects larger than 5 GiB", cl)
+		}
+	} else {
+		err = f.copyObjectTo(ctx, cl, src, destBucket, destName)
+	}
+
+	if err != nil {
+		if isNotFoundErr(err) {
+			err = fs.ErrNotExist
+		}
+		return &fs.PathError{
+			Op:   "copyto",
+			Path: name,
+			Err:  permissionErr(err),
+		}
+	}
+	return nil
+}
+
+// copyObjectTo copies the key src of the filesystem's bucket to the key dst
+// of bucket with a single CopyObject.
+func (f *S3FS) copyObjectTo(ctx context.Context, cl S3Client, src, bucket, dst string) error {
+	cc, ok := cl.(copyAPIClient)
+	if !ok {
+		return fmt.Errorf("s3fs: %T does not implement CopyObject", cl)
+	}
+
+	_, err := cc.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:               &bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(dst),
+		CopySource:           aws.String(copySource(f.bucket, src, "")),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, f.optFns...)
+	return err
+}
@@ -0,0 +1,87 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestCopyTo(t *testing.T) {
+	staging, prod := newMemClient(), newMemClient()
+	staging.put("site/index.html", []byte("index"))
+	staging.put("site/a b+c.html", []byte("special"))
+	cl := bucketsClient{"staging": staging, "prod": prod}
+
+	t.Run("copy", func(t *testing.T) {
+		fsys := s3fs.New(cl, "staging")
+		for _, name := range []string{"site/index.html", "site/a b+c.html"} {
+			if err := fsys.CopyTo(name, "prod", "public/"+name); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for key, want := range map[string]string{"public/site/index.html": "index", "public/site/a b+c.html": "special"} {
+			o, ok := prod.get(key)
+			if !ok {
+				t.Fatalf("%s was not copied", key)
+			}
+			if string(o.data) != want {
+				t.Errorf("%s: want %q; got %q", key, want, o.data)
+			}
+		}
+		if _, ok := staging.get("site/index.html"); !ok {
+			t.Error("want the source to be kept")
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		fsys := s3fs.New(cl, "staging", s3fs.WithPrefix("site"))
+		if err := fsys.CopyTo("index.html", "prod", "index.html"); err != nil {
+			t.Fatal(err)
+		}
+
+		var in *s3.CopyObjectInput
+		prod.mu.Lock()
+		for _, i := range prod.inputs {
+			if i, ok := i.(*s3.CopyObjectInput); ok {
+				in = i
+			}
+		}
+		prod.mu.Unlock()
+
+		// the source is in the prefix, the destination is not.
+		if src := aws.ToString(in.CopySource); src != "staging/site/index.html" {
+			t.Errorf("want CopySource staging/site/index.html; got %s", src)
+		}
+		if _, ok := prod.get("index.html"); !ok {
+			t.Error("want index.html to be copied without the prefix")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := s3fs.New(cl, "staging").CopyTo("site/missing.html", "prod", "missing.html")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fsys := s3fs.New(cl, "staging")
+		for _, args := range [][3]string{
+			{"../index.html", "prod", "index.html"},
+			{".", "prod", "index.html"},
+			{"site/index.html", "", "index.html"},
+			{"site/index.html", "Prod_Bucket", "index.html"},
+			{"site/index.html", "prod", ""},
+		} {
+			if err := fsys.CopyTo(args[0], args[1], args[2]); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%v: want %v; got %v", args, fs.ErrInvalid, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	_ fs.File     = (*countedFile)(nil)
+	_ io.Seeker   = (*countedSeekFile)(nil)
+	_ io.ReaderAt = (*countedSeekFile)(nil)
+)
+
+// ReadStats are the statistics of a file opened with OpenCounted. They are
+// updated as the file is used and should not be read concurrently with it.
+type ReadStats struct {
+	// BytesRead is the number of bytes returned by Read and ReadAt.
+	BytesRead int64
+	// GetObjectCalls is the number of GetObject requests made for the
+	// file, including the one made by OpenCounted.
+	GetObjectCalls int64
+	// S3Time is the total time spent opening, reading and seeking the
+	// file, most of which is waiting on S3.
+	S3Time time.Duration
+}
+
+type readStatsKey struct{}
+
+// OpenCounted opens the file name like Open and returns it together with
+// its ReadStats. This is meant for attributing the cost of a request to
+// the files it reads; files opened with Open are not counted.
+//
+// The returned file implements io.Seeker and io.ReaderAt if the one
+// returned by Open does, e.g. with WithReadSeeker. Directories can not be
+// opened with OpenCounted.
+func (f *S3FS) OpenCounted(name string) (fs.File, *ReadStats, error) {
+	stats := &ReadStats{}
+	ctx := context.WithValue(context.Background(), readStatsKey{}, stats)
+
+	start := time.Now()
+	file, err := f.OpenContext(ctx, name)
+	stats.S3Time += time.Since(start)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, ok := file.(fs.ReadDirFile); ok {
+		file.Close()
+		return nil, nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  ErrIsDir,
+		}
+	}
+
+	cf := &countedFile{File: file, stats: stats}
+	if _, ok := file.(seekReaderAt); ok {
+		return &countedSeekFile{cf}, stats, nil
+	}
+	return cf, stats, nil
+}
+
+// getObject calls GetObject, counting it in the ReadStats of ctx if any.
+func (f *S3FS) getObject(ctx context.Context, in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	var out *s3.GetObjectOutput
+	err := f.retryRead(ctx, func() (err error) {
+		if stats, ok := ctx.Value(readStatsKey{}).(*ReadStats); ok {
+			// ranges are fetched concurrently by WithParallelRangeReads.
+			atomic.AddInt64(&stats.GetObjectCalls, 1)
+		}
+		out, err = f.cl.GetObject(ctx, f.getInput(in), f.optFns...)
+		return err
+	})
+	return out, err
+}
+
+// countedFile updates its ReadStats as it is read.
+type countedFile struct {
+	fs.File
+	stats *ReadStats
+}
+
+func (f *countedFile) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := f.File.Read(p)
+	f.count(start, n)
+	return n, err
+}
+
+func (f *countedFile) count(start time.Time, n int) {
+	f.stats.BytesRead += int64(n)
+	f.stats.S3Time += time.Since(start)
+}
+
+type seekReaderAt interface {
+	io.Seeker
+	io.ReaderAt
+}
+
+// countedSeekFile is a countedFile whose file can seek and read at offsets.
+type countedSeekFile struct{ *countedFile }
+
+func (f *countedSeekFile) ReadAt(p []byte, offset int64) (int, error) {
+	start := time.Now()
+	n, err := f.File.(io.ReaderAt).ReadAt(p, offset)
+	f.count(start, n)
+	return n, err
+}
+
+func (f *countedSeekFile) Seek(offset int64, whence int) (int64, error) {
+	start := time.Now()
+	off, err := f.File.(io.Seeker).Seek(offset, whence)
+	f.count(start, 0)
+	return off, err
+}
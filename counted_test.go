@@ -0,0 +1,85 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenCounted(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("hello, world"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+	f, stats, err := fsys.OpenCounted("dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello, world" {
+		t.Fatalf("want %q; got %q", "hello, world", data)
+	}
+
+	if stats.BytesRead != 12 {
+		t.Errorf("want 12 bytes read; got %d", stats.BytesRead)
+	}
+	if stats.GetObjectCalls != 1 {
+		t.Errorf("want 1 GetObject call; got %d", stats.GetObjectCalls)
+	}
+
+	p := make([]byte, 5)
+	if _, err := f.(io.ReaderAt).ReadAt(p, 7); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if string(p) != "world" {
+		t.Errorf("want world; got %q", p)
+	}
+
+	if stats.BytesRead != 17 {
+		t.Errorf("want 17 bytes read; got %d", stats.BytesRead)
+	}
+	if stats.GetObjectCalls != 2 {
+		t.Errorf("want 2 GetObject calls; got %d", stats.GetObjectCalls)
+	}
+	if stats.S3Time <= 0 {
+		t.Errorf("want S3 time to be recorded; got %v", stats.S3Time)
+	}
+
+	t.Run("not counted by Open", func(t *testing.T) {
+		f, err := fsys.Open("dir/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(f)
+		f.Close()
+
+		if stats.GetObjectCalls != 2 {
+			t.Errorf("want 2 GetObject calls; got %d", stats.GetObjectCalls)
+		}
+	})
+
+	t.Run("no seek", func(t *testing.T) {
+		f, _, err := s3fs.New(cl, "test").OpenCounted("dir/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if _, ok := f.(io.Seeker); ok {
+			t.Error("want file not to implement io.Seeker")
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		if _, _, err := fsys.OpenCounted("dir"); !errors.Is(err, s3fs.ErrIsDir) {
+			t.Errorf("want ErrIsDir; got %v", err)
+		}
+	})
+}
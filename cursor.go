@@ -0,0 +1,114 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Cursor is a position in a directory listing returned by ReadDirResume.
+// It implements encoding.TextMarshaler and encoding.TextUnmarshaler, so it
+// can be persisted to resume a listing after a restart. The zero Cursor is
+// the start of the listing.
+type Cursor struct {
+	after string
+	done  bool
+}
+
+const (
+	cursorAfter = "after:"
+	cursorDone  = "done"
+)
+
+// Done reports whether the listing is complete.
+func (c Cursor) Done() bool { return c.done }
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Cursor) MarshalText() ([]byte, error) {
+	switch {
+	case c.done:
+		return []byte(cursorDone), nil
+	case c.after == "":
+		return []byte{}, nil
+	}
+	return []byte(cursorAfter + c.after), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Cursor) UnmarshalText(text []byte) error {
+	s := string(text)
+	switch {
+	case s == "":
+		*c = Cursor{}
+	case s == cursorDone:
+		*c = Cursor{done: true}
+	case strings.HasPrefix(s, cursorAfter) && len(s) > len(cursorAfter):
+		*c = Cursor{after: strings.TrimPrefix(s, cursorAfter)}
+	default:
+		return fmt.Errorf("s3fs: invalid cursor %q", s)
+	}
+	return nil
+}
+
+// ReadDirResume reads up to n entries of the directory name following
+// cursor and returns the cursor to continue with. If n <= 0 all the
+// remaining entries are returned. Once the listing is complete the
+// returned cursor is Done and no more entries are returned.
+//
+// Unlike a continuation token the cursor stays valid across processes and
+// does not expire, as it only records the name of the last entry returned.
+// Entries added before the cursor after it was taken are not seen.
+func (f *S3FS) ReadDirResume(name string, cursor Cursor, n int) ([]fs.DirEntry, Cursor, error) {
+	if cursor.done {
+		return []fs.DirEntry{}, cursor, nil
+	}
+
+	d, err := f.openDir(context.TODO(), name)
+	if err != nil {
+		return nil, cursor, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	var des []fs.DirEntry
+	if d, ok := d.(*dir); ok {
+		d.startAfter = cursor.after
+		des, err = d.ReadDir(n)
+	} else {
+		des, err = readDirAfter(d, cursor.after, n)
+	}
+
+	switch {
+	case errors.Is(err, io.EOF) || n <= 0 && err == nil:
+		cursor.done = true
+	case err != nil:
+		return nil, cursor, err
+	}
+
+	if len(des) > 0 {
+		cursor.after = des[len(des)-1].Name()
+	}
+	return des, cursor, nil
+}
+
+// readDirAfter reads up to n entries of d that sort after the name after,
+// for directories that cannot start their listing there.
+func readDirAfter(d fs.ReadDirFile, after string, n int) ([]fs.DirEntry, error) {
+	des, err := d.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(des) > 0 && after != "" && des[0].Name() <= after {
+		des = des[1:]
+	}
+	if n <= 0 || n >= len(des) {
+		return des, io.EOF
+	}
+	return des[:n], nil
+}
@@ -0,0 +1,69 @@
+package s3fs_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestReadDirResume(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{"dir/a.txt", "dir/b/c.txt", "dir/b.txt", "dir/d/e.txt", "dir/f.txt", "other.txt"} {
+		cl.put(key, []byte(key))
+	}
+
+	want := []string{"a.txt", "b", "b.txt", "d", "f.txt"}
+
+	var (
+		got    []string
+		cursor s3fs.Cursor
+	)
+	for i := 0; !cursor.Done(); i++ {
+		if i > len(want) {
+			t.Fatal("listing did not complete")
+		}
+
+		// every page is read by a new filesystem from a persisted cursor,
+		// as if the process restarted.
+		data, err := json.Marshal(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resumed s3fs.Cursor
+		if err := json.Unmarshal(data, &resumed); err != nil {
+			t.Fatal(err)
+		}
+
+		des, next, err := s3fs.New(cl, "test").ReadDirResume("dir", resumed, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, de := range des {
+			got = append(got, de.Name())
+		}
+		cursor = next
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	t.Run("all", func(t *testing.T) {
+		des, cursor, err := s3fs.New(cl, "test").ReadDirResume("dir", s3fs.Cursor{}, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(des) != len(want) || !cursor.Done() {
+			t.Errorf("want %d entries and a done cursor; got %d, %v", len(want), len(des), cursor.Done())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var c s3fs.Cursor
+		if err := c.UnmarshalText([]byte("bogus")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// SetReadDeadline makes reads of the file fail once t has passed, like the
+// read deadline of a net.Conn, including a Read that is blocked on a stuck
+// download: the requests of the file are canceled, and reads return an
+// error matching context.DeadlineExceeded. An expired file cannot be read
+// anymore; it only has to be closed. A zero t removes the deadline.
+//
+// Canceling the context given to OpenContext aborts the reads the same
+// way, with the error of the context.
+func (f *file) SetReadDeadline(t time.Time) error {
+	if f.deadline == nil {
+		return fmt.Errorf("s3fs: %s does not support read deadlines", f.name)
+	}
+	f.deadline.set(t)
+	return nil
+}
+
+// readDeadline cancels the context of a file when its deadline passes.
+type readDeadline struct {
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+func newReadDeadline(cancel context.CancelFunc) *readDeadline {
+	return &readDeadline{cancel: cancel}
+}
+
+func (d *readDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if t.IsZero() || d.expired {
+		return
+	}
+
+	wait := time.Until(t)
+	if wait <= 0 {
+		d.expire()
+		return
+	}
+	d.timer = time.AfterFunc(wait, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.expire()
+	})
+}
+
+// expire cancels the context. d.mu must be held.
+func (d *readDeadline) expire() {
+	d.expired = true
+	d.cancel()
+}
+
+func (d *readDeadline) hasExpired() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.expired
+}
+
+// stop releases the context and the timer.
+func (d *readDeadline) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.cancel()
+}
+
+// readErr returns the error of a read that failed with err. Reads that
+// failed because the context of the file is done, or its deadline passed,
+// return an error matching the context error, or
+// context.DeadlineExceeded.
+func (f *file) readErr(op string, err error) error {
+	ctxErr := f.context().Err()
+	if err == nil || errors.Is(err, io.EOF) || ctxErr == nil {
+		return err
+	}
+	if f.deadline != nil && f.deadline.hasExpired() {
+		ctxErr = context.DeadlineExceeded
+	}
+	return &fs.PathError{
+		Op:   op,
+		Path: f.name,
+		Err:  ctxErr,
+	}
+}
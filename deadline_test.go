@@ -0,0 +1,123 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// stuckClient returns bodies that block until the context of their request
+// is done, as a stuck HTTP download does.
+type stuckClient struct {
+	*memClient
+}
+
+func (c stuckClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Body.Close()
+	out.Body = stuckBody{ctx: ctx}
+	return out, nil
+}
+
+type stuckBody struct {
+	ctx context.Context
+}
+
+func (b stuckBody) Read([]byte) (int, error) {
+	<-b.ctx.Done()
+	return 0, b.ctx.Err()
+}
+
+func (stuckBody) Close() error { return nil }
+
+// readResult reads from r and fails the test if the read does not return
+// within a second.
+func readResult(t *testing.T, r io.Reader) error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 8))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("read did not return")
+		return nil
+	}
+}
+
+func TestSetReadDeadline(t *testing.T) {
+	cl := stuckClient{newMemClient()}
+	cl.put("file.txt", []byte("content"))
+	fsys := s3fs.New(cl, "test")
+
+	type deadliner interface {
+		SetReadDeadline(time.Time) error
+	}
+
+	t.Run("deadline", func(t *testing.T) {
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		d, ok := f.(deadliner)
+		if !ok {
+			t.Fatalf("%T has no SetReadDeadline", f)
+		}
+		if err := d.SetReadDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := readResult(t, f); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+		// the file stays expired.
+		if err := readResult(t, f); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v after the deadline; got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("past deadline", func(t *testing.T) {
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if err := f.(deadliner).SetReadDeadline(time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if err := readResult(t, f); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		f, err := fsys.OpenContext(ctx, "file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err := readResult(t, f); !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package s3fs
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Decompressor returns a reader of the decompressed content of r.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// WithExtensionDecompress makes Open and ReadFile decompress objects based
+// on the extension of their name, whatever their Content-Encoding: ".gz"
+// is read with compress/gzip and ".bz2" with compress/bzip2. Other
+// extensions can be added with WithDecompressor; objects with unknown
+// extensions are read as they are.
+//
+// Decompressed files cannot Seek and Stat reports the compressed size,
+// unless the object was written with WithCompressOnWrite, see
+// WithAutoDecompress.
+func WithExtensionDecompress(fsys *S3FS) {
+	fsys.setDecompressor(".gz", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+	fsys.setDecompressor(".bz2", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	})
+}
+
+// WithAutoDecompress makes Open and ReadFile decompress objects stored with
+// Content-Encoding gzip, such as those written with WithCompressOnWrite.
+// Without it their compressed bytes are read, as S3 returns them.
+//
+// Decompressed files cannot Seek. Stat, of the filesystem and of opened
+// files, reports the uncompressed size of objects written with
+// WithCompressOnWrite, which store it in their metadata, and the stored,
+// compressed size of others, as it is not known up front. Entries of
+// ReadDir report the stored size. The decompressor of the name's
+// extension, if any, takes precedence.
+func WithAutoDecompress(fsys *S3FS) { fsys.autoDecompress = true }
+
+// WithDecompressor makes Open and ReadFile decompress the objects whose
+// name ends with the extension ext, e.g. ".zst", with fn. Extensions are
+// matched case-insensitively, and fn takes precedence over the
+// decompressor WithExtensionDecompress sets for ext.
+func WithDecompressor(ext string, fn Decompressor) Option {
+	return func(fsys *S3FS) {
+		if fsys.decompressors == nil {
+			fsys.decompressors = make(map[string]Decompressor)
+		}
+		fsys.decompressors[strings.ToLower(ext)] = fn
+	}
+}
+
+// setDecompressor sets the decompressor of ext unless it already has one.
+func (f *S3FS) setDecompressor(ext string, fn Decompressor) {
+	if f.decompressors == nil {
+		f.decompressors = make(map[string]Decompressor)
+	}
+	if _, ok := f.decompressors[ext]; !ok {
+		f.decompressors[ext] = fn
+	}
+}
+
+// decompressor returns the decompressor for name, or nil if it is read as
+// it is.
+func (f *S3FS) decompressor(name string) Decompressor {
+	return f.decompressors[strings.ToLower(path.Ext(name))]
+}
+
+// decompressorFor is like decompressor, but also returns a gzip
+// decompressor for objects stored with contentEncoding gzip if
+// WithAutoDecompress is used.
+func (f *S3FS) decompressorFor(name, contentEncoding string) Decompressor {
+	if dec := f.decompressor(name); dec != nil {
+		return dec
+	}
+	if f.autoDecompress && isGzipEncoding(contentEncoding) {
+		return func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		}
+	}
+	return nil
+}
+
+// isGzipEncoding reports whether the Content-Encoding contentEncoding is gzip.
+func isGzipEncoding(contentEncoding string) bool {
+	return strings.EqualFold(contentEncoding, "gzip") || strings.EqualFold(contentEncoding, "x-gzip")
+}
+
+// contentEncoding returns the Content-Encoding of f if it is an object.
+func contentEncoding(f fs.File) string {
+	if fl, ok := f.(*file); ok {
+		return fl.contentEncoding
+	}
+	return ""
+}
+
+// objectMetadata returns the user metadata of f if it is an object.
+func objectMetadata(f fs.File) map[string]string {
+	if fl, ok := f.(*file); ok {
+		return fl.metadata
+	}
+	return nil
+}
+
+// decompress returns file decompressed with dec. metadata is the user
+// metadata of the object, which can hold its uncompressed size.
+func decompress(dec Decompressor, file fs.File, metadata map[string]string) (fs.File, error) {
+	r, err := dec(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &decompressedFile{ReadCloser: r, file: file, metadata: metadata}, nil
+}
+
+var _ fs.File = (*decompressedFile)(nil)
+
+// decompressedFile is a file decompressed while it is read.
+type decompressedFile struct {
+	io.ReadCloser
+	file     fs.File
+	metadata map[string]string
+}
+
+// Stat reports the uncompressed size if WithCompressOnWrite stored it, and
+// the compressed size otherwise.
+func (f *decompressedFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info, ok := fi.(*fileInfo); ok {
+		cp := *info
+		cp.size = gzipSize(cp.size, f.metadata)
+		return &cp, nil
+	}
+	return fi, nil
+}
+
+func (f *decompressedFile) Close() error {
+	derr := f.ReadCloser.Close()
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	return derr
+}
@@ -0,0 +1,140 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestExtensionDecompress(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, "hello, world")
+	zw.Close()
+
+	cl := newMemClient()
+	cl.put("logs/app.log.gz", buf.Bytes())
+	cl.put("logs/app.log.GZ", buf.Bytes())
+	cl.put("logs/app.log.rev", []byte("dlrow ,olleh"))
+	cl.put("logs/app.log", []byte("plain"))
+
+	reverse := func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithExtensionDecompress, s3fs.WithDecompressor(".rev", reverse))
+
+	for name, want := range map[string]string{
+		"logs/app.log.gz":  "hello, world",
+		"logs/app.log.GZ":  "hello, world",
+		"logs/app.log.rev": "hello, world",
+		"logs/app.log":     "plain",
+	} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: want %q; got %q", name, want, data)
+		}
+
+		if data, err := fsys.ReadFile(name); err != nil || string(data) != want {
+			t.Errorf("%s: ReadFile: want %q; got %q, %v", name, want, data, err)
+		}
+	}
+
+	t.Run("no seek", func(t *testing.T) {
+		f, err := fsys.Open("logs/app.log.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if _, ok := f.(io.Seeker); ok {
+			t.Error("want decompressed file not to implement io.Seeker")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cl.put("broken.gz", []byte("this is not gzip data"))
+
+		_, err := fsys.Open("broken.gz")
+		if err == nil || !strings.Contains(err.Error(), "gzip") {
+			t.Errorf("want gzip error; got %v", err)
+		}
+	})
+}
+
+func TestAutoDecompress(t *testing.T) {
+	content := strings.Repeat("compressible text ", 100)
+
+	cl := newMemClient()
+	if err := s3fs.New(cl, "test", s3fs.WithCompressOnWrite(gzip.DefaultCompression)).WriteFile("data.txt", []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	cl.put("plain.txt", []byte("plain"))
+	o, _ := cl.get("data.txt")
+
+	fsys := s3fs.New(cl, "test", s3fs.WithAutoDecompress, s3fs.WithReadSeeker)
+
+	f, err := fsys.Open("data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, ok := f.(io.Seeker); ok {
+		t.Error("expected a decompressed file not to Seek")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(content)) {
+		t.Errorf("want the uncompressed size %d; got %d", len(content), fi.Size())
+	}
+	if fi, err := fsys.Stat("data.txt"); err != nil || fi.Size() != int64(len(content)) {
+		t.Errorf("want Stat to report the uncompressed size %d; got %v (%v)", len(content), fi, err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Error("Open did not yield the decompressed content")
+	}
+
+	if data, err := fsys.ReadFile("data.txt"); err != nil || string(data) != content {
+		t.Errorf("ReadFile did not yield the decompressed content: %v", err)
+	}
+	if data, err := fsys.ReadFile("plain.txt"); err != nil || string(data) != "plain" {
+		t.Errorf("want plain; got %q and %v", data, err)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		data, err := s3fs.New(cl, "test").ReadFile("data.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, o.data) {
+			t.Error("expected the raw compressed bytes")
+		}
+	})
+}
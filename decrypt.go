@@ -0,0 +1,183 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// Metadata of client-side encrypted objects, as written by the AWS S3
+// Encryption Client (v2 format).
+const (
+	metaKeyV2   = "x-amz-key-v2"
+	metaIV      = "x-amz-iv"
+	metaCEKAlg  = "x-amz-cek-alg"
+	metaWrapAlg = "x-amz-wrap-alg"
+	metaMatDesc = "x-amz-matdesc"
+	metaTagLen  = "x-amz-tag-len"
+)
+
+// gcmTagSize is the size of the tag following the AES-GCM ciphertext.
+const gcmTagSize = 16
+
+// KMSClient decrypts the data keys of client-side encrypted objects. It is
+// usually a thin adapter over the Decrypt API of AWS KMS.
+type KMSClient interface {
+	// Decrypt returns the plaintext of the data key ciphertext, which was
+	// encrypted with the KMS key keyID under encryptionContext.
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+// KeyResolver returns the ID of the KMS key the data key of the object name
+// was encrypted with, given the encryption context stored with the object.
+// Returning an error refuses to decrypt the object.
+type KeyResolver func(name string, encryptionContext map[string]string) (keyID string, err error)
+
+// WithClientSideDecryption makes Open and ReadFile decrypt objects that were
+// encrypted on the client before they were uploaded, using envelope
+// encryption: the content is encrypted with a data key that is stored,
+// encrypted with KMS, in the object's metadata. Objects without that
+// metadata are read as they are.
+//
+// The metadata is that of the AWS S3 Encryption Client v2 with KMS:
+//
+//	x-amz-key-v2    base64 of the data key encrypted with KMS
+//	x-amz-iv        base64 of the 12 byte AES-GCM nonce
+//	x-amz-cek-alg   "AES/GCM/NoPadding"
+//	x-amz-wrap-alg  "kms+context"
+//	x-amz-matdesc   the KMS encryption context as a JSON object
+//	x-amz-tag-len   "128"
+//
+// and the body is the AES-GCM ciphertext followed by its 16 byte tag.
+//
+// AES-GCM authenticates the object as a whole, so the object is downloaded
+// entirely before the first Read returns. Decrypted files cannot Seek and
+// Stat reports the decrypted size.
+func WithClientSideDecryption(kms KMSClient, resolve KeyResolver) Option {
+	return func(fsys *S3FS) {
+		fsys.decryption = &decryption{kms: kms, resolve: resolve}
+	}
+}
+
+type decryption struct {
+	kms     KMSClient
+	resolve KeyResolver
+}
+
+// decrypt reads and decrypts body, the content of the object name, if its
+// metadata says it is client-side encrypted. It reports whether it is;
+// body is left unread if not.
+func (d *decryption) decrypt(ctx context.Context, name string, metadata map[string]string, body io.Reader) ([]byte, bool, error) {
+	wrapped, ok := metadata[metaKeyV2]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if alg := metadata[metaCEKAlg]; alg != "AES/GCM/NoPadding" {
+		return nil, true, fmt.Errorf("s3fs: unsupported content encryption %q", alg)
+	}
+	if alg := metadata[metaWrapAlg]; alg != "kms+context" {
+		return nil, true, fmt.Errorf("s3fs: unsupported key wrapping %q", alg)
+	}
+	if tagLen, ok := metadata[metaTagLen]; ok && tagLen != "128" {
+		return nil, true, fmt.Errorf("s3fs: unsupported tag length %s", tagLen)
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: invalid %s: %w", metaKeyV2, err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(metadata[metaIV])
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: invalid %s: %w", metaIV, err)
+	}
+
+	var encCtx map[string]string
+	if err := json.Unmarshal([]byte(metadata[metaMatDesc]), &encCtx); err != nil {
+		return nil, true, fmt.Errorf("s3fs: invalid %s: %w", metaMatDesc, err)
+	}
+
+	keyID, err := d.resolve(name, encCtx)
+	if err != nil {
+		return nil, true, err
+	}
+
+	key, err := d.kms.Decrypt(ctx, keyID, encryptedKey, encCtx)
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: decrypt data key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: decrypt: %w", err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: decrypt: %w", err)
+	}
+
+	ciphertext, err := io.ReadAll(body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	plaintext, err := gcm.Open(ciphertext[:0], iv, ciphertext, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("s3fs: decrypt: %w", err)
+	}
+	return plaintext, true, nil
+}
+
+// decryptFile returns file decrypted if it is client-side encrypted, and
+// whether it was. file is closed if an error is returned.
+func (d *decryption) decryptFile(ctx context.Context, name string, f fs.File) (fs.File, bool, error) {
+	fl, ok := f.(*file)
+	if !ok {
+		return f, false, nil
+	}
+
+	// the ciphertext is read from the body as it is.
+	data, encrypted, err := d.decrypt(ctx, name, fl.metadata, fl.ReadCloser)
+	if err != nil {
+		f.Close()
+		return nil, true, err
+	}
+	if !encrypted {
+		return f, false, nil
+	}
+	return &decryptedFile{Reader: bytes.NewReader(data), file: fl, size: int64(len(data))}, true, nil
+}
+
+var _ fs.File = (*decryptedFile)(nil)
+
+// decryptedFile is a client-side encrypted file whose content was
+// decrypted.
+type decryptedFile struct {
+	io.Reader
+	file fs.File
+	size int64
+}
+
+func (f *decryptedFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return decryptedInfo{FileInfo: fi, size: f.size}, nil
+}
+
+func (f *decryptedFile) Close() error { return f.file.Close() }
+
+// decryptedInfo reports the decrypted size of a file.
+type decryptedInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (fi decryptedInfo) Size() int64 { return fi.size }
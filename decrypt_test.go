@@ -0,0 +1,196 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeKMS wraps data keys by XORing them with a master key, and remembers
+// the encryption context they were wrapped under.
+type fakeKMS struct {
+	keyID  string
+	master []byte
+	encCtx map[string]string
+}
+
+func (k *fakeKMS) wrap(key []byte, encCtx map[string]string) []byte {
+	k.encCtx = encCtx
+	wrapped := make([]byte, len(key))
+	for i := range key {
+		wrapped[i] = key[i] ^ k.master[i]
+	}
+	return wrapped
+}
+
+func (k *fakeKMS) Decrypt(ctx context.Context, keyID string, ciphertext []byte, encCtx map[string]string) ([]byte, error) {
+	if keyID != k.keyID {
+		return nil, errors.New("unknown key " + keyID)
+	}
+	if !reflect.DeepEqual(encCtx, k.encCtx) {
+		return nil, errors.New("encryption context mismatch")
+	}
+	return k.wrap(ciphertext, encCtx), nil
+}
+
+func TestClientSideDecryption(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("secret "), 100)
+
+	kms := &fakeKMS{keyID: "key-1", master: bytes.Repeat([]byte{0x5a}, 32)}
+	dataKey := bytes.Repeat([]byte{0x42}, 32)
+	iv := bytes.Repeat([]byte{0x01}, 12)
+	encCtx := map[string]string{"aws:x-amz-cek-alg": "AES/GCM/NoPadding"}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matdesc, err := json.Marshal(encCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl := newMemClient()
+	o := cl.put("secret.txt", gcm.Seal(nil, iv, plaintext, nil))
+	o.metadata = map[string]string{
+		"x-amz-key-v2":   base64.StdEncoding.EncodeToString(kms.wrap(dataKey, encCtx)),
+		"x-amz-iv":       base64.StdEncoding.EncodeToString(iv),
+		"x-amz-cek-alg":  "AES/GCM/NoPadding",
+		"x-amz-wrap-alg": "kms+context",
+		"x-amz-matdesc":  string(matdesc),
+		"x-amz-tag-len":  "128",
+	}
+	cl.put("plain.txt", []byte("plain"))
+
+	var resolved string
+	fsys := s3fs.New(cl, "test", s3fs.WithClientSideDecryption(kms, func(name string, encCtx map[string]string) (string, error) {
+		resolved = name
+		return "key-1", nil
+	}))
+
+	t.Run("open", func(t *testing.T) {
+		f, err := fsys.Open("secret.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, plaintext) {
+			t.Errorf("want %q; got %q", plaintext, data)
+		}
+		if resolved != "secret.txt" {
+			t.Errorf("want the key of secret.txt to be resolved; got %q", resolved)
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(len(plaintext)) {
+			t.Errorf("want size %d; got %d", len(plaintext), fi.Size())
+		}
+	})
+
+	t.Run("read file", func(t *testing.T) {
+		data, err := fsys.ReadFile("secret.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, plaintext) {
+			t.Errorf("want %q; got %q", plaintext, data)
+		}
+	})
+
+	t.Run("read file limit", func(t *testing.T) {
+		if _, err := fsys.ReadFileLimit("secret.txt", int64(len(plaintext))-1); !errors.Is(err, s3fs.ErrTooLarge) {
+			t.Errorf("want %v; got %v", s3fs.ErrTooLarge, err)
+		}
+
+		// the ciphertext is not downloaded entirely.
+		cc := &countingClient{memClient: cl}
+		limited := s3fs.New(cc, "test", s3fs.WithClientSideDecryption(kms, func(string, map[string]string) (string, error) {
+			return "key-1", nil
+		}))
+		if _, err := limited.ReadFileLimit("secret.txt", 10); !errors.Is(err, s3fs.ErrTooLarge) {
+			t.Errorf("want %v; got %v", s3fs.ErrTooLarge, err)
+		}
+		if cc.read > 10+16+1 {
+			t.Errorf("want at most %d bytes read; got %d", 10+16+1, cc.read)
+		}
+
+		data, err := fsys.ReadFileLimit("secret.txt", int64(len(plaintext)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, plaintext) {
+			t.Errorf("want %q; got %q", plaintext, data)
+		}
+	})
+
+	t.Run("not encrypted", func(t *testing.T) {
+		data, err := fsys.ReadFile("plain.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "plain" {
+			t.Errorf("want %q; got %q", "plain", data)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		o.data[0] ^= 0xff
+		defer func() { o.data[0] ^= 0xff }()
+
+		if _, err := fsys.ReadFile("secret.txt"); err == nil {
+			t.Error("expected tampered content to fail to decrypt")
+		}
+	})
+}
+
+// countingClient counts the bytes read from the bodies of GetObject.
+type countingClient struct {
+	*memClient
+	read int64
+}
+
+func (c *countingClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Body = struct {
+		io.Reader
+		io.Closer
+	}{&countingReader{r: out.Body, n: &c.read}, out.Body}
+	return out, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,130 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// DiagnosticReport is the result of Diagnose.
+type DiagnosticReport struct {
+	// Region is the region of the bucket as reported by S3, or empty if it
+	// is unknown.
+	Region string
+	// Key is the object the HeadObject and GetObject probes used, the first
+	// object listed. If it is empty the listing failed or found no object
+	// and those probes were skipped.
+	Key string
+
+	// Bucket, List, Head and Read report whether HeadBucket,
+	// ListObjectsV2, HeadObject and GetObject succeeded.
+	Bucket, List, Head, Read bool
+
+	// Errors holds the errors of the failed probes by operation name.
+	// Use IsPermission to tell denied permissions from other failures.
+	Errors map[string]error
+}
+
+// Diagnose probes what the filesystem's client may do in the bucket, to
+// find misconfigurations at startup. It makes a HeadBucket, lists a single
+// object, and if one was listed makes a HeadObject and reads its first
+// byte.
+//
+// Failed probes are reported in the DiagnosticReport; the error is only
+// non-nil if ctx is done.
+func (f *S3FS) Diagnose(ctx context.Context) (DiagnosticReport, error) {
+	report := DiagnosticReport{Errors: make(map[string]error)}
+
+	probe := func(op string, err error) bool {
+		if err != nil {
+			report.Errors[op] = err
+			return false
+		}
+		return true
+	}
+
+	var region bucketRegion
+	optFns := append(f.optFns[:len(f.optFns):len(f.optFns)], func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, region.register)
+	})
+
+	_, err := f.cl.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: &f.bucket,
+	}, optFns...)
+	report.Bucket = probe("HeadBucket", err)
+	report.Region = region.region
+
+	out, err := f.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:       &f.bucket,
+		RequestPayer: f.requestPayer,
+		MaxKeys:      1,
+	}, f.optFns...)
+	if report.List = probe("ListObjectsV2", err); report.List && len(out.Contents) > 0 {
+		report.Key = aws.ToString(out.Contents[0].Key)
+	}
+
+	if report.Key != "" {
+		_, err := f.cl.HeadObject(ctx, f.headInput(&s3.HeadObjectInput{
+			Bucket: &f.bucket,
+			Key:    aws.String(report.Key),
+		}), f.optFns...)
+		report.Head = probe("HeadObject", err)
+
+		get, err := f.cl.GetObject(ctx, f.getInput(&s3.GetObjectInput{
+			Bucket: &f.bucket,
+			Key:    aws.String(report.Key),
+			Range:  f.rangeHeader(0, 0),
+		}), f.optFns...)
+		if report.Read = probe("GetObject", err); report.Read {
+			get.Body.Close()
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// CheckAccess makes a HeadBucket to verify, e.g. at startup, that the
+// bucket exists and that the filesystem's client may access it. The error
+// tells the failures apart: it matches ErrNoSuchBucket if the bucket does
+// not exist, IsPermission reports true for it if access was denied, and
+// otherwise the bucket could not be reached, e.g. because of a network
+// failure or a wrong endpoint.
+func (f *S3FS) CheckAccess(ctx context.Context) error {
+	_, err := f.cl.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: &f.bucket,
+	}, f.optFns...)
+	switch {
+	case err == nil:
+		return nil
+	case isNoSuchBucket(err) || httpStatusCode(err) == http.StatusNotFound:
+		// HeadBucket responses have no body, so a missing bucket is only
+		// told by the status.
+		return noSuchBucketError{bucket: f.bucket, err: err}
+	case IsPermission(err):
+		return fmt.Errorf("s3fs: access to bucket %q denied: %w", f.bucket, err)
+	default:
+		return fmt.Errorf("s3fs: cannot reach bucket %q: %w", f.bucket, err)
+	}
+}
+
+// bucketRegion captures the region a response reports the bucket in.
+type bucketRegion struct {
+	region string
+}
+
+func (r *bucketRegion) register(stack *middleware.Stack) error {
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("S3FSBucketRegion", func(
+		ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler,
+	) (middleware.DeserializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleDeserialize(ctx, in)
+		if resp, ok := out.RawResponse.(*smithyhttp.Response); ok {
+			r.region = resp.Header.Get("X-Amz-Bucket-Region")
+		}
+		return out, metadata, err
+	}), middleware.After)
+}
@@ -0,0 +1,130 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestDiagnose(t *testing.T) {
+	cl := newMemClient()
+	cl.put("a.txt", []byte("a"))
+	cl.put("b.txt", []byte("b"))
+	cl.hook = func(op string, in interface{}) error {
+		if op == "HeadObject" {
+			return responseError(http.StatusForbidden, nil)
+		}
+		return nil
+	}
+
+	report, err := s3fs.New(cl, "test").Diagnose(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Bucket || !report.List || report.Head || !report.Read {
+		t.Errorf("want bucket, list and read but not head; got %+v", report)
+	}
+	if report.Key != "a.txt" {
+		t.Errorf("want key a.txt; got %q", report.Key)
+	}
+	if err := report.Errors["HeadObject"]; !s3fs.IsPermission(err) {
+		t.Errorf("want a permission error for HeadObject; got %v", err)
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("want a single error; got %v", report.Errors)
+	}
+
+	t.Run("empty bucket", func(t *testing.T) {
+		cl := newMemClient()
+		report, err := s3fs.New(cl, "test").Diagnose(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Key != "" || report.Head || report.Read {
+			t.Errorf("want the object probes skipped; got %+v", report)
+		}
+		if n := cl.count("HeadObject") + cl.count("GetObject"); n != 0 {
+			t.Errorf("want no object requests; got %d", n)
+		}
+	})
+
+	t.Run("region", func(t *testing.T) {
+		cl := s3.New(s3.Options{
+			Region:      "us-east-1",
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"X-Amz-Bucket-Region": []string{"eu-central-1"}},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}),
+		})
+
+		report, err := s3fs.New(cl, "bucket").Diagnose(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Region != "eu-central-1" {
+			t.Errorf("want region eu-central-1; got %q", report.Region)
+		}
+	})
+}
+
+func TestCheckAccess(t *testing.T) {
+	errNetwork := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		err          error
+		noSuchBucket bool
+		permission   bool
+	}{
+		{"ok", nil, false, false},
+		{"no such bucket", apiError(http.StatusNotFound, "NoSuchBucket"), true, false},
+		{"not found", responseError(http.StatusNotFound, nil), true, false},
+		{"access denied", apiError(http.StatusForbidden, "AccessDenied"), false, true},
+		{"forbidden", responseError(http.StatusForbidden, nil), false, true},
+		{"network", errNetwork, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := newMemClient()
+			cl.hook = func(op string, in interface{}) error {
+				if op == "HeadBucket" {
+					return tt.err
+				}
+				return nil
+			}
+
+			err := s3fs.New(cl, "test").CheckAccess(context.Background())
+			if tt.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want an error")
+			}
+			if got := errors.Is(err, s3fs.ErrNoSuchBucket); got != tt.noSuchBucket {
+				t.Errorf("want ErrNoSuchBucket %v; got %v", tt.noSuchBucket, err)
+			}
+			if got := s3fs.IsPermission(err); got != tt.permission {
+				t.Errorf("want IsPermission %v; got %v", tt.permission, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want the client error wrapped; got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Diff compares the objects below the directories a and b by their path
+// relative to a and b. onlyA and onlyB hold the paths that only exist in
+// one of them, differ the paths whose objects differ in size or ETag.
+//
+// Both listings are merged page by page, so memory use does not grow with
+// the number of identical objects. Directory markers are ignored. Note that
+// the ETag of a multipart upload differs from the one of the same content
+// uploaded in a single part.
+func (f *S3FS) Diff(a, b string) (onlyA, onlyB, differ []string, err error) {
+	for _, name := range []string{a, b} {
+		if !fs.ValidPath(name) {
+			return nil, nil, nil, &fs.PathError{
+				Op:   "diff",
+				Path: name,
+				Err:  fs.ErrInvalid,
+			}
+		}
+		if err := f.validateKey(name); err != nil {
+			return nil, nil, nil, &fs.PathError{
+				Op:   "diff",
+				Path: name,
+				Err:  err,
+			}
+		}
+	}
+
+	la := f.newKeyLister(context.TODO(), a)
+	lb := f.newKeyLister(context.TODO(), b)
+
+	oa, okA, err := la.next()
+	if err != nil {
+		return nil, nil, nil, la.pathError(err)
+	}
+	ob, okB, err := lb.next()
+	if err != nil {
+		return nil, nil, nil, lb.pathError(err)
+	}
+
+	for okA || okB {
+		ka, kb := la.rel(oa), lb.rel(ob)
+
+		advanceA, advanceB := false, false
+		switch {
+		case okA && (!okB || ka < kb):
+			onlyA = append(onlyA, ka)
+			advanceA = true
+		case okB && (!okA || kb < ka):
+			onlyB = append(onlyB, kb)
+			advanceB = true
+		default:
+			if oa.Size != ob.Size || aws.ToString(oa.ETag) != aws.ToString(ob.ETag) {
+				differ = append(differ, ka)
+			}
+			advanceA, advanceB = true, true
+		}
+
+		if advanceA {
+			if oa, okA, err = la.next(); err != nil {
+				return nil, nil, nil, la.pathError(err)
+			}
+		}
+		if advanceB {
+			if ob, okB, err = lb.next(); err != nil {
+				return nil, nil, nil, lb.pathError(err)
+			}
+		}
+	}
+	return onlyA, onlyB, differ, nil
+}
+
+// keyLister iterates over all the objects below a directory in key order,
+// one listing page at a time.
+type keyLister struct {
+	fsys   *S3FS
+	ctx    context.Context
+	name   string
+	prefix string
+
+	page  []types.Object
+	token *string
+	done  bool
+	last  time.Time
+}
+
+func (f *S3FS) newKeyLister(ctx context.Context, name string) *keyLister {
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	return &keyLister{
+		fsys:   f,
+		ctx:    ctx,
+		name:   name,
+		prefix: prefix,
+	}
+}
+
+// next returns the next object, skipping directory markers. ok is false
+// once all objects were returned.
+func (l *keyLister) next() (o types.Object, ok bool, err error) {
+	for {
+		for len(l.page) > 0 {
+			o, l.page = l.page[0], l.page[1:]
+			if !strings.HasSuffix(aws.ToString(o.Key), "/") {
+				return o, true, nil
+			}
+		}
+
+		if l.done {
+			return types.Object{}, false, nil
+		}
+
+		if err := l.fsys.paceList(l.ctx, l.last); err != nil {
+			return types.Object{}, false, err
+		}
+		l.last = time.Now()
+
+		out, err := l.fsys.cl.ListObjectsV2(l.ctx, &s3.ListObjectsV2Input{
+			Bucket:            &l.fsys.bucket,
+			RequestPayer:      l.fsys.requestPayer,
+			Prefix:            aws.String(l.prefix),
+			ContinuationToken: l.token,
+		}, l.fsys.optFns...)
+		if err != nil {
+			return types.Object{}, false, err
+		}
+		l.fsys.auditList(l.prefix, out)
+
+		l.page = out.Contents
+		l.token = out.NextContinuationToken
+		l.done = !out.IsTruncated
+	}
+}
+
+// rel returns the key of o relative to the listed directory.
+func (l *keyLister) rel(o types.Object) string {
+	return strings.TrimPrefix(aws.ToString(o.Key), l.prefix)
+}
+
+func (l *keyLister) pathError(err error) error {
+	return &fs.PathError{
+		Op:   "diff",
+		Path: l.name,
+		Err:  err,
+	}
+}
@@ -0,0 +1,41 @@
+package s3fs_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestDiff(t *testing.T) {
+	cl := newMemClient()
+	// enough identical objects for both listings to span several pages.
+	for i := 0; i < 1500; i++ {
+		name := fmt.Sprintf("same%04d", i)
+		cl.put("a/"+name, []byte(name))
+		cl.put("b/"+name, []byte(name))
+	}
+	cl.put("a/removed.txt", []byte("removed"))
+	cl.put("a/sub/changed.txt", []byte("v1"))
+	cl.put("b/sub/changed.txt", []byte("v2"))
+	cl.put("b/added.txt", []byte("added"))
+	cl.put("b/sub/", nil)
+
+	fsys := s3fs.New(cl, "test")
+
+	onlyA, onlyB, differ, err := fsys.Diff("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"removed.txt"}; !reflect.DeepEqual(onlyA, want) {
+		t.Errorf("only in a: want %q; got %q", want, onlyA)
+	}
+	if want := []string{"added.txt"}; !reflect.DeepEqual(onlyB, want) {
+		t.Errorf("only in b: want %q; got %q", want, onlyB)
+	}
+	if want := []string{"sub/changed.txt"}; !reflect.DeepEqual(differ, want) {
+		t.Errorf("differ: want %q; got %q", want, differ)
+	}
+}
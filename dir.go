@@ -5,9 +5,9 @@ import (
 	"errors"
 	"io"
 	"io/fs"
-	"path"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,15 +18,34 @@ var _ fs.ReadDirFile = (*dir)(nil)
 
 type dir struct {
 	fileInfo
-	s3cl   S3Client
-	bucket string
+	fsys   *S3FS
 	marker *string
 	done   bool
-	buf    []fs.DirEntry
-	dirs   map[dirEntry]bool
+	// lastList is when the previous page was requested.
+	lastList time.Time
+	// namePrefix restricts the listing to entries whose name starts with it.
+	namePrefix string
+	// startAfter restricts the listing to entries whose name sorts after it.
+	startAfter string
+	buf        []fs.DirEntry
+	dirs       map[dirEntry]bool
+
+	// sep follows the directory name in the keys below it: "/" if empty,
+	// or the delimiter set by WithDelimiter.
+	sep string
+
+	// ctx is used for the listing requests if set.
+	ctx context.Context
+
+	// modTime is computed once for WithDirModTime.
+	modTimeOnce sync.Once
+	modTime     time.Time
 }
 
 func (d *dir) Stat() (fs.FileInfo, error) {
+	if d.fsys.dirModTime {
+		return d, nil
+	}
 	return &d.fileInfo, nil
 }
 
@@ -34,7 +53,7 @@ func (d *dir) Read([]byte) (int, error) {
 	return 0, &fs.PathError{
 		Op:   "read",
 		Path: d.name,
-		Err:  errors.New("is a directory"),
+		Err:  ErrIsDir,
 	}
 }
 
@@ -56,6 +75,13 @@ func (d *dir) ReadDir(n int) (des []fs.DirEntry, err error) {
 		return des, nil
 	}
 
+	// the pages of a directory bucket are not sorted.
+	if d.fsys.directoryBucket {
+		if err := d.readAll(); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+
 loop:
 	for len(d.buf) < n {
 		switch err := d.readNext(); {
@@ -102,19 +128,56 @@ func (d *dir) readNext() error {
 	case name == ".":
 		name = ""
 	default:
-		name += "/"
+		name += d.separator()
+	}
+	prefix := name + d.namePrefix
+	if d.fsys.directoryBucket {
+		// directory buckets only list prefixes ending with a "/".
+		prefix = name
 	}
 
-	out, err := d.s3cl.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
-		Bucket:    &d.bucket,
-		Delimiter: aws.String("/"),
-		Prefix:    &name,
-	})
-	if err != nil {
+	ctx := d.context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := d.fsys.paceList(ctx, d.lastList); err != nil {
 		return err
 	}
+	d.lastList = time.Now()
+
+	in := &s3.ListObjectsV2Input{
+		Bucket:            &d.fsys.bucket,
+		RequestPayer:      d.fsys.requestPayer,
+		Delimiter:         d.fsys.listDelimiter(),
+		Prefix:            &prefix,
+		ContinuationToken: d.marker,
+		FetchOwner:        d.fsys.fetchOwner,
+		MaxKeys:           d.fsys.maxKeys,
+	}
+	if d.startAfter != "" && d.marker == nil && !d.fsys.directoryBucket {
+		in.StartAfter = aws.String(name + d.startAfter)
+	}
+
+	out, err := d.fsys.cl.ListObjectsV2(ctx, in, d.fsys.optFns...)
+	if err != nil {
+		return d.fsys.bucketErr(err)
+	}
+	d.fsys.auditList(prefix, out)
+
+	empty := len(out.CommonPrefixes)+len(out.Contents) == 0
+	if d.fsys.directoryBucket {
+		// pages before the last one can be empty.
+		empty = !out.IsTruncated && len(d.buf)+len(d.dirs)+len(out.CommonPrefixes)+len(out.Contents) == 0
+	}
 
-	if d.name != "." && len(out.CommonPrefixes)+len(out.Contents) == 0 {
+	// an empty filtered listing only means that nothing matched.
+	if d.name != "." && d.namePrefix == "" && d.startAfter == "" && empty {
+		// a directory opened without a stat can be below the delimiter.
+		if sep := d.fsys.customDelimiter(); d.sep == "" && d.marker == nil && sep != "" {
+			d.sep = sep
+			return d.readNext()
+		}
 		return &fs.PathError{
 			Op:   "readdir",
 			Path: strings.TrimSuffix(name, "/"),
@@ -122,8 +185,8 @@ func (d *dir) readNext() error {
 		}
 	}
 
-	//d.marker = out.NextMarker
-	d.done = out.IsTruncated
+	d.marker = out.NextContinuationToken
+	d.done = !out.IsTruncated
 
 	if d.dirs == nil {
 		d.dirs = make(map[dirEntry]bool)
@@ -133,31 +196,66 @@ func (d *dir) readNext() error {
 		if p.Prefix == nil {
 			continue
 		}
-
-		de := dirEntry{
-			fileInfo: fileInfo{
-				name: path.Base(*p.Prefix),
-				mode: fs.ModeDir,
-			},
+		entry, below := d.entryName(name, *p.Prefix)
+		if entry == "" || !d.keep(entry) {
+			continue
 		}
 
-		if _, ok := d.dirs[de]; !ok {
-			d.dirs[de] = false
+		key := strings.TrimSuffix(*p.Prefix, aws.ToString(d.fsys.listDelimiter()))
+		if below {
+			key = name + entry
 		}
+		d.addDir(entry, key)
 	}
 
+	start := len(d.buf)
+	var keys []string
 	for _, o := range out.Contents {
-		if o.Key == nil {
+		// directory markers, such as the "name/" of the directory itself,
+		// are not files.
+		if o.Key == nil || (isDirMarker(o) && o.Size == 0) {
+			continue
+		}
+		entry, below := d.entryName(name, *o.Key)
+		if entry == "" || !d.keep(entry) {
+			continue
+		}
+		if below {
+			d.addDir(entry, name+entry)
 			continue
 		}
+		keys = append(keys, *o.Key)
 
-		d.buf = append(d.buf, dirEntry{
+		de := dirEntry{
 			fileInfo: fileInfo{
-				name:    path.Base(*o.Key),
+				name:    entry,
 				size:    o.Size,
 				modTime: derefTime(o.LastModified),
+				key:     *o.Key,
 			},
-		})
+			relName: entry,
+		}
+
+		if d.fsys.fetchOwner && o.Owner != nil {
+			de.sys = &ObjectInfo{
+				OwnerID:          aws.ToString(o.Owner.ID),
+				OwnerDisplayName: aws.ToString(o.Owner.DisplayName),
+			}
+		}
+
+		d.buf = append(d.buf, de)
+	}
+
+	if d.fsys.prefetchConcurrency > 0 {
+		if err := d.prefetchMetadata(d.buf[start:], keys); err != nil {
+			return err
+		}
+	}
+
+	if d.fsys.listTagsConcurrency > 0 {
+		if err := d.fetchTags(d.buf[start:], keys); err != nil {
+			return err
+		}
 	}
 
 	d.mergeDirFiles()
@@ -168,6 +266,149 @@ func (d *dir) readNext() error {
 	return nil
 }
 
+// entryName returns the name of the entry of key, a key or common prefix
+// listed below prefix, and whether the entry is a directory because key is
+// below one, such as a common prefix. The name is empty for the key of
+// prefix itself and for keys with an empty element, such as "dir//file",
+// which are not entries.
+//
+// The name is a single element whatever the delimiter: a key below a
+// subdirectory, as listed by stores that do not group keys or with a
+// delimiter other than "/", names the subdirectory. Flat listings, with an
+// empty delimiter, name every key by its path below prefix instead.
+func (d *dir) entryName(prefix, key string) (string, bool) {
+	name := strings.TrimPrefix(key, prefix)
+	if d.fsys.listDelimiter() == nil {
+		if strings.HasPrefix(name, "/") || strings.Contains(name, "//") {
+			return "", false
+		}
+		return name, false
+	}
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		return name[:i], true
+	}
+	return strings.TrimSuffix(name, aws.ToString(d.fsys.listDelimiter())), false
+}
+
+// separator returns the separator following the directory name in keys.
+func (d *dir) separator() string {
+	if d.sep == "" {
+		return "/"
+	}
+	return d.sep
+}
+
+// addDir adds the subdirectory name, whose key is key, to the listing
+// unless it is already listed.
+func (d *dir) addDir(name, key string) {
+	de := dirEntry{
+		fileInfo: fileInfo{
+			name: name,
+			mode: fs.ModeDir,
+			key:  key,
+		},
+		relName: name,
+	}
+
+	if _, ok := d.dirs[de]; !ok {
+		d.dirs[de] = false
+	}
+}
+
+// keep reports whether the entry name belongs to the listing. The prefix
+// sent to directory buckets omits namePrefix, which is checked here.
+func (d *dir) keep(name string) bool {
+	if d.fsys.directoryBucket && !strings.HasPrefix(name, d.namePrefix) {
+		return false
+	}
+	return d.after(name)
+}
+
+// after reports whether the entry name sorts after startAfter. The listing
+// starts after the key of startAfter, which still lets through a directory
+// of that name, as its prefix sorts after the key.
+func (d *dir) after(name string) bool {
+	return d.startAfter == "" || name > d.startAfter
+}
+
+func (d *dir) context() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return context.TODO()
+}
+
+// prefetchMetadata fills the content type, metadata and HTTP headers of
+// des, whose keys are keys, with concurrent HeadObjects.
+func (d *dir) prefetchMetadata(des []fs.DirEntry, keys []string) error {
+	return d.updateEntries(des, keys, d.fsys.prefetchConcurrency, func(key string, info *ObjectInfo) error {
+		head, err := d.fsys.cl.HeadObject(d.context(), d.fsys.headInput(&s3.HeadObjectInput{
+			Bucket: &d.fsys.bucket,
+			Key:    aws.String(key),
+		}), d.fsys.optFns...)
+		if err != nil {
+			return err
+		}
+
+		info.ContentType = aws.ToString(head.ContentType)
+		info.Metadata = head.Metadata
+		info.setHeaders(head.CacheControl, head.ContentDisposition, head.ContentEncoding, head.Expires)
+		return nil
+	})
+}
+
+// updateEntries calls update with the ObjectInfo of every entry of des,
+// whose keys are keys, with at most concurrency calls in flight. Entries
+// whose object was deleted since it was listed are left as they are. The
+// first other error is returned.
+func (d *dir) updateEntries(des []fs.DirEntry, keys []string, concurrency int, update func(key string, info *ObjectInfo) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+
+	sem := make(chan struct{}, concurrency)
+	for i := range des {
+		i := i
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			// every goroutine owns a distinct element of des.
+			de := des[i].(dirEntry)
+			info := &ObjectInfo{}
+			if de.sys != nil {
+				*info = *de.sys
+			}
+
+			if err := update(keys[i], info); err != nil {
+				// the object was deleted since it was listed.
+				if isNotFoundErr(err) {
+					return
+				}
+
+				mu.Lock()
+				if rerr == nil {
+					rerr = err
+				}
+				mu.Unlock()
+				return
+			}
+
+			de.sys = info
+			des[i] = de
+		}()
+	}
+	wg.Wait()
+
+	return rerr
+}
+
 func (d *dir) mergeDirFiles() {
 	if d.buf == nil {
 		// according to fs docs ReadDir should never return nil slice,
@@ -175,6 +416,11 @@ func (d *dir) mergeDirFiles() {
 		d.buf = []fs.DirEntry{}
 	}
 
+	// entries of unsorted pages can only be merged once all are listed.
+	if d.fsys.directoryBucket && !d.done {
+		return
+	}
+
 	// we need a current len for sort.Search that doesn't change; otherwise
 	// we could not append to the same slice.
 	l := len(d.buf)
@@ -201,6 +447,17 @@ func (d *dir) mergeDirFiles() {
 
 type dirEntry struct {
 	fileInfo
+	// relName is the name of the entry relative to its directory: its key
+	// below the directory in flat listings, which can have slashes, and
+	// its base name otherwise. It defaults to the base name.
+	relName string
+}
+
+func (de dirEntry) Name() string {
+	if de.relName != "" {
+		return de.relName
+	}
+	return de.fileInfo.Name()
 }
 
 func (de dirEntry) Type() fs.FileMode          { return de.Mode().Type() }
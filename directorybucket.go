@@ -0,0 +1,22 @@
+package s3fs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// directoryBucketSuffix ends the names of S3 Express One Zone directory
+// buckets, e.g. "bucket--use1-az4--x-s3".
+const directoryBucketSuffix = "--x-s3"
+
+// WithDirectoryBucket adapts the filesystem to S3 Express One Zone
+// directory buckets. Their listings are not sorted, only accept prefixes
+// that end with "/" and can return empty pages before the last one, so
+// directories are listed in full before ReadDir returns and filtered and
+// sorted by S3FS. It panics if the bucket name does not end with "--x-s3".
+func WithDirectoryBucket(fsys *S3FS) {
+	if !strings.HasSuffix(fsys.bucket, directoryBucketSuffix) {
+		panic("s3fs: " + strconv.Quote(fsys.bucket) + " is not a directory bucket")
+	}
+	fsys.directoryBucket = true
+}
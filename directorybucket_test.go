@@ -0,0 +1,136 @@
+package s3fs_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// unsortedClient lists like a directory bucket: every page is in reverse
+// order and the first page of a listing is empty.
+type unsortedClient struct {
+	*memClient
+}
+
+func (c unsortedClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if in.ContinuationToken == nil {
+		return &s3.ListObjectsV2Output{
+			IsTruncated:           true,
+			NextContinuationToken: aws.String(""),
+		}, nil
+	}
+	if aws.ToString(in.ContinuationToken) == "" {
+		in.ContinuationToken = nil
+	}
+
+	out, err := c.memClient.ListObjectsV2(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(out.Contents)-1; i < j; i, j = i+1, j-1 {
+		out.Contents[i], out.Contents[j] = out.Contents[j], out.Contents[i]
+	}
+	for i, j := 0, len(out.CommonPrefixes)-1; i < j; i, j = i+1, j-1 {
+		out.CommonPrefixes[i], out.CommonPrefixes[j] = out.CommonPrefixes[j], out.CommonPrefixes[i]
+	}
+	return out, nil
+}
+
+func TestDirectoryBucket(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"dir/a.txt",
+		"dir/b/c.txt",
+		"dir/d.txt",
+		"dir/e/f.txt",
+		"dir/g.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+	cl.hook = func(op string, in interface{}) error {
+		if in, ok := in.(*s3.ListObjectsV2Input); ok {
+			in.MaxKeys = 2
+		}
+		return nil
+	}
+
+	fsys := s3fs.New(unsortedClient{cl}, "bucket--use1-az4--x-s3", s3fs.WithDirectoryBucket)
+
+	t.Run("readdir", func(t *testing.T) {
+		f, err := fsys.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var names []string
+		for {
+			des, err := f.(fs.ReadDirFile).ReadDir(2)
+			for _, de := range des {
+				names = append(names, de.Name())
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if want := []string{"a.txt", "b", "d.txt", "e", "g.txt"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("want %q; got %q", want, names)
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		fi, err := fsys.Stat("dir/e")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Error("want a directory")
+		}
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		cl.inputs = nil
+
+		got, err := fsys.Glob("dir/[de]*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"dir/d.txt", "dir/e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok && !strings.HasSuffix(aws.ToString(in.Prefix), "/") {
+				t.Errorf("want prefixes to end with a /; got %q", aws.ToString(in.Prefix))
+			}
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, err := fs.ReadDir(fsys, "missing"); !s3fs.IsNotExist(err) {
+			t.Errorf("want not exist; got %v", err)
+		}
+	})
+
+	t.Run("invalid bucket", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		s3fs.New(cl, "bucket", s3fs.WithDirectoryBucket)
+	})
+}
@@ -0,0 +1,74 @@
+package s3fs
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithDirModTime makes the ModTime of directories returned by Open and Stat
+// the newest LastModified of the objects directly in them, instead of the
+// zero time. It is computed with a listing of the directory the first time
+// ModTime is called and cached afterwards; a directory holding only
+// subdirectories, or whose listing fails, still reports the zero time.
+//
+// Directory entries returned by ReadDir keep the zero time.
+func WithDirModTime(fsys *S3FS) { fsys.dirModTime = true }
+
+// ModTime returns the modification time of the directory, see
+// WithDirModTime.
+func (d *dir) ModTime() time.Time {
+	if !d.fsys.dirModTime {
+		return d.fileInfo.ModTime()
+	}
+
+	d.modTimeOnce.Do(func() { d.modTime = d.newestChild() })
+	return d.modTime
+}
+
+// newestChild returns the newest LastModified of the objects directly in
+// the directory.
+func (d *dir) newestChild() time.Time {
+	prefix := d.name + "/"
+	if d.name == "." {
+		prefix = ""
+	}
+
+	var (
+		newest time.Time
+		token  *string
+		last   time.Time
+	)
+	for {
+		ctx := d.context()
+		if err := d.fsys.paceList(ctx, last); err != nil {
+			return time.Time{}
+		}
+		last = time.Now()
+
+		out, err := d.fsys.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &d.fsys.bucket,
+			RequestPayer:      d.fsys.requestPayer,
+			Delimiter:         aws.String("/"),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+			MaxKeys:           d.fsys.maxKeys,
+		}, d.fsys.optFns...)
+		if err != nil {
+			return time.Time{}
+		}
+		d.fsys.auditList(prefix, out)
+
+		for _, o := range out.Contents {
+			if mt := derefTime(o.LastModified); mt.After(newest) {
+				newest = mt
+			}
+		}
+
+		if !out.IsTruncated {
+			return newest
+		}
+		token = out.NextContinuationToken
+	}
+}
@@ -0,0 +1,69 @@
+package s3fs_test
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWithDirModTime(t *testing.T) {
+	cl := newMemClient()
+	newest := cl.now.Add(2 * time.Hour)
+	cl.put("dir/a.txt", nil).lastModified = cl.now.Add(time.Hour)
+	cl.put("dir/b.txt", nil).lastModified = newest
+	cl.put("dir/sub/c.txt", nil).lastModified = cl.now.Add(3 * time.Hour)
+	cl.put("only/sub/d.txt", nil)
+
+	fsys := s3fs.New(cl, "test", s3fs.WithDirModTime)
+
+	fi, err := fs.Stat(fsys, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lists := cl.count("ListObjectsV2")
+	for i := 0; i < 3; i++ {
+		// objects in subdirectories are not considered.
+		if mt := fi.ModTime(); !mt.Equal(newest) {
+			t.Errorf("want %v; got %v", newest, mt)
+		}
+	}
+	if n := cl.count("ListObjectsV2") - lists; n != 1 {
+		t.Errorf("want 1 listing for ModTime; got %d", n)
+	}
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || !fi.ModTime().Equal(newest) {
+		t.Errorf("want %v from the opened directory; got %v, %v", newest, fi.ModTime(), err)
+	}
+
+	fi, err = fs.Stat(fsys, "only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().IsZero() {
+		t.Errorf("want the zero time for a directory without objects; got %v", fi.ModTime())
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test")
+		fi, err := fs.Stat(fsys, "dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lists := cl.count("ListObjectsV2")
+		if !fi.ModTime().IsZero() {
+			t.Errorf("want the zero time; got %v", fi.ModTime())
+		}
+		if n := cl.count("ListObjectsV2") - lists; n != 0 {
+			t.Errorf("want no listing; got %d", n)
+		}
+	})
+}
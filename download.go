@@ -0,0 +1,103 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithConcurrentDownload makes ReadFile download objects of at least
+// partSize bytes with manager.Downloader, fetching parts of partSize bytes
+// with concurrency ranged GetObjects at a time instead of streaming a
+// single body. Smaller objects are read with a single GetObject, after the
+// HeadObject that tells their size.
+//
+// A partSize below 1 means manager.DefaultDownloadPartSize (5 MiB) and a
+// concurrency below 1 means manager.DefaultDownloadConcurrency (5). Files
+// returned by Open still stream their body; see WithParallelRangeReads.
+func WithConcurrentDownload(partSize int64, concurrency int) Option {
+	if partSize < 1 {
+		partSize = manager.DefaultDownloadPartSize
+	}
+	if concurrency < 1 {
+		concurrency = manager.DefaultDownloadConcurrency
+	}
+	return func(fsys *S3FS) {
+		fsys.download = &download{partSize: partSize, concurrency: concurrency}
+	}
+}
+
+type download struct {
+	partSize    int64
+	concurrency int
+}
+
+// downloadObject downloads name with manager.Downloader if it is large
+// enough and returns it as if it was read with GetObject. It returns a nil
+// output and no error if name is small and must be read with GetObject.
+func (f *S3FS) downloadObject(ctx context.Context, name string) (*s3.GetObjectOutput, error) {
+	head, err := f.headObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if head.ContentLength < f.download.partSize {
+		return nil, nil
+	}
+
+	d := manager.NewDownloader(f.cl, func(d *manager.Downloader) {
+		d.PartSize = f.download.partSize
+		d.Concurrency = f.download.concurrency
+		d.ClientOptions = append(d.ClientOptions, f.optFns...)
+	})
+
+	// the parts must all come from the object that was sized.
+	buf := manager.NewWriteAtBuffer(make([]byte, 0, head.ContentLength))
+	n, err := d.Download(ctx, buf, f.getInput(&s3.GetObjectInput{
+		Bucket:  &f.bucket,
+		Key:     aws.String(name),
+		IfMatch: head.ETag,
+	}))
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return nil, fmt.Errorf("s3fs: download %s: %w", name, ErrFileChanged)
+		}
+		return nil, err
+	}
+
+	data := buf.Bytes()[:n]
+	return &s3.GetObjectOutput{
+		Body:            io.NopCloser(bytes.NewReader(data)),
+		ContentLength:   n,
+		ContentEncoding: head.ContentEncoding,
+		ETag:            head.ETag,
+		Metadata:        head.Metadata,
+	}, nil
+}
+
+// fetchObject gets the object name, downloading it with WithConcurrentDownload
+// if it is large enough.
+func (f *S3FS) fetchObject(ctx context.Context, name string) (*s3.GetObjectOutput, error) {
+	if f.download != nil {
+		if out, err := f.downloadObject(ctx, name); out != nil || err != nil {
+			return out, err
+		}
+	}
+
+	out, err := f.getObject(ctx, &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+	return out, nil
+}
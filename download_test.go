@@ -0,0 +1,111 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestConcurrentDownload(t *testing.T) {
+	content := make([]byte, 3<<20+123)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	cl := newMemClient()
+	cl.put("large.bin", content)
+	cl.put("small.bin", content[:100])
+
+	want, err := s3fs.New(cl, "test").ReadFile("large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithConcurrentDownload(1<<20, 3))
+
+	t.Run("large", func(t *testing.T) {
+		before := cl.count("GetObject")
+		got, err := fsys.ReadFile("large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) || !bytes.Equal(got, want) {
+			t.Errorf("want the %d bytes of a sequential read; got %d bytes", len(want), len(got))
+		}
+		if n := cl.count("GetObject") - before; n != 4 {
+			t.Errorf("want 4 parts; got %d GetObjects", n)
+		}
+	})
+
+	t.Run("small", func(t *testing.T) {
+		before := cl.count("GetObject")
+		got, err := fsys.ReadFile("small.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content[:100]) {
+			t.Error("unexpected content")
+		}
+		if n := cl.count("GetObject") - before; n != 1 {
+			t.Errorf("want a single GetObject; got %d", n)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := fsys.ReadFile("missing.bin"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("retried head", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("large.bin", content)
+
+		failed := false
+		cl.hook = func(op string, in interface{}) error {
+			if op == "HeadObject" && !failed {
+				failed = true
+				return apiError(http.StatusServiceUnavailable, "ServiceUnavailable")
+			}
+			return nil
+		}
+
+		fsys := s3fs.New(cl, "test", s3fs.WithConcurrentDownload(1<<20, 1), s3fs.WithRetry(2, time.Millisecond))
+		got, err := fsys.ReadFile("large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Error("unexpected content")
+		}
+		if n := cl.count("HeadObject"); n != 2 {
+			t.Errorf("want 2 HeadObjects; got %d", n)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("large.bin", content)
+
+		gets := 0
+		cl.hook = func(op string, in interface{}) error {
+			if _, ok := in.(*s3.GetObjectInput); ok {
+				if gets++; gets == 2 {
+					cl.put("large.bin", content[1:])
+				}
+			}
+			return nil
+		}
+
+		fsys := s3fs.New(cl, "test", s3fs.WithConcurrentDownload(1<<20, 1))
+		if _, err := fsys.ReadFile("large.bin"); !errors.Is(err, s3fs.ErrFileChanged) {
+			t.Errorf("want %v; got %v", s3fs.ErrFileChanged, err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// defaultEndpointRegion is the region NewWithEndpoint signs requests for.
+// Most S3-compatible stores accept any region, and MinIO expects this one.
+const defaultEndpointRegion = "us-east-1"
+
+// NewWithEndpoint returns a new filesystem for bucket on the S3-compatible
+// store at endpoint, such as MinIO or Backblaze B2, with a client built for
+// it. endpoint is the http or https URL of the store, e.g.
+// "http://localhost:9000". pathStyle sends the bucket in the path of the
+// URL ("endpoint/bucket/key") instead of in the host name
+// ("bucket.endpoint/key"), which most stores other than AWS require.
+//
+// Requests are signed with creds for the region us-east-1; a nil creds
+// makes anonymous requests. Other client settings, such as the region, can
+// be changed with WithRequestOptions.
+func NewWithEndpoint(bucket, endpoint string, pathStyle bool, creds aws.CredentialsProvider, opts ...Option) (*S3FS, error) {
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("s3fs: invalid endpoint %q: must be an http or https URL", endpoint)
+	}
+	if !validBucketName(bucket) {
+		return nil, fmt.Errorf("s3fs: invalid bucket name %q", bucket)
+	}
+
+	cl := s3.New(s3.Options{
+		Region:           defaultEndpointRegion,
+		Credentials:      creds,
+		EndpointResolver: s3.EndpointResolverFromURL(endpoint),
+		UsePathStyle:     pathStyle,
+	})
+	return New(cl, bucket, opts...), nil
+}
+
+// WithEndpointPerOperation sends requests to the endpoint fn returns for
+// the name of their S3 operation, e.g. "GetObject" or "ListObjectsV2". This
+// lets reads and listings target different endpoints, such as a specific
+// availability zone. If fn returns an empty string the client's endpoint is
+// used.
+//
+// The endpoints must be absolute http or https URLs; requests for which fn
+// returns anything else fail. Only clients built with the s3 package
+// support this option.
+func WithEndpointPerOperation(fn func(op string) string) Option {
+	return WithRequestOptions(func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return resolveOperationEndpoint(stack, fn(stack.ID()))
+		})
+	})
+}
+
+// resolveOperationEndpoint makes the operation of stack resolve to the
+// endpoint rawurl.
+func resolveOperationEndpoint(stack *middleware.Stack, rawurl string) error {
+	if rawurl == "" {
+		return nil
+	}
+
+	if u, err := url.Parse(rawurl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("s3fs: invalid endpoint %q for %s: must be an http or https URL", rawurl, stack.ID())
+	}
+
+	m, ok := stack.Serialize.Get((&s3.ResolveEndpoint{}).ID())
+	if !ok {
+		return nil
+	}
+	resolve, ok := m.(*s3.ResolveEndpoint)
+	if !ok {
+		return nil
+	}
+
+	_, err := stack.Serialize.Swap(resolve.ID(), &s3.ResolveEndpoint{
+		Resolver: s3.EndpointResolverFunc(func(region string, _ s3.EndpointResolverOptions) (aws.Endpoint, error) {
+			return aws.Endpoint{
+				URL:           rawurl,
+				SigningRegion: region,
+				Source:        aws.EndpointSourceCustom,
+			}, nil
+		}),
+		Options: resolve.Options,
+	})
+	return err
+}
@@ -0,0 +1,197 @@
+package s3fs
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrIsDir is returned when a directory is read as if it was a file.
+var ErrIsDir = errors.New("is a directory")
+
+// ErrBadDigest is matched by errors of uploads whose content did not match
+// the checksum sent with it, which usually means it was corrupted in
+// transit.
+var ErrBadDigest = errors.New("bad digest")
+
+// badDigestError marks err as ErrBadDigest while keeping it unwrappable.
+type badDigestError struct{ err error }
+
+func (e badDigestError) Error() string { return "bad digest: " + e.err.Error() }
+
+func (e badDigestError) Unwrap() error { return e.err }
+
+func (badDigestError) Is(target error) bool { return target == ErrBadDigest }
+
+// isBadDigest reports whether S3 rejected an upload because of a checksum
+// mismatch.
+func isBadDigest(err error) bool {
+	switch errorCode(err) {
+	case "BadDigest", "InvalidDigest", "XAmzContentSHA256Mismatch":
+		return true
+	}
+	return false
+}
+
+// ErrNoSuchBucket is matched by errors of requests made to a bucket that
+// does not exist.
+var ErrNoSuchBucket = errors.New("no such bucket")
+
+// noSuchBucketError marks err as ErrNoSuchBucket while keeping it
+// unwrappable.
+type noSuchBucketError struct {
+	bucket string
+	err    error
+}
+
+func (e noSuchBucketError) Error() string {
+	return "bucket " + strconv.Quote(e.bucket) + " does not exist: " + e.err.Error()
+}
+
+func (e noSuchBucketError) Unwrap() error { return e.err }
+
+func (noSuchBucketError) Is(target error) bool { return target == ErrNoSuchBucket }
+
+// isNoSuchBucket reports whether err means that the bucket does not exist.
+func isNoSuchBucket(err error) bool {
+	var nsb *types.NoSuchBucket
+	return errors.As(err, &nsb) || errorCode(err) == "NoSuchBucket"
+}
+
+// bucketErr returns err marked as ErrNoSuchBucket if it was caused by the
+// bucket of f not existing, and err otherwise.
+func (f *S3FS) bucketErr(err error) error {
+	if isNoSuchBucket(err) && !errors.Is(err, ErrNoSuchBucket) {
+		return noSuchBucketError{bucket: f.bucket, err: err}
+	}
+	return err
+}
+
+// ErrPreconditionFailed is returned by conditional operations whose
+// condition, such as an expected ETag, did not hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrNotModified is returned by OpenIfNoneMatch when the object still has
+// the given ETag.
+var ErrNotModified = errors.New("not modified")
+
+// isNotModified reports whether a conditional GetObject failed because the
+// object matched If-None-Match.
+func isNotModified(err error) bool {
+	return errorCode(err) == "NotModified" || httpStatusCode(err) == http.StatusNotModified
+}
+
+// ErrFileChanged is returned when a file opened with WithReadSeeker has to
+// be reopened (by Seek or ReadAt) but the object changed on S3 since it was
+// opened. The ETag captured at open is sent with If-Match to detect this.
+//
+// For compatibility errors.Is(ErrFileChanged, fs.ErrNotExist) reports true.
+var ErrFileChanged error = fileChangedError{}
+
+type fileChangedError struct{}
+
+func (fileChangedError) Error() string { return "file has changed" }
+
+func (fileChangedError) Is(target error) bool { return target == fs.ErrNotExist }
+
+// IsNotExist reports whether err means that the object or directory does
+// not exist.
+func IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || isNotFoundErr(err)
+}
+
+// IsPermission reports whether err was caused by missing permissions,
+// e.g. S3 responding with AccessDenied.
+func IsPermission(err error) bool {
+	if errors.Is(err, fs.ErrPermission) {
+		return true
+	}
+
+	switch errorCode(err) {
+	case "AccessDenied", "AllAccessDisabled", "AccountProblem", "InvalidAccessKeyId", "SignatureDoesNotMatch":
+		return true
+	}
+	return httpStatusCode(err) == http.StatusForbidden && !IsArchived(err)
+}
+
+// permissionError marks err as fs.ErrPermission while keeping it
+// unwrappable.
+type permissionError struct {
+	err error
+}
+
+func (e permissionError) Error() string { return e.err.Error() }
+
+func (e permissionError) Unwrap() error { return e.err }
+
+func (permissionError) Is(target error) bool { return target == fs.ErrPermission }
+
+// permissionErr returns err marked as fs.ErrPermission if it was caused by
+// missing permissions, and err otherwise.
+func permissionErr(err error) error {
+	if err != nil && !errors.Is(err, fs.ErrPermission) && IsPermission(err) {
+		return permissionError{err: err}
+	}
+	return err
+}
+
+// IsThrottled reports whether err means that S3 throttled the request and
+// that it is worth retrying later.
+func IsThrottled(err error) bool {
+	switch errorCode(err) {
+	case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded",
+		"TooManyRequests", "TooManyRequestsException", "RequestThrottled":
+		return true
+	}
+	return httpStatusCode(err) == http.StatusTooManyRequests
+}
+
+// IsArchived reports whether err means that the object is archived (e.g. in
+// the Glacier storage classes) and has to be restored before it can be read.
+func IsArchived(err error) bool {
+	var ios *types.InvalidObjectState
+	if errors.As(err, &ios) {
+		return true
+	}
+
+	var onat *types.ObjectNotInActiveTierError
+	if errors.As(err, &onat) {
+		return true
+	}
+
+	switch errorCode(err) {
+	case "InvalidObjectState", "ObjectNotInActiveTierError":
+		return true
+	}
+	return false
+}
+
+// isPreconditionFailed reports whether a conditional request failed
+// because If-Match did not match.
+func isPreconditionFailed(err error) bool {
+	return errorCode(err) == "PreconditionFailed" || httpStatusCode(err) == http.StatusPreconditionFailed
+}
+
+// errorCode returns the S3 error code of err or an empty string if err is
+// not an API error.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+// httpStatusCode returns the HTTP status code of the response that caused err
+// or 0 if err did not come from a response.
+func httpStatusCode(err error) int {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
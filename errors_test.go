@@ -0,0 +1,210 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	fixtures := []struct {
+		desc  string
+		err   error
+		check func(error) bool
+	}{
+		{
+			desc:  "not exist",
+			check: s3fs.IsNotExist,
+		},
+		{
+			desc:  "permission",
+			err:   apiError(http.StatusForbidden, "AccessDenied"),
+			check: s3fs.IsPermission,
+		},
+		{
+			desc:  "throttled",
+			err:   apiError(http.StatusServiceUnavailable, "SlowDown"),
+			check: s3fs.IsThrottled,
+		},
+		{
+			desc:  "archived",
+			err:   responseError(http.StatusForbidden, &types.InvalidObjectState{}),
+			check: s3fs.IsArchived,
+		},
+	}
+
+	predicates := map[string]func(error) bool{
+		"not exist":  s3fs.IsNotExist,
+		"permission": s3fs.IsPermission,
+		"throttled":  s3fs.IsThrottled,
+		"archived":   s3fs.IsArchived,
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			cl := newMemClient()
+			cl.put("file.txt", []byte("content"))
+			cl.hook = func(op string, in interface{}) error { return f.err }
+
+			fsys := s3fs.New(cl, "test")
+
+			name := "file.txt"
+			if f.err == nil {
+				name = "missing.txt"
+			}
+
+			_, openErr := fsys.Open(name)
+			_, statErr := fsys.Stat(name)
+
+			for op, err := range map[string]error{"open": openErr, "stat": statErr} {
+				if f.desc == "archived" && op == "stat" {
+					// HeadObject does not fail on archived objects.
+					continue
+				}
+
+				if !f.check(err) {
+					t.Errorf("%s: expected %s to match %v", op, f.desc, err)
+				}
+
+				for desc, p := range predicates {
+					if desc != f.desc && p(err) {
+						t.Errorf("%s: did not expect %s to match %v", op, desc, err)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		for desc, p := range predicates {
+			if p(nil) {
+				t.Errorf("did not expect %s to match nil", desc)
+			}
+		}
+	})
+}
+
+func TestNotFoundErrors(t *testing.T) {
+	fixtures := []struct {
+		desc string
+		err  error
+	}{
+		{desc: "typed NoSuchKey", err: responseError(http.StatusNotFound, &types.NoSuchKey{})},
+		{desc: "typed NotFound", err: responseError(http.StatusNotFound, &types.NotFound{})},
+		{desc: "NoSuchKey code", err: apiError(http.StatusNotFound, "NoSuchKey")},
+		{desc: "NotFound code", err: apiError(http.StatusNotFound, "NotFound")},
+		{desc: "bare 404", err: responseError(http.StatusNotFound, errors.New("not found"))},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			cl := newMemClient()
+			cl.hook = func(op string, in interface{}) error {
+				if op == "HeadObject" || op == "GetObject" {
+					return f.err
+				}
+				return nil
+			}
+
+			fsys := s3fs.New(cl, "test")
+
+			_, openErr := fsys.Open("missing.txt")
+			_, statErr := fsys.Stat("missing.txt")
+
+			for op, err := range map[string]error{"open": openErr, "stat": statErr} {
+				if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s: want %v; got %v", op, fs.ErrNotExist, err)
+				}
+			}
+		})
+	}
+
+	t.Run("no such bucket", func(t *testing.T) {
+		cl := newMemClient()
+		cl.hook = func(op string, in interface{}) error {
+			if op == "HeadObject" {
+				// HeadObject responses have no body to tell why.
+				return responseError(http.StatusNotFound, &types.NotFound{})
+			}
+			return apiError(http.StatusNotFound, "NoSuchBucket")
+		}
+
+		fsys := s3fs.New(cl, "missing-bucket")
+
+		_, openErr := fsys.Open("file.txt")
+		_, statErr := fsys.Stat("file.txt")
+		_, readDirErr := fsys.ReadDir(".")
+
+		for op, err := range map[string]error{"open": openErr, "stat": statErr, "readdir": readDirErr} {
+			if !errors.Is(err, s3fs.ErrNoSuchBucket) {
+				t.Errorf("%s: want %v; got %v", op, s3fs.ErrNoSuchBucket, err)
+			}
+			if s3fs.IsNotExist(err) {
+				t.Errorf("%s: did not expect a missing bucket to be a missing file: %v", op, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), `bucket "missing-bucket" does not exist`) {
+				t.Errorf("%s: want the error to name the bucket; got %v", op, err)
+			}
+		}
+	})
+}
+
+func TestPermissionErrors(t *testing.T) {
+	fixtures := []struct {
+		desc string
+		err  error
+	}{
+		{desc: "AccessDenied code", err: apiError(http.StatusForbidden, "AccessDenied")},
+		{desc: "bare 403", err: responseError(http.StatusForbidden, errors.New("forbidden"))},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			cl := newMemClient()
+			cl.put("file.txt", []byte("content"))
+			cl.hook = func(op string, in interface{}) error {
+				if op == "HeadObject" || op == "GetObject" {
+					return f.err
+				}
+				return nil
+			}
+
+			fsys := s3fs.New(cl, "test")
+
+			_, openErr := fsys.Open("file.txt")
+			_, statErr := fsys.Stat("file.txt")
+			_, readErr := fsys.ReadFile("file.txt")
+
+			for op, err := range map[string]error{"open": openErr, "stat": statErr, "readfile": readErr} {
+				if !errors.Is(err, fs.ErrPermission) {
+					t.Errorf("%s: want %v; got %v", op, fs.ErrPermission, err)
+				}
+				if errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%s: did not expect %v to match %v", op, err, fs.ErrNotExist)
+				}
+				if !errors.Is(err, f.err) {
+					t.Errorf("%s: want the S3 error wrapped; got %v", op, err)
+				}
+
+				var perr *fs.PathError
+				if !errors.As(err, &perr) || perr.Path != "file.txt" {
+					t.Errorf("%s: want a *fs.PathError for file.txt; got %v", op, err)
+				}
+			}
+
+			// the denied object is not looked for as a directory.
+			if n := cl.count("ListObjectsV2"); n != 0 {
+				t.Errorf("want no listing; got %d", n)
+			}
+		})
+	}
+}
@@ -6,12 +6,11 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
@@ -20,45 +19,118 @@ var (
 	_ fs.File     = (*file)(nil)
 	_ fs.FileInfo = (*fileInfo)(nil)
 	_ io.Seeker   = (*file)(nil)
+	_ io.ReaderAt = (*file)(nil)
+	_ io.WriterTo = (*file)(nil)
 )
 
 type file struct {
-	cl     S3Client
-	bucket string
-	name   string
+	fsys *S3FS
+	name string
+	// versionID is the version of the object that is read, nil for the
+	// latest one.
+	versionID *string
 
 	io.ReadCloser
 	stat   func() (fs.FileInfo, error)
 	offset int64
+	// end is the offset the body ends at, as announced by its response.
+	end    int64
+	closed bool
 	eTag   string
+	// metadata is the user metadata GetObject returned.
+	metadata map[string]string
+	// contentEncoding is the Content-Encoding GetObject returned.
+	contentEncoding string
+
+	// stripBOM is set while a byte order mark is still to be stripped by
+	// the first Read.
+	stripBOM bool
+	// resumes counts the body errors resumed from since the last read
+	// that made progress.
+	resumes int
+
+	// ctx is used for the requests made while reading if set.
+	ctx context.Context
+	// deadline cancels ctx with SetReadDeadline and on Close.
+	deadline *readDeadline
+
+	rangeCache *rangeCache
+	// window holds the parts last read with WithPartAlignedReads.
+	window *partWindow
 }
 
-func openFile(cl S3Client, bucket string, name string) (fs.File, error) {
-	out, err := cl.GetObject(context.TODO(), &s3.GetObjectInput{
-		Key:    &name,
-		Bucket: &bucket,
-	})
-
+func (f *S3FS) openFile(ctx context.Context, name string) (fs.File, error) {
+	fl, err := f.openFileVersion(ctx, name, nil)
 	if err != nil {
 		return nil, err
 	}
+	return fl, nil
+}
 
-	statFunc := getStatFunc(cl, bucket, name, *out)
+// openFileVersion opens the version versionID of name, or its latest
+// version if versionID is nil.
+func (f *S3FS) openFileVersion(ctx context.Context, name string, versionID *string) (*file, error) {
+	if err := f.validateKey(name); err != nil {
+		return nil, err
+	}
 
-	return &file{
-		cl:         cl,
-		bucket:     bucket,
-		name:       name,
-		ReadCloser: out.Body,
-		stat:       statFunc,
-		offset:     0,
-		eTag:       *out.ETag,
-	}, nil
+	return f.openFileInput(ctx, &s3.GetObjectInput{
+		Key:       &name,
+		Bucket:    &f.bucket,
+		VersionId: versionID,
+	})
 }
 
-func getStatFunc(cl S3Client, bucket string, name string, s3ObjOutput s3.GetObjectOutput) func() (fs.FileInfo, error) {
+// openFileInput opens the object GetObject returns for in.
+func (f *S3FS) openFileInput(ctx context.Context, in *s3.GetObjectInput) (*file, error) {
+	name, versionID := aws.StringValue(in.Key), in.VersionId
+
+	// the body is bound to readCtx, so that canceling it aborts a read
+	// blocked on the body.
+	readCtx, cancel := context.WithCancel(ctx)
+	out, err := f.getObject(readCtx, in)
+	if err != nil {
+		cancel()
+		return nil, f.bucketErr(err)
+	}
+
+	statFunc := getStatFunc(ctx, f, name, versionID, *out)
+
+	fl := &file{
+		fsys:            f,
+		name:            name,
+		versionID:       versionID,
+		ReadCloser:      out.Body,
+		stat:            statFunc,
+		offset:          0,
+		end:             out.ContentLength,
+		eTag:            aws.StringValue(out.ETag),
+		metadata:        out.Metadata,
+		contentEncoding: aws.StringValue(out.ContentEncoding),
+		stripBOM:        f.stripBOM,
+		ctx:             readCtx,
+		deadline:        newReadDeadline(cancel),
+	}
+	if f.partSize > 0 {
+		fl.window = &partWindow{}
+	}
+	return fl, nil
+}
+
+func getStatFunc(ctx context.Context, fsys *S3FS, name string, versionID *string, s3ObjOutput s3.GetObjectOutput) func() (fs.FileInfo, error) {
 	statFunc := func() (fs.FileInfo, error) {
-		return stat(cl, bucket, name)
+		return fsys.stat(ctx, name)
+	}
+	if versionID != nil {
+		statFunc = func() (fs.FileInfo, error) {
+			head, err := fsys.headObject(ctx, name, func(in *s3.HeadObjectInput) {
+				in.VersionId = versionID
+			})
+			if err != nil {
+				return nil, err
+			}
+			return headFileInfo(name, head), nil
+		}
 	}
 
 	if s3ObjOutput.ContentLength > 0 && s3ObjOutput.LastModified != nil {
@@ -70,6 +142,9 @@ func getStatFunc(cl S3Client, bucket string, name string, s3ObjOutput s3.GetObje
 				name:    path.Base(name),
 				size:    s3ObjOutput.ContentLength,
 				modTime: *s3ObjOutput.LastModified,
+				eTag:    aws.StringValue(s3ObjOutput.ETag),
+				sys: withETag(withVersionID(headerInfo(s3ObjOutput.CacheControl, s3ObjOutput.ContentDisposition,
+					s3ObjOutput.ContentEncoding, s3ObjOutput.Expires), s3ObjOutput.VersionId), s3ObjOutput.ETag),
 			}, nil
 		}
 	}
@@ -78,8 +153,107 @@ func getStatFunc(cl S3Client, bucket string, name string, s3ObjOutput s3.GetObje
 }
 
 func (f *file) Read(p []byte) (int, error) {
+	n, err := f.read(p)
+	return n, f.readErr("read", err)
+}
+
+func (f *file) read(p []byte) (int, error) {
+	if f.stripBOM {
+		f.stripBOM = false
+		if f.offset == 0 {
+			if err := f.skipBOM(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if f.fsys.parallelReads > 1 && len(p) >= 2*minParallelChunk {
+		return f.readParallel(p)
+	}
+	return f.readBody(p)
+}
+
+// readBody reads from the current body of the file.
+func (f *file) readBody(p []byte) (int, error) {
 	n, err := f.ReadCloser.Read(p)
 	f.offset += int64(n)
+	if err == io.EOF && f.offset < f.end {
+		err = f.truncated()
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) && f.canResume() {
+		f.resumes++
+		if err = f.resume(); err == nil && n == 0 {
+			return f.readBody(p)
+		}
+		return n, err
+	}
+
+	if n > 0 {
+		f.resumes = 0
+	}
+	return n, err
+}
+
+// WriteTo implements io.WriterTo, so that io.Copy streams the rest of the
+// body to w rather than copying it through a buffer Read by Read. Failed
+// bodies are resumed as with Read; errors of w are returned as they are.
+func (f *file) WriteTo(w io.Writer) (int64, error) {
+	n, err := f.writeTo(w)
+	return n, f.readErr("read", err)
+}
+
+func (f *file) writeTo(w io.Writer) (int64, error) {
+	if f.stripBOM {
+		f.stripBOM = false
+		if f.offset == 0 {
+			if err := f.skipBOM(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var written int64
+	for {
+		body := &bodyReader{r: f.ReadCloser}
+		n, err := io.Copy(w, body)
+		f.offset += n
+		written += n
+		if n > 0 {
+			f.resumes = 0
+		}
+		if err == nil && f.offset < f.end {
+			err = f.truncated()
+			body.err = err
+		}
+
+		if err == nil || body.err == nil || !f.canResume() {
+			return written, err
+		}
+		f.resumes++
+		if err := f.resume(); err != nil {
+			return written, err
+		}
+	}
+}
+
+// truncated returns the error of a body that ended before f.end.
+func (f *file) truncated() error {
+	return fmt.Errorf("s3fs: body of %s ended at byte %d of %d: %w", f.name, f.offset, f.end, io.ErrUnexpectedEOF)
+}
+
+// bodyReader records the error of reading r, to tell it from the errors of
+// the writer io.Copy writes to.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
 	return n, err
 }
 
@@ -116,53 +290,320 @@ func (f *file) Seek(offset int64, whence int) (int64, error) {
 		return 0, errors.New("s3fs.file.Seek: cannot seek. remote file has no etag")
 	}
 
-	if err := f.Close(); err != nil {
+	if err := f.ReadCloser.Close(); err != nil {
 		return f.offset, err
 	}
 
-	if newOffset >= size {
+	if err := f.openAt(newOffset, size); err != nil {
+		return 0, fmt.Errorf("s3fs.file.Seek: %w", err)
+	}
+	return f.offset, nil
+}
+
+// maxDrain is the most unread bytes Close reads from the body, so that the
+// HTTP client can reuse the connection.
+const maxDrain = 4 << 10
+
+// Close closes the body of the file after reading up to 4 KiB of what is
+// left of it, so that the HTTP client can reuse the connection of a body
+// that was read almost to its end. Larger rests are cheaper to drop along
+// with the connection. Closing a closed file returns fs.ErrClosed.
+func (f *file) Close() error {
+	if f.closed {
+		return &fs.PathError{
+			Op:   "close",
+			Path: f.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+	f.closed = true
+
+	// a body reopened lazily after a parallel Read has nothing to drain.
+	if _, ok := f.ReadCloser.(reopenBody); !ok {
+		io.CopyN(io.Discard, f.ReadCloser, maxDrain)
+	}
+	err := f.ReadCloser.Close()
+	if f.deadline != nil {
+		f.deadline.stop()
+	}
+	return err
+}
+
+// openAt replaces the body of the file, which must be closed, with one
+// starting at offset.
+func (f *file) openAt(offset, size int64) error {
+	if offset >= size {
 		f.ReadCloser = io.NopCloser(eofReader{})
-		f.offset = newOffset
-		return f.offset, nil
+		f.offset, f.end = offset, offset
+		return nil
 	}
 
-	rawObject, err := f.cl.GetObject(context.TODO(),
-		&s3.GetObjectInput{
-			Bucket:  aws.String(f.bucket),
-			Key:     aws.String(f.name),
-			Range:   aws.String(fmt.Sprintf("bytes=%d-", newOffset)),
-			IfMatch: aws.String(f.eTag),
-		})
+	in := &s3.GetObjectInput{
+		Bucket:    aws.String(f.fsys.bucket),
+		Key:       aws.String(f.name),
+		Range:     f.fsys.rangeHeader(offset, -1),
+		VersionId: f.versionID,
+	}
+	if f.eTag != "" {
+		in.IfMatch = aws.String(f.eTag)
+	}
+
+	rawObject, err := f.fsys.getObject(f.context(), in)
 
 	if err != nil {
-		var requestFailureError awserr.RequestFailure
-		if errors.As(err, &requestFailureError) && requestFailureError.StatusCode() == http.StatusPreconditionFailed {
-			return 0, fmt.Errorf("s3fs.file.Seek: file has changed while seeking: %w", fs.ErrNotExist)
+		if isPreconditionFailed(err) {
+			return ErrFileChanged
 		}
-		return 0, err
+		return err
 	}
 
-	f.offset = newOffset
-	f.ReadCloser = rawObject.Body
+	if err := f.fsys.skipIgnoredRange(rawObject, offset); err != nil {
+		return err
+	}
 
-	return f.offset, nil
+	f.offset, f.end = offset, size
+	f.ReadCloser = rawObject.Body
+	return nil
 }
 
+// ReadAt implements io.ReaderAt with ranged GetObjects. It does not use or
+// move the offset of the file, so it is safe to call concurrently, also
+// with Read and Seek.
 func (f *file) ReadAt(p []byte, offset int64) (int, error) {
-	_, err := f.Seek(offset, io.SeekStart)
+	n, err := f.readAt(p, offset)
+	return n, f.readErr("read", err)
+}
+
+// readAt reads len(p) bytes at offset with ranged GetObjects, without moving
+// the offset of the file. Ranges are aligned and cached as configured.
+func (f *file) readAt(p []byte, offset int64) (int, error) {
+	if f.window != nil {
+		return f.readAtAligned(p, offset)
+	}
+	return f.fetchRange(p, offset)
+}
+
+// fetchRange reads len(p) bytes at offset through the range cache, if any.
+func (f *file) fetchRange(p []byte, offset int64) (int, error) {
+	if f.rangeCache != nil {
+		return f.readAtCached(p, offset)
+	}
+	return f.readRange(p, offset)
+}
+
+// readAtAligned reads the parts p spans and keeps them in the file's
+// window, so that following reads of the same parts need no request. Only
+// the parts that are not kept are requested.
+func (f *file) readAtAligned(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("s3fs.file.ReadAt: negative offset")
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := stat.Size()
+	if offset >= size {
+		return 0, io.EOF
+	}
+	end := offset + int64(len(p))
+	if end > size {
+		end = size
+	}
+
+	w := f.window
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ps := f.fsys.partSize
+	n := 0
+	for i := offset / ps; i*ps < end; {
+		pos, partStart := offset+int64(n), i*ps
+
+		data, ok := w.get(i)
+		next := i + 1
+		if !ok {
+			// the missing parts that follow are read with the same request.
+			for next*ps < end && !w.has(next) {
+				next++
+			}
+			fetchEnd := next * ps
+			if fetchEnd > size {
+				fetchEnd = size
+			}
+
+			buf := make([]byte, fetchEnd-partStart)
+			m, err := f.fetchRange(buf, partStart)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return n, err
+			}
+			data = buf[:m]
+			for k := i; k < next && (k-i)*ps < int64(len(data)); k++ {
+				lo, hi := (k-i)*ps, (k-i+1)*ps
+				if hi > int64(len(data)) {
+					hi = int64(len(data))
+				}
+				w.put(k, data[lo:hi])
+			}
+		}
+
+		if pos-partStart >= int64(len(data)) {
+			break
+		}
+		n += copy(p[n:], data[pos-partStart:])
+		if partStart+int64(len(data)) < next*ps && partStart+int64(len(data)) < end {
+			// the object is shorter than its size.
+			break
+		}
+		i = next
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// maxWindowParts is the number of parts a partWindow keeps.
+const maxWindowParts = 8
+
+// partWindow keeps the parts of an object last read for
+// WithPartAlignedReads, evicting the least recently used ones.
+type partWindow struct {
+	mu    sync.Mutex
+	parts map[int64][]byte
+	// order holds the indexes of parts, least recently used first.
+	order []int64
+}
+
+// get returns the part i if it is kept.
+func (w *partWindow) get(i int64) ([]byte, bool) {
+	data, ok := w.parts[i]
+	if ok {
+		w.touch(i)
+	}
+	return data, ok
+}
+
+func (w *partWindow) has(i int64) bool {
+	_, ok := w.parts[i]
+	return ok
+}
+
+// put keeps data as the part i.
+func (w *partWindow) put(i int64, data []byte) {
+	if w.parts == nil {
+		w.parts = make(map[int64][]byte)
+	}
+	if _, ok := w.parts[i]; !ok && len(w.parts) == maxWindowParts {
+		delete(w.parts, w.order[0])
+		w.order = w.order[1:]
+	}
+	w.parts[i] = data
+	w.touch(i)
+}
+
+// touch marks the part i as the most recently used.
+func (w *partWindow) touch(i int64) {
+	for j, k := range w.order {
+		if k == i {
+			w.order = append(w.order[:j], w.order[j+1:]...)
+			break
+		}
+	}
+	w.order = append(w.order, i)
+}
+
+// readAtCached implements ReadAt on top of the range cache. Unlike the
+// regular ReadAt it does not move the offset of the file.
+func (f *file) readAtCached(p []byte, offset int64) (int, error) {
+	if data, ok := f.rangeCache.get(f.name, f.eTag, offset, len(p)); ok {
+		n := copy(p, data)
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+
+	n, err := f.readRange(p, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	f.rangeCache.put(f.name, f.eTag, offset, len(p), p[:n])
+	return n, err
+}
+
+// readRange reads len(p) bytes starting at offset with a single ranged
+// GetObject. It returns io.EOF if fewer bytes were available.
+func (f *file) readRange(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, errors.New("s3fs.file.ReadAt: negative offset")
+	}
+
+	stat, err := f.Stat()
 	if err != nil {
 		return 0, err
 	}
-	return f.Read(p)
+
+	if offset >= stat.Size() || len(p) == 0 {
+		if offset >= stat.Size() {
+			return 0, io.EOF
+		}
+		return 0, nil
+	}
+
+	in := &s3.GetObjectInput{
+		Bucket:    aws.String(f.fsys.bucket),
+		Key:       aws.String(f.name),
+		Range:     f.fsys.rangeHeader(offset, offset+int64(len(p))-1),
+		VersionId: f.versionID,
+	}
+	if f.eTag != "" {
+		in.IfMatch = aws.String(f.eTag)
+	}
+
+	out, err := f.fsys.getObject(f.context(), in)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return 0, fmt.Errorf("s3fs.file.ReadAt: %w", ErrFileChanged)
+		}
+		return 0, err
+	}
+
+	if err := f.fsys.skipIgnoredRange(out, offset); err != nil {
+		return 0, fmt.Errorf("s3fs.file.ReadAt: %w", err)
+	}
+	defer out.Body.Close()
+
+	n, err := io.ReadFull(out.Body, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
 }
 
 func (f file) Stat() (fs.FileInfo, error) { return f.stat() }
 
+func (f *file) context() context.Context {
+	if f.ctx != nil {
+		return f.ctx
+	}
+	return context.TODO()
+}
+
 type fileInfo struct {
 	name    string
 	size    int64
 	mode    fs.FileMode
 	modTime time.Time
+	eTag    string
+	sys     *ObjectInfo
+	// key is the full key of listed entries, whose name is relative to
+	// their directory. It defaults to name.
+	key string
 }
 
 func (fi fileInfo) Name() string       { return path.Base(fi.name) }
@@ -170,7 +611,98 @@ func (fi fileInfo) Size() int64        { return fi.size }
 func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
 func (fi fileInfo) ModTime() time.Time { return fi.modTime }
 func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
-func (fi fileInfo) Sys() interface{}   { return nil }
+func (fi fileInfo) Sys() interface{} {
+	if fi.sys == nil {
+		return nil
+	}
+	return fi.sys
+}
+
+// Key returns the full key of the object, or the path of the directory, as
+// it is passed to Open.
+func (fi fileInfo) Key() string {
+	if fi.key != "" {
+		return fi.key
+	}
+	return fi.name
+}
+
+// ObjectInfo holds S3 specific information about an object. FileInfo.Sys
+// returns *ObjectInfo when any of it is known, otherwise it returns nil.
+type ObjectInfo struct {
+	// OwnerID and OwnerDisplayName are only set on listed objects
+	// if WithFetchOwner is used.
+	OwnerID          string
+	OwnerDisplayName string
+
+	// ContentType and Metadata are only set on listed objects if
+	// WithListPrefetchMetadata is used.
+	ContentType string
+	Metadata    map[string]string
+
+	// Tags are only set on listed objects if WithListTags is used.
+	Tags map[string]string
+
+	// VersionID is the version of the object, set by Stat and on opened
+	// files for objects of versioned buckets.
+	VersionID string
+
+	// ETag is the entity tag of the object, set by Stat and on opened
+	// files. It can be passed to OpenIfNoneMatch later on.
+	ETag string
+
+	// The HTTP caching headers are set whenever S3 returned them, e.g. for
+	// Stat and for files opened with Open.
+	CacheControl       string
+	ContentDisposition string
+	ContentEncoding    string
+	Expires            time.Time
+}
+
+// setHeaders sets the HTTP caching headers of info.
+func (info *ObjectInfo) setHeaders(cacheControl, contentDisposition, contentEncoding *string, expires *time.Time) {
+	info.CacheControl = aws.StringValue(cacheControl)
+	info.ContentDisposition = aws.StringValue(contentDisposition)
+	info.ContentEncoding = aws.StringValue(contentEncoding)
+	info.Expires = derefTime(expires)
+}
+
+// headerInfo returns an *ObjectInfo holding the HTTP caching headers or nil
+// if none of them is set.
+func headerInfo(cacheControl, contentDisposition, contentEncoding *string, expires *time.Time) *ObjectInfo {
+	if cacheControl == nil && contentDisposition == nil && contentEncoding == nil && expires == nil {
+		return nil
+	}
+
+	info := &ObjectInfo{}
+	info.setHeaders(cacheControl, contentDisposition, contentEncoding, expires)
+	return info
+}
+
+// withVersionID returns info with the VersionID set to versionID, and info
+// as it is if versionID is nil.
+func withVersionID(info *ObjectInfo, versionID *string) *ObjectInfo {
+	if versionID == nil {
+		return info
+	}
+	if info == nil {
+		info = &ObjectInfo{}
+	}
+	info.VersionID = *versionID
+	return info
+}
+
+// withETag sets the ETag of info to eTag, allocating info if needed.
+func withETag(info *ObjectInfo, eTag *string) *ObjectInfo {
+	if eTag == nil {
+		return info
+	}
+	if info == nil {
+		info = &ObjectInfo{}
+	}
+	info.ETag = *eTag
+	return info
+}
 
 type eofReader struct{}
 
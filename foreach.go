@@ -0,0 +1,65 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// ErrStop can be returned by the function given to ForEach to stop the
+// listing early. ForEach then returns nil.
+var ErrStop = errors.New("stop")
+
+// ForEach calls fn for every entry of the directory name. Unlike ReadDir
+// the entries are not accumulated: fn is called with the entries of a
+// ListObjectsV2 page as soon as it arrives, and the next page is only
+// requested once fn returned for all of them, so very large prefixes can be
+// listed in constant memory. This does not hold with WithDirectoryBucket:
+// the listings of directory buckets are not sorted, so the whole directory
+// is read and sorted before fn is first called, as with ReadDir.
+//
+// The entries come in the order of the keys, as with ReadDir(n) on the
+// opened directory: a directory can come after a file it is a prefix of,
+// such as "b" after "b.txt", since "b/" sorts after "b.txt".
+//
+// If fn returns ErrStop, ForEach stops and returns nil; any other error
+// stops the listing and is returned as is.
+func (f *S3FS) ForEach(name string, fn func(fs.DirEntry) error) error {
+	return f.ForEachContext(context.Background(), name, fn)
+}
+
+// ForEachContext is like ForEach, but makes its requests with ctx.
+func (f *S3FS) ForEachContext(ctx context.Context, name string, fn func(fs.DirEntry) error) error {
+	d, err := f.openDir(ctx, name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+	defer d.Close()
+
+	for {
+		// a single entry is asked for, so that no page is requested before
+		// the buffered ones were handed to fn.
+		des, err := d.ReadDir(1)
+		for _, de := range des {
+			if err := fn(de); err != nil {
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		case len(des) == 0:
+			return nil
+		}
+	}
+}
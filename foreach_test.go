@@ -0,0 +1,81 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestForEach(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{"dir/a.txt", "dir/b/c.txt", "dir/b.txt", "dir/d/e.txt", "dir/f.txt", "other.txt"} {
+		cl.put(key, []byte(key))
+	}
+	fsys := s3fs.New(cl, "test", s3fs.WithMaxKeys(2))
+
+	t.Run("all", func(t *testing.T) {
+		var got []string
+		err := fsys.ForEach("dir", func(de fs.DirEntry) error {
+			got = append(got, de.Name())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		des, err := fsys.ReadDir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		for _, de := range des {
+			want = append(want, de.Name())
+		}
+		// names are sorted across pages by ReadDir only.
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		before := cl.count("ListObjectsV2")
+		var got []string
+		err := fsys.ForEach("dir", func(de fs.DirEntry) error {
+			got = append(got, de.Name())
+			if len(got) == 2 {
+				return s3fs.ErrStop
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		// the stat of dir and its first page.
+		if n := cl.count("ListObjectsV2") - before; n > 2 {
+			t.Errorf("%d listing requests, want at most 2", n)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		errFn := errors.New("fn failed")
+		err := fsys.ForEach("dir", func(fs.DirEntry) error { return errFn })
+		if err != errFn {
+			t.Errorf("got %v, want %v", err, errFn)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := fsys.ForEach("missing", func(fs.DirEntry) error { return nil })
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got %v, want fs.ErrNotExist", err)
+		}
+	})
+}
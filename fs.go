@@ -4,7 +4,14 @@ package s3fs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 
@@ -14,9 +21,10 @@ import (
 )
 
 var (
-	_ fs.FS        = (*S3FS)(nil)
-	_ fs.StatFS    = (*S3FS)(nil)
-	_ fs.ReadDirFS = (*S3FS)(nil)
+	_ fs.FS         = (*S3FS)(nil)
+	_ fs.StatFS     = (*S3FS)(nil)
+	_ fs.ReadDirFS  = (*S3FS)(nil)
+	_ fs.ReadFileFS = (*S3FS)(nil)
 )
 
 var errNotDir = errors.New("not a dir")
@@ -28,10 +36,168 @@ type Option func(*S3FS)
 //
 // BUG(WilliamFrei): Seeking on S3 requires reopening the file at the specified
 // position. This can cause problems if the file changed between opening
-// and calling Seek. In that case, fs.ErrNotExist error is returned, which
-// has to be handled by the caller.
+// and calling Seek. In that case, ErrFileChanged error is returned (which
+// also matches fs.ErrNotExist), and has to be handled by the caller.
 func WithReadSeeker(fsys *S3FS) { fsys.readSeeker = true }
 
+// WithFetchOwner makes directory listings request the owner of each object.
+// The owner is then available through the *ObjectInfo returned by Sys
+// on the entries' FileInfo. It is off by default to save bandwidth.
+func WithFetchOwner(fsys *S3FS) { fsys.fetchOwner = true }
+
+// WithListPrefetchMetadata makes directory listings HeadObject every listed
+// object, with at most concurrency requests in flight, so that the
+// *ObjectInfo returned by Sys on the entries' FileInfo carries the content
+// type and user metadata.
+//
+// This trades upfront latency for avoiding a Stat per entry later and is
+// only worth it when the caller needs the metadata of most entries.
+// A concurrency below 1 means 1.
+func WithListPrefetchMetadata(concurrency int) Option {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(fsys *S3FS) { fsys.prefetchConcurrency = concurrency }
+}
+
+// WithPartAlignedReads makes ReadAt round the ranges it requests out to
+// multiples of partSize, typically the part size the objects were uploaded
+// with. The last parts read are kept per file, up to 8 of them, so that
+// sequential, overlapping or backward reads, as done when scanning Parquet
+// or ORC files, reuse them instead of making a request each.
+//
+// ReadAt is only exposed on files when WithReadSeeker is used.
+func WithPartAlignedReads(partSize int64) Option {
+	return func(fsys *S3FS) {
+		if partSize > 0 {
+			fsys.partSize = partSize
+		}
+	}
+}
+
+// WithStatViaGet makes Stat fall back to a one byte ranged GetObject when
+// HeadObject is denied, which happens with IAM policies that allow
+// s3:GetObject only on GET requests.
+//
+// The fallback costs an extra request on every Stat of such objects and
+// reports only the size, ETag and modification time.
+func WithStatViaGet(fsys *S3FS) { fsys.statViaGet = true }
+
+// WithDirFirst makes Open look for a directory before it looks for an
+// object: it lists the name as a prefix first, and only gets the object if
+// the prefix is empty. Opening a directory then takes a single
+// ListObjectsV2 instead of a failed GetObject, a HeadObject and a
+// ListObjectsV2, while opening a file takes a ListObjectsV2 more.
+//
+// It pays off when most opened names are directories, e.g. when walking a
+// tree; by default files are looked for first, which is cheaper for reads
+// of files. A name that is both an object and a prefix opens as a
+// directory.
+func WithDirFirst(fsys *S3FS) { fsys.dirFirst = true }
+
+// WithKeyValidator sets a function that validates every name before it is
+// sent to S3, on top of the fs.ValidPath check. It lets deployments enforce
+// their own key policies (length limits, allowed characters, required
+// prefixes, ...). The validator's error is returned wrapped in a
+// *fs.PathError and no S3 request is made.
+func WithKeyValidator(fn func(name string) error) Option {
+	return func(fsys *S3FS) { fsys.keyValidator = fn }
+}
+
+// WithStorageClass sets the storage class of the objects written by the
+// filesystem, e.g. types.StorageClassStandardIa. It panics if class is not a
+// storage class known to the SDK.
+func WithStorageClass(class types.StorageClass) Option {
+	if !isKnownStorageClass(class) {
+		panic("s3fs: unknown storage class " + strconv.Quote(string(class)))
+	}
+	return func(fsys *S3FS) { fsys.storageClass = class }
+}
+
+func isKnownStorageClass(class types.StorageClass) bool {
+	for _, c := range class.Values() {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// WithServerSideEncryption makes S3 encrypt the objects written by the
+// filesystem with alg: types.ServerSideEncryptionAes256 for SSE-S3, or
+// types.ServerSideEncryptionAwsKms for SSE-KMS with the KMS key kmsKeyID,
+// or the AWS managed key if kmsKeyID is empty. Reads need no option, S3
+// decrypts the objects itself.
+//
+// It panics if alg is not known to the SDK, or if kmsKeyID is set for an
+// algorithm other than KMS.
+func WithServerSideEncryption(alg types.ServerSideEncryption, kmsKeyID string) Option {
+	known := false
+	for _, a := range alg.Values() {
+		known = known || a == alg
+	}
+	if !known {
+		panic("s3fs: unknown server-side encryption " + strconv.Quote(string(alg)))
+	}
+
+	var keyID *string
+	if kmsKeyID != "" {
+		if alg != types.ServerSideEncryptionAwsKms && alg != types.ServerSideEncryptionAwsKmsDsse {
+			panic("s3fs: KMS key for server-side encryption " + strconv.Quote(string(alg)))
+		}
+		keyID = &kmsKeyID
+	}
+	return func(fsys *S3FS) { fsys.sse, fsys.sseKMSKeyID = alg, keyID }
+}
+
+// WithListAuditor sets a function that is called for every page of every
+// listing with the listed prefix and the number of entries S3 returned.
+// S3 does not report keys hidden by IAM policies, but unexpectedly small
+// pages can hint at them.
+func WithListAuditor(fn func(prefix string, returned int)) Option {
+	return func(fsys *S3FS) { fsys.listAuditor = fn }
+}
+
+// WithDelimiter sets the delimiter used to list directories, which is "/"
+// by default. With another delimiter, e.g. "-", the keys of a directory
+// are grouped up to the delimiter: "logs/2021-01" is listed by
+// ReadDir("logs") as the directory "2021", which lists "01". With an empty
+// delimiter ReadDir returns every object below the directory as a file
+// entry named by its key relative to the directory, e.g. "sub/file.txt",
+// and no subdirectories.
+//
+// Stat reports name as a directory if any key starts with name + "/", or
+// with name and the delimiter, whatever the delimiter: with an empty one,
+// the directories of "/" still exist, even though they are not listed.
+func WithDelimiter(d string) Option {
+	return func(fsys *S3FS) { fsys.delimiter = &d }
+}
+
+// WithMaxKeys sets the number of keys requested per ListObjectsV2 page by
+// ReadDir and the other listings of several keys, such as RemoveAll and
+// Prune. Small pages return sooner, large ones make fewer requests; the
+// results are the same. n is clamped to 1..1000, the maximum S3 returns,
+// and S3 picks the page size (1000) by default.
+func WithMaxKeys(n int32) Option {
+	if n < 1 {
+		n = 1
+	}
+	if n > maxListKeys {
+		n = maxListKeys
+	}
+	return func(fsys *S3FS) { fsys.maxKeys = n }
+}
+
+// maxListKeys is the most keys S3 returns per ListObjectsV2 page.
+const maxListKeys = 1000
+
+// WithRequestOptions sets functions that modify the S3 client options of
+// every request made by the filesystem, e.g. to change the region or add
+// middleware.
+func WithRequestOptions(optFns ...func(*s3.Options)) Option {
+	return func(fsys *S3FS) { fsys.optFns = append(fsys.optFns, optFns...) }
+}
+
 type S3Client interface {
 	manager.ListObjectsV2APIClient
 	manager.DeleteObjectsAPIClient
@@ -45,15 +211,81 @@ type S3Client interface {
 //
 // S3 has a flat structure instead of a hierarchy. S3FS simulates directories
 // by using prefixes and delims ("/"). Because directories are simulated, ModTime
-// is always a default Time value (IsZero returns true).
+// is always a default Time value (IsZero returns true), unless WithDirModTime
+// is used.
 type S3FS struct {
 	cl         S3Client
 	bucket     string
+	prefix     string
 	readSeeker bool
+	fetchOwner bool
+	statViaGet bool
+	dirFirst   bool
+	rangeCache *rangeCache
+	partSize   int64
+
+	caseInsensitive  bool
+	keyValidator     func(name string) error
+	storageClass     types.StorageClass
+	sse              types.ServerSideEncryption
+	sseKMSKeyID      *string
+	sseCustomerKey   *sseCustomerKey
+	requestPayer     types.RequestPayer
+	observer         Observer
+	compressOnWrite  bool
+	compressLevel    int
+	writeInterceptor func(name string, data []byte) ([]byte, error)
+	gzipFallback     bool
+	rangeFallback    bool
+	rangeFormatter   func(start, end int64) string
+	stripBOM         bool
+	resumableReads   bool
+	dirModTime       bool
+	maxKeys          int32
+	missingAsEmpty   bool
+	autoDecompress   bool
+	download         *download
+	directoryBucket  bool
+	trashPrefix      string
+	decompressors    map[string]Decompressor
+	// delimiter is the listing delimiter set by WithDelimiter; nil means
+	// "/".
+	delimiter  *string
+	decryption *decryption
+	retry      *retrier
+
+	// prefetchConcurrency is the number of concurrent HeadObjects made
+	// per listing page; 0 disables prefetching.
+	prefetchConcurrency int
+	// listTagsConcurrency is the number of concurrent GetObjectTaggings
+	// made per listing page; 0 disables fetching tags.
+	listTagsConcurrency int
+	// parallelReads is the number of concurrent ranged GetObjects a large
+	// Read is split into; 0 disables splitting.
+	parallelReads int
+
+	listPacing  time.Duration
+	listAuditor func(prefix string, returned int)
+
+	statCache *statCache
+	snapshot  *snapshot
+
+	// optFns are passed to every client call.
+	optFns []func(*s3.Options)
 }
 
-// New returns a new filesystem that works on the specified bucket.
+// New returns a new filesystem that works on the specified bucket. It
+// panics if cl is nil or bucket is not a valid S3 bucket name, as these
+// would otherwise only fail on the first request. Directory bucket names
+// and ARNs, such as those of access points, are valid bucket names.
 func New(cl S3Client, bucket string, opts ...Option) *S3FS {
+	if cl == nil {
+		panic("s3fs: nil client")
+	}
+	if !validBucketName(bucket) {
+		panic("s3fs: invalid bucket name " + strconv.Quote(bucket))
+	}
+
 	fsys := &S3FS{
 		cl:     cl,
 		bucket: bucket,
@@ -63,11 +295,73 @@ func New(cl S3Client, bucket string, opts ...Option) *S3FS {
 		opt(fsys)
 	}
 
+	if fsys.prefix != "" {
+		fsys.cl = newPrefixClient(fsys.cl, fsys.prefix)
+	}
+	if fsys.observer != nil {
+		fsys.cl = newObservedClient(fsys.cl, fsys.observer)
+	}
+
 	return fsys
 }
 
-// Open implements fs.FS.
+// validBucketName reports whether name follows the S3 bucket naming
+// rules: 3 to 63 lowercase letters, digits, dots and hyphens, beginning and
+// ending with a letter or digit, without adjacent dots and not formatted as
+// an IP address. ARNs are left to S3.
+func validBucketName(name string) bool {
+	if strings.HasPrefix(name, "arn:") {
+		return true
+	}
+	if len(name) < 3 || len(name) > 63 || strings.Contains(name, "..") || net.ParseIP(name) != nil {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case (c == '.' || c == '-') && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// OpenWith is like Open, but applies optFns on top of the options set with
+// WithRequestOptions to the requests made by this call and by the returned
+// file. It is meant for one-off requests, e.g. reading from a bucket in
+// another region, without creating a new filesystem.
+func (f *S3FS) OpenWith(name string, optFns ...func(*s3.Options)) (fs.File, error) {
+	return f.withOptions(optFns).Open(name)
+}
+
+// withOptions returns a copy of f whose requests use optFns in addition to
+// the filesystem's own options.
+func (f *S3FS) withOptions(optFns []func(*s3.Options)) *S3FS {
+	if len(optFns) == 0 {
+		return f
+	}
+
+	c := *f
+	c.optFns = make([]func(*s3.Options), 0, len(f.optFns)+len(optFns))
+	c.optFns = append(c.optFns, f.optFns...)
+	c.optFns = append(c.optFns, optFns...)
+	return &c
+}
+
+// Open implements fs.FS. Directories are opened as fs.ReadDirFile, which
+// callers type-assert to list them; reading them fails with ErrIsDir, as
+// reading a directory opened with os.Open does.
 func (f *S3FS) Open(name string) (fs.File, error) {
+	return f.OpenContext(context.Background(), name)
+}
+
+// OpenContext is like Open, but makes its requests with ctx. Reads of the
+// returned file, including the requests a Seek makes, use ctx too, so
+// canceling it aborts a slow download. Files of objects also have a
+// SetReadDeadline(time.Time) error method to bound their reads.
+func (f *S3FS) OpenContext(ctx context.Context, name string) (fs.File, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{
 			Op:   "open",
@@ -76,19 +370,83 @@ func (f *S3FS) Open(name string) (fs.File, error) {
 		}
 	}
 
+	if f.snapshot != nil {
+		fi, err := f.stat(ctx, name)
+		if err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  err,
+			}
+		}
+		if d, ok := fi.(fs.ReadDirFile); ok {
+			return d, nil
+		}
+	}
+
 	if name == "." {
-		return openDir(f.cl, f.bucket, name)
+		return f.openDir(ctx, name)
+	}
+
+	if f.dirFirst {
+		if err := f.validateKey(name); err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  err,
+			}
+		}
+
+		d, err := f.listDir(ctx, name)
+		if err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  permissionErr(err),
+			}
+		}
+		if d != nil {
+			return d, nil
+		}
 	}
 
-	file, err := openFile(f.cl, f.bucket, name)
+	file, err := f.openFile(ctx, name)
 
 	if err != nil {
 		if isNotFoundErr(err) {
-			switch d, err := openDir(f.cl, f.bucket, name); {
-			case err == nil:
-				return d, nil
-			case !isNotFoundErr(err) && !errors.Is(err, errNotDir) && !errors.Is(err, fs.ErrNotExist):
-				return nil, err
+			// WithDirFirst already looked for the directory.
+			if !f.dirFirst {
+				switch d, err := f.openDir(ctx, name); {
+				case err == nil:
+					return d, nil
+				case !isNotFoundErr(err) && !errors.Is(err, errNotDir) && !errors.Is(err, fs.ErrNotExist):
+					return nil, err
+				}
+			}
+
+			if f.gzipFallback {
+				switch gz, err := f.openGzip(ctx, name); {
+				case err == nil:
+					return gz, nil
+				case !errors.Is(err, fs.ErrNotExist):
+					return nil, &fs.PathError{
+						Op:   "open",
+						Path: name,
+						Err:  err,
+					}
+				}
+			}
+
+			if f.caseInsensitive {
+				file, err := f.openCaseInsensitive(ctx, name)
+				if f.missingAsEmpty && errors.Is(err, fs.ErrNotExist) {
+					return newMissingFile(name), nil
+				}
+				return file, err
+			}
+
+			if f.missingAsEmpty {
+				return newMissingFile(name), nil
 			}
 
 			return nil, &fs.PathError{
@@ -101,10 +459,44 @@ func (f *S3FS) Open(name string) (fs.File, error) {
 		return nil, &fs.PathError{
 			Op:   "open",
 			Path: name,
-			Err:  err,
+			Err:  permissionErr(err),
+		}
+	}
+
+	encoding, metadata := contentEncoding(file), objectMetadata(file)
+
+	var decrypted bool
+	if f.decryption != nil {
+		file, decrypted, err = f.decryption.decryptFile(ctx, name, file)
+		if err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  err,
+			}
 		}
 	}
 
+	if dec := f.decompressorFor(name, encoding); dec != nil {
+		file, err := decompress(dec, file, metadata)
+		if err != nil {
+			return nil, &fs.PathError{
+				Op:   "open",
+				Path: name,
+				Err:  err,
+			}
+		}
+		return file, nil
+	}
+
+	if decrypted {
+		return file, nil
+	}
+
+	if f.rangeCache != nil {
+		f.rangeCache.attach(file)
+	}
+
 	if !f.readSeeker {
 		file = fileNoSeek{file}
 	}
@@ -114,20 +506,41 @@ func (f *S3FS) Open(name string) (fs.File, error) {
 
 // Stat implements fs.StatFS.
 func (f *S3FS) Stat(name string) (fs.FileInfo, error) {
-	fi, err := stat(f.cl, f.bucket, name)
+	return f.StatContext(context.Background(), name)
+}
+
+// StatContext is like Stat, but makes its requests with ctx.
+func (f *S3FS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	fi, err := f.stat(ctx, name)
+	if err != nil && f.gzipFallback && errors.Is(err, fs.ErrNotExist) {
+		fi, err = f.statGzip(ctx, name)
+	}
+	if err != nil && f.caseInsensitive && errors.Is(err, fs.ErrNotExist) {
+		fi, err = f.statCaseInsensitive(ctx, name)
+	}
 	if err != nil {
 		return nil, &fs.PathError{
 			Op:   "stat",
 			Path: name,
-			Err:  err,
+			Err:  permissionErr(err),
 		}
 	}
 	return fi, nil
 }
 
 // ReadDir implements fs.ReadDirFS.
+//
+// The entries and their FileInfo have a Key() string method returning the
+// full key of the object, e.g. "a/b/c.txt" for the entry "c.txt" of
+// ReadDir("a/b"), which saves joining names that do not round-trip through
+// package path. Keys are relative to the prefix of WithPrefix.
 func (f *S3FS) ReadDir(name string) ([]fs.DirEntry, error) {
-	d, err := openDir(f.cl, f.bucket, name)
+	return f.ReadDirContext(context.Background(), name)
+}
+
+// ReadDirContext is like ReadDir, but makes its requests with ctx.
+func (f *S3FS) ReadDirContext(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	d, err := f.openDir(ctx, name)
 	if err != nil {
 		return nil, &fs.PathError{
 			Op:   "readdir",
@@ -138,63 +551,273 @@ func (f *S3FS) ReadDir(name string) ([]fs.DirEntry, error) {
 	return d.ReadDir(-1)
 }
 
-func stat(cl S3Client, bucket, name string) (fs.FileInfo, error) {
+// ReadFile implements fs.ReadFileFS. The object is downloaded with a single
+// GetObject. If name is a directory, the error wraps ErrIsDir.
+func (f *S3FS) ReadFile(name string) ([]byte, error) {
+	if name == "." {
+		return nil, &fs.PathError{
+			Op:   "readfile",
+			Path: name,
+			Err:  ErrIsDir,
+		}
+	}
+
+	data, err := f.readObject(context.TODO(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		// S3 does not know directories, so a prefix looks like a missing
+		// object to GetObject.
+		if fi, serr := f.stat(context.TODO(), name); serr == nil && fi.IsDir() {
+			err = ErrIsDir
+		} else if f.gzipFallback {
+			data, err = f.readGzip(name)
+		}
+	}
+	if f.missingAsEmpty && errors.Is(err, fs.ErrNotExist) {
+		data, err = []byte{}, nil
+	}
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "readfile",
+			Path: name,
+			Err:  permissionErr(err),
+		}
+	}
+	return data, nil
+}
+
+// ReadDirInfo is like ReadDir, but returns the entries' FileInfo. The
+// FileInfo is built straight from the listing, so no further requests are
+// needed to get the size and modification time of the objects.
+func (f *S3FS) ReadDirInfo(name string) ([]fs.FileInfo, error) {
+	des, err := f.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fis := make([]fs.FileInfo, 0, len(des))
+	for _, de := range des {
+		if de, ok := de.(dirEntry); ok {
+			fis = append(fis, de.fileInfo)
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+		fis = append(fis, fi)
+	}
+	return fis, nil
+}
+
+func (f *S3FS) stat(ctx context.Context, name string) (fs.FileInfo, error) {
 	if !fs.ValidPath(name) {
 		return nil, fs.ErrInvalid
 	}
 
+	if err := f.validateKey(name); err != nil {
+		return nil, err
+	}
+
+	if f.snapshot != nil {
+		return f.snapshot.stat(name)
+	}
+
 	if name == "." {
 		return &dir{
-			s3cl:   cl,
-			bucket: bucket,
+			fsys: f,
 			fileInfo: fileInfo{
 				name: ".",
 				mode: fs.ModeDir,
 			},
+			ctx: ctx,
 		}, nil
 	}
 
-	head, err := cl.HeadObject(context.TODO(), &s3.HeadObjectInput{
-		Bucket: &bucket,
-		Key:    aws.String(name),
+	if f.statCache != nil {
+		if fi, ok := f.cachedStat(ctx, name); ok {
+			if fi == nil {
+				return nil, fs.ErrNotExist
+			}
+			if fi.IsDir() {
+				return &dir{
+					fsys:     f,
+					fileInfo: *fi,
+					ctx:      ctx,
+				}, nil
+			}
+			return fi, nil
+		}
+	}
+
+	var head *s3.HeadObjectOutput
+	err := f.retryRead(ctx, func() (err error) {
+		head, err = f.cl.HeadObject(ctx, f.headInput(&s3.HeadObjectInput{
+			Bucket: &f.bucket,
+			Key:    aws.String(name),
+		}), f.optFns...)
+		return err
 	})
 	if err != nil {
-		if !isNotFoundErr(err) {
-			return nil, err
+		switch {
+		case f.statViaGet && IsPermission(err):
+			fi, err := f.statViaGetObject(ctx, name)
+			if err == nil {
+				return fi, nil
+			}
+			if !isNotFoundErr(err) {
+				return nil, err
+			}
+		case !isNotFoundErr(err):
+			return nil, f.bucketErr(err)
 		}
 	} else {
-		return &fileInfo{
-			name:    name,
-			size:    head.ContentLength,
-			mode:    0,
-			modTime: derefTime(head.LastModified),
-		}, nil
+		fi := headFileInfo(name, head)
+		if f.decompressorFor(name, aws.ToString(head.ContentEncoding)) != nil {
+			fi.size = gzipSize(fi.size, head.Metadata)
+		}
+		if f.statCache != nil {
+			f.statCache.put(name, fi)
+		}
+		return fi, nil
 	}
 
-	out, err := cl.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
-		Bucket:    &bucket,
-		Delimiter: aws.String("/"),
-		Prefix:    aws.String(name + "/"),
-		MaxKeys:   1,
-	})
+	d, err := f.listDir(ctx, name)
 	if err != nil {
 		return nil, err
 	}
-	if len(out.CommonPrefixes) > 0 || len(out.Contents) > 0 {
-		return &dir{
-			s3cl:   cl,
-			bucket: bucket,
-			fileInfo: fileInfo{
-				name: name,
-				mode: fs.ModeDir,
-			},
-		}, nil
+	if d != nil {
+		if f.statCache != nil {
+			f.statCache.putDir(name)
+		}
+		return d, nil
+	}
+
+	if f.statCache != nil {
+		f.statCache.putMissing(name)
 	}
 	return nil, fs.ErrNotExist
 }
 
-func openDir(cl S3Client, bucket, name string) (fs.ReadDirFile, error) {
-	fi, err := stat(cl, bucket, name)
+// listDir returns the directory name if a single key ListObjectsV2 of its
+// prefix finds anything, and nil otherwise. With a delimiter other than
+// "/", the keys starting with name and the delimiter are listed too.
+func (f *S3FS) listDir(ctx context.Context, name string) (*dir, error) {
+	seps := []string{"/"}
+	if sep := f.customDelimiter(); sep != "" {
+		seps = append(seps, sep)
+	}
+
+	for _, sep := range seps {
+		ok, err := f.hasKeys(ctx, name+sep)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &dir{
+				fsys: f,
+				fileInfo: fileInfo{
+					name: name,
+					mode: fs.ModeDir,
+				},
+				sep: sep,
+				ctx: ctx,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// hasKeys reports whether any key starts with prefix.
+func (f *S3FS) hasKeys(ctx context.Context, prefix string) (bool, error) {
+	var out *s3.ListObjectsV2Output
+	err := f.retryRead(ctx, func() (err error) {
+		out, err = f.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:       &f.bucket,
+			RequestPayer: f.requestPayer,
+			Delimiter:    f.listDelimiter(),
+			Prefix:       aws.String(prefix),
+			MaxKeys:      1,
+		}, f.optFns...)
+		return err
+	})
+	if err != nil {
+		return false, f.bucketErr(err)
+	}
+	// a truncated page of a directory bucket can be empty.
+	return len(out.CommonPrefixes) > 0 || len(out.Contents) > 0 || (f.directoryBucket && out.IsTruncated), nil
+}
+
+// listDelimiter returns the Delimiter of directory listings, nil for flat
+// listings.
+func (f *S3FS) listDelimiter() *string {
+	switch {
+	case f.delimiter == nil:
+		return aws.String("/")
+	case *f.delimiter == "":
+		return nil
+	}
+	return f.delimiter
+}
+
+// customDelimiter returns the delimiter set by WithDelimiter if it is
+// neither "/" nor empty, and "" otherwise.
+func (f *S3FS) customDelimiter() string {
+	if d := aws.ToString(f.delimiter); d != "/" {
+		return d
+	}
+	return ""
+}
+
+// headFileInfo returns the FileInfo of the object name described by head.
+func headFileInfo(name string, head *s3.HeadObjectOutput) *fileInfo {
+	return &fileInfo{
+		name:    name,
+		size:    head.ContentLength,
+		modTime: derefTime(head.LastModified),
+		eTag:    aws.ToString(head.ETag),
+		sys:     withETag(withVersionID(headerInfo(head.CacheControl, head.ContentDisposition, head.ContentEncoding, head.Expires), head.VersionId), head.ETag),
+	}
+}
+
+// statViaGetObject learns the size of an object from a single byte ranged
+// GetObject. It is used when HeadObject isn't allowed.
+func (f *S3FS) statViaGetObject(ctx context.Context, name string) (fs.FileInfo, error) {
+	out, err := f.cl.GetObject(ctx, f.getInput(&s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name),
+		Range:  f.rangeHeader(0, 0),
+	}), f.optFns...)
+	if err != nil {
+		// the first byte of an empty object is not satisfiable.
+		if httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable {
+			return &fileInfo{name: name}, nil
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	size := out.ContentLength
+	if cr := aws.ToString(out.ContentRange); cr != "" {
+		// Content-Range has the form "bytes 0-0/size".
+		if i := strings.LastIndexByte(cr, '/'); i >= 0 {
+			if n, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				size = n
+			}
+		}
+	}
+
+	return &fileInfo{
+		name:    name,
+		size:    size,
+		modTime: derefTime(out.LastModified),
+		eTag:    aws.ToString(out.ETag),
+		sys:     headerInfo(out.CacheControl, out.ContentDisposition, out.ContentEncoding, out.Expires),
+	}, nil
+}
+
+func (f *S3FS) openDir(ctx context.Context, name string) (fs.ReadDirFile, error) {
+	fi, err := f.stat(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -205,18 +828,83 @@ func openDir(cl S3Client, bucket, name string) (fs.ReadDirFile, error) {
 	return nil, errNotDir
 }
 
+// auditList reports a listing page to the auditor set with WithListAuditor.
+func (f *S3FS) auditList(prefix string, out *s3.ListObjectsV2Output) {
+	if f.listAuditor != nil {
+		f.listAuditor(prefix, len(out.Contents)+len(out.CommonPrefixes))
+	}
+}
+
+// knownDir returns the directory name, which is known to exist, without a
+// stat. Its listing uses ctx.
+func (f *S3FS) knownDir(ctx context.Context, name string) fs.ReadDirFile {
+	if f.snapshot != nil {
+		return f.snapshot.dir(name)
+	}
+
+	return &dir{
+		fsys: f,
+		fileInfo: fileInfo{
+			name: name,
+			mode: fs.ModeDir,
+		},
+		ctx: ctx,
+	}
+}
+
+// validateKey runs the key validator set with WithKeyValidator.
+func (f *S3FS) validateKey(name string) error {
+	if f.keyValidator == nil || name == "." {
+		return nil
+	}
+	return f.keyValidator(name)
+}
+
 var notFoundCodes = map[string]struct{}{
-	//s3.ErrCodeNoSuchKey: {},
-	"NotFound": {}, // localstack
+	"NoSuchKey":     {},
+	"NoSuchVersion": {}, // GetObject of a VersionId
+	"NotFound":      {}, // HeadObject, localstack
 }
 
+// isNotFoundErr reports whether err means that the requested object does
+// not exist. HeadObject responses have no body, so a 404 without a known
+// error code counts too. A missing bucket does not; see isNoSuchBucket.
 func isNotFoundErr(err error) bool {
+	// a denied request says nothing about whether the object exists.
+	if err == nil || isNoSuchBucket(err) || IsPermission(err) {
+		return false
+	}
+
 	var nsk *types.NoSuchKey
 	if errors.As(err, &nsk) {
-		// handle NoSuchKey error
 		return true
 	}
-	return false
+
+	var nf *types.NotFound
+	if errors.As(err, &nf) {
+		return true
+	}
+
+	if _, ok := notFoundCodes[errorCode(err)]; ok {
+		return true
+	}
+	return httpStatusCode(err) == http.StatusNotFound
 }
 
 type fileNoSeek struct{ fs.File }
+
+// WriteTo keeps the io.WriterTo of the file that Seek is hidden from.
+func (f fileNoSeek) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := f.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, struct{ io.Reader }{f.File})
+}
+
+// SetReadDeadline keeps the read deadline of the file.
+func (f fileNoSeek) SetReadDeadline(t time.Time) error {
+	if d, ok := f.File.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return d.SetReadDeadline(t)
+	}
+	return fmt.Errorf("s3fs: %T does not support read deadlines", f.File)
+}
@@ -6,12 +6,16 @@ import (
 	"crypto/tls"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"path"
 	"reflect"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"testing/fstest"
@@ -20,10 +24,9 @@ import (
 	"github.com/matthewp/s3fs"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 var (
@@ -978,10 +981,11 @@ func TestFS(t *testing.T) {
 							t.Fatalf("expected err to be PathError: got %#v", err)
 						}
 
-						// currently we don't implement fs.SubFS.
-						// fs.Sub calls open instead of Stat.
-						if perr.Op != "open" {
-							t.Errorf("expected op to be open; got %s", perr.Op)
+						if perr.Op != "stat" {
+							t.Errorf("expected op to be stat; got %s", perr.Op)
+						}
+						if perr.Path != "not-exist" {
+							t.Errorf("expected path to be not-exist; got %s", perr.Path)
 						}
 					})
 				})
@@ -1053,9 +1057,9 @@ func TestFS(t *testing.T) {
 		s3fs *s3fs.S3FS
 	}{
 		{desc: "standard", s3fs: s3fs.New(s3cl, *bucket)},
-		{desc: "max keys = 1", s3fs: s3fs.New(&client{MaxKeys: aws.Int64(1), S3API: s3cl}, *bucket)},
-		{desc: "max keys = 2", s3fs: s3fs.New(&client{MaxKeys: aws.Int64(2), S3API: s3cl}, *bucket)},
-		{desc: "max keys = 3", s3fs: s3fs.New(&client{MaxKeys: aws.Int64(3), S3API: s3cl}, *bucket)},
+		{desc: "max keys = 1", s3fs: s3fs.New(&client{MaxKeys: 1, testClient: s3cl}, *bucket)},
+		{desc: "max keys = 2", s3fs: s3fs.New(&client{MaxKeys: 2, testClient: s3cl}, *bucket)},
+		{desc: "max keys = 3", s3fs: s3fs.New(&client{MaxKeys: 3, testClient: s3cl}, *bucket)},
 	}
 
 	for _, f := range fixtures {
@@ -1076,13 +1080,13 @@ func TestDirRead(t *testing.T) {
 	tests := []struct {
 		desc     string
 		n        int
-		outs     []s3.ListObjectsOutput
+		outs     []s3.ListObjectsV2Output
 		expected [][]fileinfo
 	}{
 		{
 			desc: "all in one request - dir first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, []string{"b", "d", "f"}),
 			},
 			expected: [][]fileinfo{
@@ -1097,7 +1101,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - n = 0",
 			n:    0,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, []string{"b", "d", "f"}),
 			},
 			expected: [][]fileinfo{
@@ -1114,7 +1118,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - n = 2",
 			n:    2,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput([]string{"c"}, []string{"b", "d"}),
 				newListOutput([]string{"e"}, nil),
@@ -1138,7 +1142,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "one per request - dir first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput(nil, []string{"b"}),
 				newListOutput([]string{"c"}, []string{"d"}),
@@ -1157,7 +1161,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - file first",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"b", "d", "f"}, []string{"a", "c", "e"}),
 			},
 			expected: [][]fileinfo{
@@ -1172,7 +1176,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "with dir duplicates",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c"}, []string{"b"}),
 				newListOutput([]string{"c", "e", "c"}, []string{"d"}),
 				newListOutput([]string{"e", "a"}, []string{"f"}),
@@ -1190,7 +1194,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "all in one request - dirs only",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a", "c", "e"}, nil),
 			},
 			expected: [][]fileinfo{
@@ -1202,7 +1206,7 @@ func TestDirRead(t *testing.T) {
 		{
 			desc: "single dir per request - dirs only",
 			n:    1,
-			outs: []s3.ListObjectsOutput{
+			outs: []s3.ListObjectsV2Output{
 				newListOutput([]string{"a"}, nil),
 				newListOutput([]string{"c"}, nil),
 				newListOutput([]string{"e"}, nil),
@@ -1260,43 +1264,75 @@ func TestDirRead(t *testing.T) {
 	}
 }
 
+func TestStatMissing(t *testing.T) {
+	// HeadObject fails with NotFound instead of NoSuchKey.
+	fsys := s3fs.New(newMemClient(), "test")
+
+	if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
 type mockClient struct {
 	*s3.Client
-	outs []s3.ListObjectsOutput
+	outs []s3.ListObjectsV2Output
 	i    int
 }
 
-func (c *mockClient) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+func (c *mockClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
 	defer func() { c.i++ }()
 	if c.i < len(c.outs) {
 		return &c.outs[c.i], nil
 	}
 
-	return &s3.ListObjectsOutput{
+	return &s3.ListObjectsV2Output{
 		IsTruncated: false,
 	}, nil
 }
 
-func newListOutput(dirs, files []string) (out s3.ListObjectsOutput) {
+func newListOutput(dirs, files []string) (out s3.ListObjectsV2Output) {
 	for _, d := range dirs {
-		out.CommonPrefixes = append(out.CommonPrefixes, &s3.CommonPrefix{
+		out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{
 			Prefix: aws.String(d),
 		})
 	}
 
 	for _, f := range files {
-		out.Contents = append(out.Contents, &s3.Object{
+		out.Contents = append(out.Contents, types.Object{
 			Key:          aws.String(f),
-			Size:         aws.Int64(0),
+			Size:         0,
 			LastModified: aws.Time(time.Time{}),
 		})
 	}
+
+	out.IsTruncated = true
+	out.NextContinuationToken = aws.String("next")
 	return out
 }
 
-func newClient(t *testing.T) *s3.Client {
+// testClient is the client used by the integration tests. On top of what
+// s3fs needs it allows to manage the test bucket.
+type testClient interface {
+	s3fs.S3Client
+	CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+func newClient(t *testing.T) testClient {
 	t.Helper()
 
+	url := *endpoint
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+	conn, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("s3 endpoint %s is not reachable: %v", *endpoint, err)
+	}
+	conn.Close()
+
 	cl := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -1305,26 +1341,27 @@ func newClient(t *testing.T) *s3.Client {
 		},
 	}
 
-	s, err := session.NewSession(
-		aws.NewConfig().
-			WithEndpoint(*endpoint).
-			WithRegion(region).
-			WithS3ForcePathStyle(true).
-			WithHTTPClient(cl).
-			WithCredentials(credentials.NewStaticCredentials(accessKeyID, secretKey, "")),
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
+	s3cl := s3.New(s3.Options{
+		Region:           region,
+		EndpointResolver: s3.EndpointResolverFromURL(url),
+		UsePathStyle:     true,
+		HTTPClient:       cl,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     accessKeyID,
+				SecretAccessKey: secretKey,
+			}, nil
+		}),
+	})
 
-	return &modTimeTruncateClient{&metricClient{s3.New(s)}}
+	return &modTimeTruncateClient{&metricClient{s3cl}}
 }
 
-func writeFile(t *testing.T, cl *s3.Client, bucket, name string, data []byte) {
+func writeFile(t *testing.T, cl testClient, bucket, name string, data []byte) {
 	t.Helper()
 
-	uploader := s3manager.NewUploaderWithClient(cl)
-	_, err := uploader.Upload(&s3manager.UploadInput{
+	uploader := manager.NewUploader(cl)
+	_, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
 		Body:   strings.NewReader(string(data)),
 		Bucket: &bucket,
 		Key:    &name,
@@ -1334,7 +1371,7 @@ func writeFile(t *testing.T, cl *s3.Client, bucket, name string, data []byte) {
 	}
 }
 
-func deleteFile(t *testing.T, cl *s3.Client, bucket, name string) {
+func deleteFile(t *testing.T, cl testClient, bucket, name string) {
 	t.Helper()
 
 	_, err := cl.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
@@ -1346,24 +1383,25 @@ func deleteFile(t *testing.T, cl *s3.Client, bucket, name string) {
 	}
 }
 
-func createBucket(t *testing.T, cl *s3.Client, bucket string) {
+func createBucket(t *testing.T, cl testClient, bucket string) {
 	t.Helper()
 
 	_, err := cl.CreateBucket(context.TODO(), &s3.CreateBucketInput{
 		Bucket: &bucket,
 	})
 	if err != nil {
-		//if awserr, ok := err.(awserr.Error); ok && awserr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
-		//	return
-		//}
+		var owned *types.BucketAlreadyOwnedByYou
+		if errors.As(err, &owned) {
+			return
+		}
 		t.Fatal(err)
 	}
 }
 
-func cleanBucket(t *testing.T, cl *s3.Client, bucket string) {
+func cleanBucket(t *testing.T, cl testClient, bucket string) {
 	t.Helper()
 
-	out, err := cl.ListObjects(context.TODO(), &s3.ListObjectsInput{
+	out, err := cl.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
 	})
 	if err != nil {
@@ -1389,33 +1427,33 @@ func envDefault(env, def string) string {
 }
 
 type client struct {
-	MaxKeys *int64
-	cl      *s3.Client
+	MaxKeys int32
+	testClient
 }
 
-func (c *client) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
-	if c.MaxKeys != nil {
+func (c *client) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if c.MaxKeys != 0 {
 		in.MaxKeys = c.MaxKeys
 	}
-	return c.cl.ListObjects(in)
+	return c.testClient.ListObjectsV2(ctx, in, optFns...)
 }
 
 type modTimeTruncateClient struct {
-	cl *s3.Client
+	*metricClient
 }
 
 // Minio returns modTime that includes microseconds if data comes from ListObjects
 // while data coming from GetObject's modTimes are accurate down to seconds.
 // To make this test pass while using Minio we build this client that truncates
 // modTimes to Second.
-func (c *modTimeTruncateClient) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
-	out, err := c.ListObjects(in)
+func (c *modTimeTruncateClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, err := c.metricClient.ListObjectsV2(ctx, in, optFns...)
 	if err != nil {
 		return out, err
 	}
 
-	for _, o := range out.Contents {
-		o.LastModified = aws.Time(o.LastModified.Truncate(time.Second))
+	for i, o := range out.Contents {
+		out.Contents[i].LastModified = aws.Time(o.LastModified.Truncate(time.Second))
 	}
 	return out, err
 }
@@ -1430,12 +1468,1628 @@ type metricClient struct {
 	*s3.Client
 }
 
-func (c *metricClient) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+func (c *metricClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
 	atomic.AddInt64(&listC, 1)
-	return c.ListObjects(in)
+	return c.Client.ListObjectsV2(ctx, in, optFns...)
 }
 
-func (c *metricClient) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+func (c *metricClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
 	atomic.AddInt64(&getC, 1)
-	return c.GetObject(in)
+	return c.Client.GetObject(ctx, in, optFns...)
+}
+
+func TestFetchOwner(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("content"))
+
+	fixtures := []struct {
+		desc string
+		opts []s3fs.Option
+		want interface{}
+	}{
+		{
+			desc: "disabled",
+			want: nil,
+		},
+		{
+			desc: "enabled",
+			opts: []s3fs.Option{s3fs.WithFetchOwner},
+			want: &s3fs.ObjectInfo{OwnerID: "owner-id", OwnerDisplayName: "owner"},
+		},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			des, err := s3fs.New(cl, "test", f.opts...).ReadDir(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(des) != 1 {
+				t.Fatalf("want 1 entry; got %d", len(des))
+			}
+
+			fi, err := des[0].Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(fi.Sys(), f.want) {
+				t.Errorf("want %#v; got %#v", f.want, fi.Sys())
+			}
+		})
+	}
+}
+
+func TestKeyValidator(t *testing.T) {
+	errForbidden := errors.New("forbidden key")
+
+	cl := newMemClient()
+	cl.put("public/file.txt", []byte("content"))
+	cl.put("private/file.txt", []byte("content"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithKeyValidator(func(name string) error {
+		if strings.HasPrefix(name, "private") {
+			return errForbidden
+		}
+		return nil
+	}))
+
+	fixtures := []struct {
+		desc string
+		op   string
+		fn   func() error
+	}{
+		{"open", "open", func() error { _, err := fsys.Open("private/file.txt"); return err }},
+		{"stat", "stat", func() error { _, err := fsys.Stat("private/file.txt"); return err }},
+		{"readdir", "readdir", func() error { _, err := fsys.ReadDir("private"); return err }},
+		{"mkdir", "mkdir", func() error { return fsys.MkdirAll("private/dir") }},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			before := len(cl.inputs)
+
+			err := f.fn()
+			if !errors.Is(err, errForbidden) {
+				t.Fatalf("want %v; got %v", errForbidden, err)
+			}
+
+			var perr *fs.PathError
+			if !errors.As(err, &perr) || perr.Op != f.op {
+				t.Errorf("want *fs.PathError with op %s; got %#v", f.op, err)
+			}
+
+			if n := len(cl.inputs) - before; n != 0 {
+				t.Errorf("want no S3 calls; got %d", n)
+			}
+		})
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		if _, err := fsys.Stat("public/file.txt"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestStatViaGet(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("content"))
+	cl.put("empty.txt", nil)
+	cl.put("dir/file.txt", []byte("content"))
+	cl.hook = func(op string, in interface{}) error {
+		if op == "HeadObject" {
+			return apiError(http.StatusForbidden, "AccessDenied")
+		}
+		return nil
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		_, err := s3fs.New(cl, "test").Stat("file.txt")
+		if !s3fs.IsPermission(err) {
+			t.Errorf("want permission error; got %v", err)
+		}
+	})
+
+	fsys := s3fs.New(cl, "test", s3fs.WithStatViaGet)
+
+	fixtures := []struct {
+		name  string
+		size  int64
+		isDir bool
+	}{
+		{name: "file.txt", size: 7},
+		{name: "empty.txt", size: 0},
+		{name: "dir", isDir: true},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.name, func(t *testing.T) {
+			fi, err := fsys.Stat(f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fi.Size() != f.size {
+				t.Errorf("want size %d; got %d", f.size, fi.Size())
+			}
+			if fi.IsDir() != f.isDir {
+				t.Errorf("want isDir %v; got %v", f.isDir, fi.IsDir())
+			}
+		})
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.Stat("missing.txt")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestSeekFileChanged(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("content"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cl.put("file.txt", []byte("changed content"))
+
+	t.Run("seek", func(t *testing.T) {
+		_, err := f.(io.Seeker).Seek(2, io.SeekStart)
+		if !errors.Is(err, s3fs.ErrFileChanged) {
+			t.Errorf("want %v; got %v", s3fs.ErrFileChanged, err)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+
+		for _, in := range cl.getInputs() {
+			if in.Range != nil && in.IfMatch == nil {
+				t.Error("expected reopen to send If-Match")
+			}
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		g, err := fsys.Open("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer g.Close()
+
+		if _, err := g.(io.Seeker).Seek(8, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "content" {
+			t.Errorf("want content; got %s", data)
+		}
+	})
+}
+
+func TestReadStreams(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+
+	cl := newMemClient()
+	cl.put("large.bin", content)
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+	f, err := fsys.Open("large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.CopyBuffer(&buf, struct{ io.Reader }{f}, make([]byte, 32<<10)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Fatal("unexpected content")
+	}
+
+	// the 32 reads are served from the body of the GetObject made by Open.
+	if n := cl.count("GetObject"); n != 1 {
+		t.Errorf("want 1 GetObject; got %d", n)
+	}
+
+	if _, err := f.(io.Seeker).Seek(16, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := io.ReadFull(f, make([]byte, 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := cl.count("GetObject"); n != 2 {
+		t.Errorf("want a single GetObject after Seek; got %d in total", n)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+
+	cl := newMemClient()
+	cl.put("large.bin", content)
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+	f, err := fsys.Open("large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.(io.Seeker).Seek(16, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	before := cl.count("GetObject")
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)-16) || !bytes.Equal(buf.Bytes(), content[16:]) {
+		t.Fatalf("want the %d bytes after the offset; got %d", len(content)-16, n)
+	}
+	if n := cl.count("GetObject") - before; n != 0 {
+		t.Errorf("want the body of the Seek streamed; got %d more GetObjects", n)
+	}
+
+	if got, err := f.(io.Seeker).Seek(0, io.SeekCurrent); err != nil || got != int64(len(content)) {
+		t.Errorf("want the offset at the end; got %d, %v", got, err)
+	}
+
+	t.Run("writer error", func(t *testing.T) {
+		f, err := fsys.Open("large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		errWrite := errors.New("disk full")
+		_, err = f.(io.WriterTo).WriteTo(writerFunc(func(p []byte) (int, error) {
+			return 0, errWrite
+		}))
+		if !errors.Is(err, errWrite) {
+			t.Errorf("want %v; got %v", errWrite, err)
+		}
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("large.bin", content)
+		cl.brokenBodies = 2
+
+		f, err := s3fs.New(cl, "test", s3fs.WithResumableReads).Open("large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var buf bytes.Buffer
+		if _, err := f.(io.WriterTo).WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("want %d bytes of content; got %d", len(content), buf.Len())
+		}
+	})
+}
+
+func TestTruncatedBody(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	cl := newMemClient()
+	cl.put("data.bin", content)
+	cl.truncatedBodies = 1
+
+	f, err := s3fs.New(cl, "test").Open("data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("want %v; got %v", io.ErrUnexpectedEOF, err)
+	}
+	if !strings.Contains(err.Error(), "data.bin") || !strings.Contains(err.Error(), "5000 of 10000") {
+		t.Errorf("want the key and byte counts in the error; got %q", err)
+	}
+	// the bytes before the truncation are still delivered.
+	if !bytes.Equal(data, content[:5000]) {
+		t.Errorf("want the first 5000 bytes; got %d", len(data))
+	}
+
+	t.Run("resumed", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.bin", content)
+		cl.truncatedBodies = 1
+
+		f, err := s3fs.New(cl, "test", s3fs.WithResumableReads).Open("data.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Errorf("want %d bytes of content; got %d", len(content), len(data))
+		}
+	})
+
+	t.Run("write to", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.bin", content)
+		cl.truncatedBodies = 1
+
+		f, err := s3fs.New(cl, "test").Open("data.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var buf bytes.Buffer
+		n, err := f.(io.WriterTo).WriteTo(&buf)
+		if !errors.Is(err, io.ErrUnexpectedEOF) || n != 5000 {
+			t.Errorf("want 5000 bytes and %v; got %d, %v", io.ErrUnexpectedEOF, n, err)
+		}
+	})
+}
+
+// closeCountingClient counts how often the bodies of its GetObjects are
+// closed and how many bytes are read from them.
+type closeCountingClient struct {
+	*memClient
+	closes, read int64
+}
+
+func (c *closeCountingClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.memClient.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Body = &countingBody{ReadCloser: out.Body, c: c}
+	return out, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	c *closeCountingClient
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.c.read += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.c.closes++
+	return b.ReadCloser.Close()
+}
+
+func TestFileClose(t *testing.T) {
+	for _, f := range []struct {
+		desc     string
+		size     int
+		wantRead int64
+	}{
+		{desc: "small rest drained", size: 1 << 10, wantRead: 1 << 10},
+		{desc: "large rest dropped", size: 1 << 20, wantRead: 1 + 4<<10},
+	} {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			cl := &closeCountingClient{memClient: newMemClient()}
+			cl.put("data.bin", make([]byte, f.size))
+
+			file, err := s3fs.New(cl, "test").Open("data.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := file.Read(make([]byte, 1)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Close(); !errors.Is(err, fs.ErrClosed) {
+				t.Errorf("want %v closing twice; got %v", fs.ErrClosed, err)
+			}
+
+			if cl.closes != 1 {
+				t.Errorf("want the body closed once; got %d", cl.closes)
+			}
+			if cl.read != f.wantRead {
+				t.Errorf("want %d bytes read; got %d", f.wantRead, cl.read)
+			}
+		})
+	}
+}
+
+// writerFunc implements io.Writer with a function.
+type writerFunc func(p []byte) (int, error)
+
+func (fn writerFunc) Write(p []byte) (int, error) { return fn(p) }
+
+func TestSeekWhence(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("0123456789"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+
+	fixtures := []struct {
+		desc   string
+		read   int
+		offset int64
+		whence int
+		want   int64
+		data   string
+		err    bool
+	}{
+		{desc: "start", offset: 3, whence: io.SeekStart, want: 3, data: "3456789"},
+		{desc: "current", read: 2, offset: 4, whence: io.SeekCurrent, want: 6, data: "6789"},
+		{desc: "current backwards", read: 5, offset: -3, whence: io.SeekCurrent, want: 2, data: "23456789"},
+		{desc: "end", offset: -3, whence: io.SeekEnd, want: 7, data: "789"},
+		{desc: "end exactly", offset: 0, whence: io.SeekEnd, want: 10, data: ""},
+		{desc: "past eof", offset: 15, whence: io.SeekStart, want: 15, data: ""},
+		{desc: "negative from start", offset: -1, whence: io.SeekStart, err: true},
+		{desc: "negative from current", read: 2, offset: -3, whence: io.SeekCurrent, err: true},
+		{desc: "negative from end", offset: -11, whence: io.SeekEnd, err: true},
+		{desc: "invalid whence", offset: 0, whence: 3, err: true},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			file, err := fsys.Open("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if _, err := io.ReadFull(file, make([]byte, f.read)); err != nil {
+				t.Fatal(err)
+			}
+
+			pos, err := file.(io.Seeker).Seek(f.offset, f.whence)
+			if f.err {
+				if err == nil {
+					t.Fatalf("expected an error; got position %d", pos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if pos != f.want {
+				t.Errorf("want position %d; got %d", f.want, pos)
+			}
+
+			data, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != f.data {
+				t.Errorf("want %q; got %q", f.data, data)
+			}
+		})
+	}
+}
+
+func TestReadAtConcurrent(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64)
+
+	cl := newMemClient()
+	cl.put("file.bin", content)
+
+	f, err := s3fs.New(cl, "test", s3fs.WithReadSeeker).Open("file.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := f.(io.ReaderAt)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+
+			// the ranges of neighbouring goroutines overlap.
+			p := make([]byte, 100)
+			n, err := r.ReadAt(p, off)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(p[:n], content[off:off+100]) {
+				t.Errorf("ReadAt(%d): want %q; got %q", off, content[off:off+100], p[:n])
+			}
+		}(int64(i * 50))
+	}
+	wg.Wait()
+
+	t.Run("offset", func(t *testing.T) {
+		// ReadAt leaves the offset of Read alone.
+		p := make([]byte, 4)
+		if _, err := io.ReadFull(f, p); err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != "0123" {
+			t.Errorf("want 0123; got %q", p)
+		}
+	})
+
+	t.Run("eof", func(t *testing.T) {
+		p := make([]byte, 10)
+		n, err := r.ReadAt(p, int64(len(content)-4))
+		if err != io.EOF {
+			t.Errorf("want io.EOF; got %v", err)
+		}
+		if string(p[:n]) != "cdef" {
+			t.Errorf("want cdef; got %q", p[:n])
+		}
+
+		if n, err := r.ReadAt(p, int64(len(content))); n != 0 || err != io.EOF {
+			t.Errorf("want 0, io.EOF; got %d, %v", n, err)
+		}
+	})
+
+	ranges := make(map[string]bool)
+	for _, in := range cl.getInputs() {
+		if in.Range != nil {
+			ranges[*in.Range] = true
+		}
+	}
+	for _, want := range []string{"bytes=0-99", "bytes=750-849"} {
+		if !ranges[want] {
+			t.Errorf("want a GetObject of range %s", want)
+		}
+	}
+}
+
+func TestReadDirPaging(t *testing.T) {
+	cl := newMemClient()
+	var want []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("file%02d.txt", i)
+		cl.put("dir/"+name, []byte("content"))
+		want = append(want, name)
+	}
+	cl.hook = func(op string, in interface{}) error {
+		if in, ok := in.(*s3.ListObjectsV2Input); ok {
+			in.MaxKeys = 3
+		}
+		return nil
+	}
+
+	f, err := s3fs.New(cl, "test").Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d := f.(fs.ReadDirFile)
+
+	// listing pages are requested as the entries are needed.
+	calls := cl.count("ListObjectsV2")
+
+	var got []string
+	for i := 0; ; i++ {
+		des, err := d.ReadDir(2)
+		for _, de := range des {
+			got = append(got, de.Name())
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(des) != 2 {
+			t.Fatalf("want 2 entries; got %d", len(des))
+		}
+
+		if i == 0 && cl.count("ListObjectsV2") != calls+1 {
+			t.Errorf("want a single listing for the first 2 entries; got %d", cl.count("ListObjectsV2")-calls)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+	if n := cl.count("ListObjectsV2") - calls; n != 4 {
+		t.Errorf("want 4 listings; got %d", n)
+	}
+
+	if des, err := d.ReadDir(2); len(des) != 0 || err != io.EOF {
+		t.Errorf("want no entries and io.EOF; got %d entries and %v", len(des), err)
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"logs/2021-01",
+		"logs/2021-02",
+		"logs/2022-01",
+		"logs/sub/a.txt",
+		"logs/z.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	readDir := func(t *testing.T, fsys fs.FS) (names []string) {
+		t.Helper()
+
+		des, err := fs.ReadDir(fsys, "logs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, de := range des {
+			name := de.Name()
+			if de.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+
+	t.Run("flat", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test", s3fs.WithDelimiter(""))
+
+		want := []string{"2021-01", "2021-02", "2022-01", "sub/a.txt", "z.txt"}
+		if got := readDir(t, fsys); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok && in.Delimiter != nil {
+				t.Errorf("want no delimiter; got %q", *in.Delimiter)
+			}
+		}
+
+		fi, err := fsys.Stat("logs/sub")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Error("want logs/sub to be a directory")
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test", s3fs.WithDelimiter("-"))
+
+		want := []string{"2021/", "2022/", "sub/", "z.txt"}
+		if got := readDir(t, fsys); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		fi, err := fsys.Stat("logs/2021")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Error("want logs/2021 to be a directory")
+		}
+
+		for _, name := range []string{"logs/2021", "logs/sub"} {
+			des, err := fs.ReadDir(fsys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, de := range des {
+				names = append(names, de.Name())
+			}
+			want := map[string][]string{"logs/2021": {"01", "02"}, "logs/sub": {"a.txt"}}[name]
+			if !reflect.DeepEqual(names, want) {
+				t.Errorf("%s: want %q; got %q", name, want, names)
+			}
+		}
+
+		var dirs []string
+		err = fsys.WalkContext(context.Background(), "logs", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				dirs = append(dirs, name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"logs", "logs/2021", "logs/2022", "logs/sub"}; !reflect.DeepEqual(dirs, want) {
+			t.Errorf("want %q; got %q", want, dirs)
+		}
+	})
+}
+
+func TestSpecialCharacterKeys(t *testing.T) {
+	keys := []string{
+		"reports/Q1 2024 (final).pdf",
+		"reports/a+b=c.txt",
+		"reports/été ☃.txt",
+		"reports/x %2F y/z.txt",
+	}
+
+	cl := newMemClient()
+	for _, key := range keys {
+		cl.put(key, []byte(key))
+	}
+	fsys := s3fs.New(cl, "test")
+
+	for _, key := range keys {
+		data, err := fs.ReadFile(fsys, key)
+		if err != nil {
+			t.Errorf("open %q: %v", key, err)
+		} else if string(data) != key {
+			t.Errorf("open %q: got content %q", key, data)
+		}
+
+		if fi, err := fsys.Stat(key); err != nil {
+			t.Errorf("stat %q: %v", key, err)
+		} else if fi.Name() != path.Base(key) {
+			t.Errorf("stat %q: got name %q", key, fi.Name())
+		}
+	}
+
+	des, err := fsys.ReadDir("reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+	want := []string{"Q1 2024 (final).pdf", "a+b=c.txt", "x %2F y", "été ☃.txt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("want entries %q; got %q", want, names)
+	}
+
+	t.Run("wire", func(t *testing.T) {
+		key := keys[0]
+		var paths []string
+		cl := s3.New(s3.Options{
+			Region:       "us-east-1",
+			Credentials:  aws.AnonymousCredentials{},
+			UsePathStyle: true,
+			HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+				paths = append(paths, r.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Length": []string{"4"}},
+					Body:       io.NopCloser(strings.NewReader("data")),
+				}, nil
+			}),
+		})
+
+		fsys := s3fs.New(cl, "bucket")
+		if _, err := fsys.ReadFile(key); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fsys.Stat(key); err != nil {
+			t.Fatal(err)
+		}
+
+		// the key is escaped once by the SDK, so it decodes back to itself.
+		for _, p := range paths {
+			if p != "/bucket/"+key {
+				t.Errorf("want path %q; got %q", "/bucket/"+key, p)
+			}
+		}
+	})
+}
+
+func TestDirEntryKey(t *testing.T) {
+	type keyer interface{ Key() string }
+
+	cl := newMemClient()
+	cl.put("a/b/c d.txt", []byte("c"))
+	cl.put("a/b/sub/e.txt", []byte("e"))
+
+	fsys := s3fs.New(cl, "test")
+	des, err := fsys.ReadDir("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"c d.txt": "a/b/c d.txt", "sub": "a/b/sub"}
+	if len(des) != len(want) {
+		t.Fatalf("want %d entries; got %d", len(want), len(des))
+	}
+	for _, de := range des {
+		k, ok := de.(keyer)
+		if !ok {
+			t.Fatalf("%s: entry has no Key method", de.Name())
+		}
+		if got := k.Key(); got != want[de.Name()] {
+			t.Errorf("%s: want key %q; got %q", de.Name(), want[de.Name()], got)
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fi.(keyer).Key(); got != want[de.Name()] {
+			t.Errorf("%s: want info key %q; got %q", de.Name(), want[de.Name()], got)
+		}
+	}
+
+	fi, err := fsys.Stat("a/b/c d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fi.(keyer).Key(); got != "a/b/c d.txt" {
+		t.Errorf("stat: want key a/b/c d.txt; got %q", got)
+	}
+
+	t.Run("walk flat", func(t *testing.T) {
+		keys := make(map[string]string)
+		err := fsys.WalkFlat(context.Background(), "a", func(name string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			keys[name] = de.(keyer).Key()
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, key := range keys {
+			if key != name {
+				t.Errorf("%s: want the key to be the walked name; got %q", name, key)
+			}
+		}
+	})
+}
+
+func TestReadDirInfo(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/a.txt", []byte("a"))
+	cl.put("dir/b.txt", []byte("bbb"))
+	cl.put("dir/sub/c.txt", []byte("c"))
+
+	fsys := s3fs.New(cl, "test")
+
+	fis, err := fsys.ReadDirInfo("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name string
+		size int64
+		dir  bool
+	}{
+		{name: "a.txt", size: 1},
+		{name: "b.txt", size: 3},
+		{name: "sub", dir: true},
+	}
+
+	if len(fis) != len(want) {
+		t.Fatalf("want %d entries; got %d", len(want), len(fis))
+	}
+	for i, w := range want {
+		fi := fis[i]
+		if fi.Name() != w.name || fi.Size() != w.size || fi.IsDir() != w.dir {
+			t.Errorf("want %+v; got %s (size %d, dir %v)", w, fi.Name(), fi.Size(), fi.IsDir())
+		}
+		if !w.dir && fi.ModTime().IsZero() {
+			t.Errorf("%s: expected modtime to be set", fi.Name())
+		}
+	}
+
+	// the only HeadObject is the stat of "dir" itself.
+	if n := cl.count("HeadObject"); n != 1 {
+		t.Errorf("want 1 HeadObject; got %d", n)
+	}
+}
+
+func TestListPrefetchMetadata(t *testing.T) {
+	cl := newMemClient()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		o := cl.put(name, []byte(name))
+		o.contentType = "text/plain"
+		o.metadata = map[string]string{"name": name}
+	}
+	cl.put("dir/d.txt", []byte("d"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithListPrefetchMetadata(2))
+
+	des, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := cl.count("HeadObject"); n != 3 {
+		t.Errorf("want 3 HeadObjects; got %d", n)
+	}
+
+	calls := len(cl.inputs)
+	for _, de := range des {
+		fi, err := de.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.IsDir() {
+			continue
+		}
+
+		info, ok := fi.Sys().(*s3fs.ObjectInfo)
+		if !ok {
+			t.Fatalf("%s: want *s3fs.ObjectInfo; got %T", fi.Name(), fi.Sys())
+		}
+		if info.ContentType != "text/plain" {
+			t.Errorf("%s: want content type text/plain; got %q", fi.Name(), info.ContentType)
+		}
+		if info.Metadata["name"] != fi.Name() {
+			t.Errorf("%s: want metadata name %q; got %q", fi.Name(), fi.Name(), info.Metadata["name"])
+		}
+	}
+
+	if n := len(cl.inputs); n != calls {
+		t.Errorf("want no S3 calls after ReadDir; got %d", n-calls)
+	}
+}
+
+func TestReadFileDir(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("content"))
+
+	fsys := s3fs.New(cl, "test")
+
+	for _, name := range []string{"dir", "."} {
+		_, err := fsys.ReadFile(name)
+		if !errors.Is(err, s3fs.ErrIsDir) {
+			t.Errorf("%s: want %v; got %v", name, s3fs.ErrIsDir, err)
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: did not expect %v", name, fs.ErrNotExist)
+		}
+	}
+
+	if _, err := fsys.ReadFile("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	data, err := fs.ReadFile(fsys, "dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+}
+
+func TestReadFileSingleRequest(t *testing.T) {
+	content := bytes.Repeat([]byte("config"), 10000)
+
+	cl := newMemClient()
+	cl.put("config.json", content)
+
+	fsys := s3fs.New(cl, "test")
+
+	data, err := fs.ReadFile(fsys, "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatal("unexpected content")
+	}
+
+	if len(cl.inputs) != 1 {
+		t.Fatalf("want a single request; got %d", len(cl.inputs))
+	}
+	if in := cl.getInputs(); len(in) != 1 || in[0].Range != nil {
+		t.Errorf("want a single GetObject without Range; got %d", len(in))
+	}
+
+	var perr *fs.PathError
+	if _, err := fs.ReadFile(fsys, "missing.json"); !errors.As(err, &perr) || perr.Op != "readfile" || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want readfile PathError matching %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestObjectInfoHeaders(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cl := newMemClient()
+	o := cl.put("cached.txt", []byte("content"))
+	o.cacheControl = "max-age=3600"
+	o.contentDisposition = `attachment; filename="cached.txt"`
+	o.contentEncoding = "identity"
+	o.expires = expires
+	plain := cl.put("plain.txt", []byte("content"))
+
+	want := &s3fs.ObjectInfo{
+		ETag:               o.etag,
+		CacheControl:       "max-age=3600",
+		ContentDisposition: `attachment; filename="cached.txt"`,
+		ContentEncoding:    "identity",
+		Expires:            expires,
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("stat", func(t *testing.T) {
+		fi, err := fsys.Stat("cached.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fi.Sys(), want) {
+			t.Errorf("want %#v; got %#v", want, fi.Sys())
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		f, err := fsys.Open("cached.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fi.Sys(), want) {
+			t.Errorf("want %#v; got %#v", want, fi.Sys())
+		}
+	})
+
+	t.Run("no headers", func(t *testing.T) {
+		fi, err := fsys.Stat("plain.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (&s3fs.ObjectInfo{ETag: plain.etag}); !reflect.DeepEqual(fi.Sys(), want) {
+			t.Errorf("want only the ETag; got %#v", fi.Sys())
+		}
+	})
+}
+
+func TestListAuditor(t *testing.T) {
+	cl := newMemClient()
+	for i := 0; i < 2500; i++ {
+		cl.put(fmt.Sprintf("dir/file%04d", i), nil)
+	}
+	cl.put("dir/sub/file", nil)
+
+	type page struct {
+		prefix   string
+		returned int
+	}
+
+	var got []page
+	fsys := s3fs.New(cl, "test", s3fs.WithListAuditor(func(prefix string, returned int) {
+		got = append(got, page{prefix, returned})
+	}))
+
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []page{{"dir/", 1000}, {"dir/", 1000}, {"dir/", 501}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	newFS := func() (*memClient, *s3fs.S3FS) {
+		cl := newMemClient()
+		cl.put("a.txt", []byte("a"))
+		cl.put("dir/b.txt", []byte("b"))
+		return cl, s3fs.New(cl, "test")
+	}
+
+	checkRoot := func(t *testing.T, fi fs.FileInfo) {
+		t.Helper()
+
+		if fi.Name() != "." {
+			t.Errorf("want name %q; got %q", ".", fi.Name())
+		}
+		if !fi.IsDir() || fi.Mode() != fs.ModeDir {
+			t.Errorf("want mode %v; got %v", fs.ModeDir, fi.Mode())
+		}
+		if fi.Size() != 0 || !fi.ModTime().IsZero() {
+			t.Errorf("want zero size and modtime; got %d, %v", fi.Size(), fi.ModTime())
+		}
+	}
+
+	names := func(des []fs.DirEntry) []string {
+		var names []string
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		return names
+	}
+
+	t.Run("stat", func(t *testing.T) {
+		cl, fsys := newFS()
+
+		fi, err := fsys.Stat(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRoot(t, fi)
+
+		if n := len(cl.inputs); n != 0 {
+			t.Errorf("want no S3 calls; got %d", n)
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		_, fsys := newFS()
+
+		f, err := fsys.Open(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRoot(t, fi)
+
+		d, ok := f.(fs.ReadDirFile)
+		if !ok {
+			t.Fatalf("want fs.ReadDirFile; got %T", f)
+		}
+		des, err := d.ReadDir(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := names(des), []string{"a.txt", "dir"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		if _, err := f.Read(make([]byte, 1)); !errors.Is(err, s3fs.ErrIsDir) {
+			t.Errorf("want %v; got %v", s3fs.ErrIsDir, err)
+		}
+	})
+
+	t.Run("readdir", func(t *testing.T) {
+		cl, fsys := newFS()
+
+		des, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := names(des), []string{"a.txt", "dir"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		// the root is listed without a prefix.
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok && aws.ToString(in.Prefix) != "" {
+				t.Errorf("want empty prefix; got %q", aws.ToString(in.Prefix))
+			}
+		}
+	})
+
+	t.Run("empty bucket", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(), "test")
+
+		fi, err := fsys.Stat(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRoot(t, fi)
+
+		des, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(des) != 0 {
+			t.Errorf("want no entries; got %q", names(des))
+		}
+	})
+
+	t.Run("key validator", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("a.txt", []byte("a"))
+
+		fsys := s3fs.New(cl, "test", s3fs.WithKeyValidator(func(string) error {
+			return errors.New("rejected")
+		}))
+		if _, err := fsys.Stat("."); err != nil {
+			t.Errorf("want the root to skip validation; got %v", err)
+		}
+		if _, err := fsys.ReadDir("."); err != nil {
+			t.Errorf("want the root to skip validation; got %v", err)
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("content"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("canceled", func(t *testing.T) {
+		if _, err := fsys.OpenContext(canceled, "dir/file.txt"); !errors.Is(err, context.Canceled) {
+			t.Errorf("OpenContext: want %v; got %v", context.Canceled, err)
+		}
+		if _, err := fsys.StatContext(canceled, "dir/file.txt"); !errors.Is(err, context.Canceled) {
+			t.Errorf("StatContext: want %v; got %v", context.Canceled, err)
+		}
+		if _, err := fsys.ReadDirContext(canceled, "dir"); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadDirContext: want %v; got %v", context.Canceled, err)
+		}
+		if n := len(cl.inputs); n != 0 {
+			t.Errorf("want no calls to go through; got %d", n)
+		}
+	})
+
+	t.Run("read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		f, err := fsys.OpenContext(ctx, "dir/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		cancel()
+		if _, err := f.(io.Seeker).Seek(3, io.SeekStart); !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("background", func(t *testing.T) {
+		des, err := fsys.ReadDirContext(context.Background(), "dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(des) != 1 || des[0].Name() != "file.txt" {
+			t.Errorf("want file.txt; got %v", des)
+		}
+	})
+}
+
+func TestReadShortBuffer(t *testing.T) {
+	content := make([]byte, 10*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	cl := newMemClient()
+	cl.put("file.bin", content)
+
+	for name, opts := range map[string][]s3fs.Option{
+		"default":    nil,
+		"readseeker": {s3fs.WithReadSeeker},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := s3fs.New(cl, "test", opts...).Open("file.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			var got []byte
+			p := make([]byte, 3)
+			for {
+				n, err := f.Read(p)
+				got = append(got, p[:n]...)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("want %d bytes equal to the object; got %d bytes that differ", len(content), len(got))
+			}
+		})
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	cl := newMemClient()
+	for _, name := range []string{"dir/a", "dir/b", "dir/c", "dir/d", "dir/sub/e"} {
+		cl.put(name, []byte(name))
+	}
+
+	listed := func(n int32) []int32 {
+		var maxKeys []int32
+		cl.hook = func(op string, in interface{}) error {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok && op == "ListObjectsV2" {
+				maxKeys = append(maxKeys, in.MaxKeys)
+			}
+			return nil
+		}
+		defer func() { cl.hook = nil }()
+
+		des, err := fs.ReadDir(s3fs.New(cl, "test", s3fs.WithMaxKeys(n)), "dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		if want := []string{"a", "b", "c", "d", "sub"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("MaxKeys %d: want %q; got %q", n, want, names)
+		}
+		return maxKeys
+	}
+
+	// the first listing is the existence check of Open, which lists 1 key.
+	if got := listed(2); !reflect.DeepEqual(got, []int32{1, 2, 2, 2}) {
+		t.Errorf("want 3 pages of 2 keys; got %v", got)
+	}
+	if got := listed(0); !reflect.DeepEqual(got, []int32{1, 1, 1, 1, 1, 1}) {
+		t.Errorf("want 5 pages of 1 key; got %v", got)
+	}
+	if got := listed(5000); !reflect.DeepEqual(got, []int32{1, 1000}) {
+		t.Errorf("want a page of 1000 keys; got %v", got)
+	}
+}
+
+func TestDirMarkers(t *testing.T) {
+	cl := newMemClient()
+	cl.put("folder/", nil)
+	cl.put("folder/file.txt", []byte("content"))
+	cl.put("folder/empty/", nil)
+	fsys := s3fs.New(cl, "test")
+
+	if err := fstest.TestFS(fsys, "folder/file.txt", "folder/empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string][]string{
+		".":            {"folder"},
+		"folder":       {"empty", "file.txt"},
+		"folder/empty": nil,
+	} {
+		des, err := fsys.ReadDir(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("ReadDir(%s): want %q; got %q", name, want, names)
+		}
+	}
+
+	for _, name := range []string{"folder", "folder/empty"} {
+		fi, err := fsys.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Errorf("expected %s to be a directory", name)
+		}
+	}
+}
+
+func TestReadDirectoryAsFile(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/sub/file.txt", []byte("content"))
+	fsys := s3fs.New(cl, "test")
+
+	for _, name := range []string{".", "dir/sub"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := f.(fs.ReadDirFile); !ok {
+			t.Errorf("%s: want fs.ReadDirFile; got %T", name, f)
+		}
+
+		n, err := f.Read(make([]byte, 10))
+		var perr *fs.PathError
+		if n != 0 || !errors.Is(err, s3fs.ErrIsDir) || !errors.As(err, &perr) || perr.Op != "read" || perr.Path != name {
+			t.Errorf("%s: want 0 bytes and read %s: %v; got %d bytes and %v", name, name, s3fs.ErrIsDir, n, err)
+		}
+		f.Close()
+	}
+}
+
+// ungroupedClient ignores the delimiter of listings, as some S3-compatible
+// stores do, and so lists every key below the prefix.
+type ungroupedClient struct {
+	*memClient
+}
+
+func (c ungroupedClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	params := *in
+	params.Delimiter = nil
+	return c.memClient.ListObjectsV2(ctx, &params, optFns...)
+}
+
+func TestReadDirEntryNames(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"photos/",
+		"photos/2023/a.jpg",
+		"photos/2023/jan/b.jpg",
+		"photos/2023-old/c.jpg",
+		"photos/2023.txt",
+		"photos//empty-element.jpg",
+		"photos-old/d.jpg",
+	} {
+		// the marker of photos has content, so it is not skipped as empty.
+		cl.put(key, []byte("x"))
+	}
+
+	want := map[string][]string{
+		".":               {"photos/", "photos-old/"},
+		"photos":          {"2023/", "2023-old/", "2023.txt"},
+		"photos/2023":     {"a.jpg", "jan/"},
+		"photos/2023/jan": {"b.jpg"},
+	}
+
+	for desc, client := range map[string]s3fs.S3Client{"grouped": cl, "ungrouped": ungroupedClient{cl}} {
+		fsys := s3fs.New(client, "test")
+		t.Run(desc, func(t *testing.T) {
+			for name, want := range want {
+				des, err := fsys.ReadDir(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var got []string
+				for _, de := range des {
+					if strings.Contains(de.Name(), "/") {
+						t.Errorf("%s: entry name %q has a slash", name, de.Name())
+					}
+					n := de.Name()
+					if de.IsDir() {
+						n += "/"
+					}
+					got = append(got, n)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: want %v; got %v", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+// twoPageClient lists "dir" in two truncated pages, the second one
+// repeating a common prefix of the first.
+type twoPageClient struct {
+	*memClient
+}
+
+func (c twoPageClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := c.record(ctx, "ListObjectsV2", in); err != nil {
+		return nil, err
+	}
+
+	object := func(key string) types.Object {
+		return types.Object{Key: aws.String(key), Size: 1, LastModified: aws.Time(c.now)}
+	}
+	if in.ContinuationToken == nil {
+		return &s3.ListObjectsV2Output{
+			Contents:              []types.Object{object("dir/a.txt"), object("dir/b.txt")},
+			CommonPrefixes:        []types.CommonPrefix{{Prefix: aws.String("dir/m/")}},
+			IsTruncated:           true,
+			NextContinuationToken: aws.String("page-2"),
+		}, nil
+	}
+	if *in.ContinuationToken != "page-2" {
+		return nil, apiError(http.StatusBadRequest, "InvalidArgument")
+	}
+	return &s3.ListObjectsV2Output{
+		Contents:       []types.Object{object("dir/n.txt"), object("dir/y.txt")},
+		CommonPrefixes: []types.CommonPrefix{{Prefix: aws.String("dir/m/")}, {Prefix: aws.String("dir/z/")}},
+	}, nil
+}
+
+func TestReadDirFollowsPages(t *testing.T) {
+	cl := twoPageClient{newMemClient()}
+	fsys := s3fs.New(cl, "test")
+
+	before := cl.count("ListObjectsV2")
+	des, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, de := range des {
+		got = append(got, de.Name())
+	}
+	if want := []string{"a.txt", "b.txt", "m", "n.txt", "y.txt", "z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v; got %v", want, got)
+	}
+	// the stat of dir, then both pages.
+	if n := cl.count("ListObjectsV2") - before; n != 3 {
+		t.Errorf("want 3 listings; got %d", n)
+	}
+}
+
+func TestDirFirst(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("content"))
+	cl.put("dir/sub/other.txt", []byte("other"))
+	fsys := s3fs.New(cl, "test", s3fs.WithDirFirst)
+
+	if err := fstest.TestFS(fsys, "dir/file.txt", "dir/sub/other.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := func() map[string]int {
+		m := make(map[string]int)
+		for _, op := range []string{"GetObject", "HeadObject", "ListObjectsV2"} {
+			m[op] = cl.count(op)
+		}
+		return m
+	}
+	diff := func(before map[string]int) map[string]int {
+		m := make(map[string]int)
+		for op, n := range requests() {
+			if n != before[op] {
+				m[op] = n - before[op]
+			}
+		}
+		return m
+	}
+
+	for _, f := range []struct {
+		name string
+		want map[string]int
+		err  error
+	}{
+		{name: "dir/sub", want: map[string]int{"ListObjectsV2": 1}},
+		{name: "dir/file.txt", want: map[string]int{"ListObjectsV2": 1, "GetObject": 1}},
+		{name: "dir/missing", want: map[string]int{"ListObjectsV2": 1, "GetObject": 1}, err: fs.ErrNotExist},
+	} {
+		before := requests()
+		file, err := fsys.Open(f.name)
+		if !errors.Is(err, f.err) {
+			t.Fatalf("%s: want %v; got %v", f.name, f.err, err)
+		}
+		if err == nil {
+			file.Close()
+		}
+
+		if got := diff(before); !reflect.DeepEqual(got, f.want) {
+			t.Errorf("%s: want requests %v; got %v", f.name, f.want, got)
+		}
+	}
 }
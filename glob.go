@@ -0,0 +1,244 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var _ fs.GlobFS = (*S3FS)(nil)
+
+// Glob implements fs.GlobFS. It follows the conventions of fs.Glob: every
+// match, file or directory, is returned as its full slash-separated path
+// relative to the root of the filesystem, e.g. "logs/2021" rather than
+// "2021". Matches are sorted.
+//
+// The literal part of the pattern before its first meta character is used
+// as listing prefix, so "logs/2021-*" only lists keys starting with
+// "logs/2021-". If only the last segment of the pattern has meta
+// characters a single directory is listed; otherwise all the keys below
+// the prefix are listed at once, so "logs/2021-*/*.json" takes a single
+// listing instead of one per matching directory.
+//
+// Unlike path.Match, patterns can contain brace expressions:
+// "logs/{2022,2023}/*.log" matches what "logs/2022/*.log" and
+// "logs/2023/*.log" do. Braces without a comma between them are matched
+// literally.
+func (f *S3FS) Glob(pattern string) ([]string, error) {
+	// check the pattern is well-formed.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	if patterns := expandBraces(pattern); len(patterns) > 1 {
+		return f.globAll(patterns)
+	}
+
+	if !hasMeta(pattern) {
+		if _, err := f.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := path.Split(pattern)
+	dir = cleanGlobPath(dir)
+
+	if !hasMeta(dir) {
+		return f.glob(dir, file, nil)
+	}
+
+	// a snapshot has to be matched against its own listing.
+	if f.snapshot == nil {
+		return f.globKeys(pattern), nil
+	}
+
+	// prevent infinite recursion.
+	if dir == pattern {
+		return nil, path.ErrBadPattern
+	}
+
+	dirs, err := f.Glob(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, d := range dirs {
+		if matches, err = f.glob(d, file, matches); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// globAll returns the union of the matches of patterns, sorted.
+func (f *S3FS) globAll(patterns []string) ([]string, error) {
+	var (
+		matches []string
+		seen    = make(map[string]bool)
+	)
+	for _, pattern := range patterns {
+		m, err := f.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range m {
+			if !seen[name] {
+				seen[name] = true
+				matches = append(matches, name)
+			}
+		}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// globKeys matches pattern against the paths of all the objects below the
+// literal prefix of pattern, and against the paths of their directories.
+// Like * does not match a "/", a path only matches if it has as many
+// segments as the pattern. Errors listing the keys are ignored.
+func (f *S3FS) globKeys(pattern string) []string {
+	prefix := literalPrefix(pattern)
+	if prefix == "" {
+		prefix = "."
+	}
+	segments := strings.Count(pattern, "/") + 1
+
+	var (
+		matches []string
+		seen    = make(map[string]bool)
+	)
+	f.listKeys(context.TODO(), "glob", prefix, func(o types.Object) {
+		// the first segments of a longer key name one of its directories.
+		segs := strings.SplitN(aws.ToString(o.Key), "/", segments+1)
+		if len(segs) < segments || segs[segments-1] == "" {
+			return
+		}
+
+		name := strings.Join(segs[:segments], "/")
+		if seen[name] || !fs.ValidPath(name) {
+			return
+		}
+		seen[name] = true
+
+		if matched, _ := path.Match(pattern, name); matched {
+			matches = append(matches, name)
+		}
+	})
+
+	sort.Strings(matches)
+	return matches
+}
+
+// glob appends to matches the entries of the directory name matching
+// pattern. Errors listing the directory are ignored, like fs.Glob does.
+func (f *S3FS) glob(name, pattern string, matches []string) ([]string, error) {
+	d, err := f.openDir(context.TODO(), name)
+	if err != nil {
+		return matches, nil
+	}
+
+	if d, ok := d.(*dir); ok {
+		d.namePrefix = literalPrefix(pattern)
+	}
+
+	des, err := d.ReadDir(-1)
+	if err != nil {
+		return matches, nil
+	}
+
+	for _, de := range des {
+		matched, err := path.Match(pattern, de.Name())
+		if err != nil {
+			return matches, err
+		}
+		if matched {
+			matches = append(matches, path.Join(name, de.Name()))
+		}
+	}
+	return matches, nil
+}
+
+// cleanGlobPath prepares dir for glob matching.
+func cleanGlobPath(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir[:len(dir)-1]
+}
+
+// hasMeta reports whether pattern contains any of the magic characters
+// recognized by path.Match.
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// literalPrefix returns the part of pattern before its first magic character.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// expandBraces returns the patterns the brace expressions of pattern expand
+// to, e.g. "{a,b}/{c,d}" expands to "a/c", "a/d", "b/c" and "b/d". Braces
+// can be nested. Escaped braces and braces without a comma are kept as
+// they are.
+func expandBraces(pattern string) []string {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			alts, end := braceAlternatives(pattern, i)
+			if len(alts) < 2 {
+				continue
+			}
+
+			var patterns []string
+			for _, alt := range alts {
+				patterns = append(patterns, expandBraces(pattern[:i]+alt+pattern[end+1:])...)
+			}
+			return patterns
+		}
+	}
+	return []string{pattern}
+}
+
+// braceAlternatives returns the comma separated alternatives of the brace
+// expression starting at pattern[start] and the index of its closing
+// brace. It returns no alternatives if the brace is not closed.
+func braceAlternatives(pattern string, start int) ([]string, int) {
+	var (
+		alts  []string
+		depth int
+		last  = start + 1
+	)
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return append(alts, pattern[last:i]), i
+			}
+		case ',':
+			if depth == 1 {
+				alts = append(alts, pattern[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,171 @@
+package s3fs_test
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestGlob(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"data/a.txt",
+		"data/b.csv",
+		"data/sub/c.txt",
+		"data/sub2/d.txt",
+		"logs/2021-01.log",
+		"logs/2022-01.log",
+		"top.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	fixtures := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "data/*", want: []string{"data/a.txt", "data/b.csv", "data/sub", "data/sub2"}},
+		{pattern: "data/sub*", want: []string{"data/sub", "data/sub2"}},
+		{pattern: "*/*.txt", want: []string{"data/a.txt"}},
+		{pattern: "data/*/*.txt", want: []string{"data/sub/c.txt", "data/sub2/d.txt"}},
+		{pattern: "*", want: []string{"data", "logs", "top.txt"}},
+		{pattern: "top.txt", want: []string{"top.txt"}},
+		{pattern: "missing*", want: nil},
+		{pattern: "data/missing/*", want: nil},
+	}
+
+	for _, f := range fixtures {
+		got, err := fs.Glob(fsys, f.pattern)
+		if err != nil {
+			t.Errorf("%s: %v", f.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, f.want) {
+			t.Errorf("%s: want %q; got %q", f.pattern, f.want, got)
+		}
+	}
+
+	t.Run("literal prefix", func(t *testing.T) {
+		cl.inputs = nil
+
+		got, err := fsys.Glob("logs/2021-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"logs/2021-01.log"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+
+		var prefixes []string
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok && in.MaxKeys == 0 {
+				prefixes = append(prefixes, *in.Prefix)
+			}
+		}
+		if want := []string{"logs/2021-"}; !reflect.DeepEqual(prefixes, want) {
+			t.Errorf("want listings of %q; got %q", want, prefixes)
+		}
+	})
+
+	t.Run("bad pattern", func(t *testing.T) {
+		if _, err := fsys.Glob("data/["); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestGlobSingleListing(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"logs/2023-01/a.json",
+		"logs/2023-01/b.txt",
+		"logs/2023-02/c.json",
+		"logs/2023-02/deep/d.json",
+		"logs/2023-03/",
+		"logs/2022-12/e.json",
+		"logs/2023-04.json",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	got, err := fsys.Glob("logs/2023-*/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"logs/2023-01/a.json", "logs/2023-02/c.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	if n := cl.count("ListObjectsV2"); n != 1 {
+		t.Errorf("want a single listing; got %d", n)
+	}
+
+	got, err = fsys.Glob("logs/*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"logs/2022-12/e.json",
+		"logs/2023-01/a.json",
+		"logs/2023-01/b.txt",
+		"logs/2023-02/c.json",
+		"logs/2023-02/deep",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	got, err = fsys.Glob("*/2023-0[13]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"logs/2023-01", "logs/2023-03"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestGlobBraces(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"logs/2021/a.log",
+		"logs/2022/b.log",
+		"logs/2022/b.txt",
+		"logs/2023/c.log",
+		"logs/2023/sub/d.log",
+		"tmpl/{id}.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	fixtures := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "logs/{2022,2023}/*.log", want: []string{"logs/2022/b.log", "logs/2023/c.log"}},
+		{pattern: "logs/{2022,202[23]}/*.log", want: []string{"logs/2022/b.log", "logs/2023/c.log"}},
+		{pattern: "logs/2022/*.{log,txt}", want: []string{"logs/2022/b.log", "logs/2022/b.txt"}},
+		{pattern: "logs/{2021/*,2023/{c,sub/d}}.log", want: []string{"logs/2021/a.log", "logs/2023/c.log", "logs/2023/sub/d.log"}},
+		{pattern: "tmpl/{id}.txt", want: []string{"tmpl/{id}.txt"}},
+		{pattern: "logs/{2024,2025}/*", want: nil},
+	}
+
+	for _, f := range fixtures {
+		got, err := fsys.Glob(f.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", f.pattern, err)
+		}
+		if !reflect.DeepEqual(got, f.want) {
+			t.Errorf("%s: want %q; got %q", f.pattern, f.want, got)
+		}
+	}
+}
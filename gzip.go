@@ -0,0 +1,117 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithGzipFallback makes Open, Stat and ReadFile of a missing object name
+// fall back to "name.gz", which is then decompressed transparently; a
+// common layout for pre-compressed static assets.
+//
+// Stat reports the decompressed size if it was stored by
+// WithCompressOnWrite, otherwise the compressed size. Files served from
+// the gzip variant cannot Seek.
+func WithGzipFallback(fsys *S3FS) { fsys.gzipFallback = true }
+
+// openGzip opens name.gz as name, decompressing its content.
+func (f *S3FS) openGzip(ctx context.Context, name string) (*gzipFile, error) {
+	if strings.HasSuffix(name, ".gz") {
+		return nil, fs.ErrNotExist
+	}
+
+	out, err := f.getObject(ctx, &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name + ".gz"),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(out.Body)
+	if err != nil {
+		out.Body.Close()
+		return nil, err
+	}
+
+	return &gzipFile{
+		Reader: zr,
+		body:   out.Body,
+		info: fileInfo{
+			name:    name,
+			size:    gzipSize(out.ContentLength, out.Metadata),
+			modTime: derefTime(out.LastModified),
+			eTag:    aws.ToString(out.ETag),
+		},
+	}, nil
+}
+
+// statGzip stats name.gz as name.
+func (f *S3FS) statGzip(ctx context.Context, name string) (fs.FileInfo, error) {
+	if strings.HasSuffix(name, ".gz") {
+		return nil, fs.ErrNotExist
+	}
+
+	head, err := f.headObject(ctx, name+".gz")
+	if err != nil {
+		return nil, err
+	}
+
+	fi := headFileInfo(name, head)
+	fi.size = gzipSize(head.ContentLength, head.Metadata)
+	return fi, nil
+}
+
+// readGzip reads the decompressed content of name.gz.
+func (f *S3FS) readGzip(name string) ([]byte, error) {
+	file, err := f.openGzip(context.TODO(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// gzipSize returns the uncompressed size stored in metadata, or size if
+// there is none.
+func gzipSize(size int64, metadata map[string]string) int64 {
+	if n, err := strconv.ParseInt(metadata[metaUncompressedSize], 10, 64); err == nil {
+		return n
+	}
+	return size
+}
+
+var _ fs.File = (*gzipFile)(nil)
+
+// gzipFile is a file decompressed while it is read.
+type gzipFile struct {
+	*gzip.Reader
+	body io.Closer
+	info fileInfo
+}
+
+func (f *gzipFile) Stat() (fs.FileInfo, error) {
+	fi := f.info
+	fi.name = path.Base(fi.name)
+	return &fi, nil
+}
+
+func (f *gzipFile) Close() error {
+	zerr := f.Reader.Close()
+	if err := f.body.Close(); err != nil {
+		return err
+	}
+	return zerr
+}
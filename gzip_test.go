@@ -0,0 +1,102 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestGzipFallback(t *testing.T) {
+	content := strings.Repeat("console.log('hello');\n", 100)
+
+	cl := newMemClient()
+	if err := s3fs.New(cl, "test", s3fs.WithCompressOnWrite(gzip.DefaultCompression)).
+		WriteFile("app.js.gz", []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a gzip variant without the uncompressed size.
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, "body {}")
+	zw.Close()
+	cl.put("style.css.gz", buf.Bytes())
+
+	cl.put("plain.txt", []byte("plain"))
+
+	fsys := s3fs.New(cl, "test", s3fs.WithGzipFallback)
+
+	t.Run("open", func(t *testing.T) {
+		f, err := fsys.Open("app.js")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Error("expected the decompressed content")
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Name() != "app.js" || fi.Size() != int64(len(content)) {
+			t.Errorf("want app.js of size %d; got %s of size %d", len(content), fi.Name(), fi.Size())
+		}
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		fi, err := fsys.Stat("app.js")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(len(content)) {
+			t.Errorf("want size %d; got %d", len(content), fi.Size())
+		}
+
+		fi, err = fsys.Stat("style.css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(buf.Len()) {
+			t.Errorf("want the compressed size %d; got %d", buf.Len(), fi.Size())
+		}
+	})
+
+	t.Run("read file", func(t *testing.T) {
+		data, err := fsys.ReadFile("style.css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "body {}" {
+			t.Errorf("want %q; got %q", "body {}", data)
+		}
+
+		data, err = fsys.ReadFile("plain.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "plain" {
+			t.Errorf("want %q; got %q", "plain", data)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		for _, name := range []string{"missing.js", "missing.js.gz"} {
+			if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%s: want %v; got %v", name, fs.ErrNotExist, err)
+			}
+		}
+	})
+}
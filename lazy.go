@@ -0,0 +1,177 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenLazy opens the object name for scattered reads. Nothing but a
+// HeadObject is sent upfront; the content is fetched with ranged GetObjects
+// as it is asked for with At and kept in memory, so that overlapping reads
+// only fetch the bytes that were not read before.
+//
+// Reads send the ETag the object had when it was opened with If-Match, and
+// fail with ErrFileChanged if it was replaced since.
+func (f *S3FS) OpenLazy(name string) (*LazyObject, error) {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	return &LazyObject{
+		fsys: f,
+		name: name,
+		size: head.ContentLength,
+		eTag: aws.ToString(head.ETag),
+	}, nil
+}
+
+// LazyObject is an object opened with OpenLazy. It is safe for concurrent
+// use.
+type LazyObject struct {
+	fsys *S3FS
+	name string
+	size int64
+	eTag string
+
+	mu sync.Mutex
+	// segments are the fetched ranges of the object, sorted by offset. They
+	// neither overlap nor touch each other.
+	segments []lazySegment
+}
+
+type lazySegment struct {
+	offset int64
+	data   []byte
+}
+
+func (s lazySegment) end() int64 { return s.offset + int64(len(s.data)) }
+
+// Size returns the size of the object.
+func (o *LazyObject) Size() int64 { return o.size }
+
+// At returns length bytes of the object starting at off. If fewer bytes are
+// available it returns them along with io.EOF. The returned slice belongs
+// to the caller.
+func (o *LazyObject) At(off, length int64) ([]byte, error) {
+	if off < 0 || length < 0 {
+		return nil, errors.New("s3fs.LazyObject.At: negative offset or length")
+	}
+
+	var eof error
+	end := off + length
+	if end > o.size {
+		end, eof = o.size, io.EOF
+	}
+	if off >= end {
+		return []byte{}, eof
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s, err := o.fill(off, end)
+	if err != nil {
+		return nil, err
+	}
+
+	p := make([]byte, end-off)
+	copy(p, s.data[off-s.offset:])
+	return p, eof
+}
+
+// fill makes sure that [off, end) was fetched and returns the segment that
+// holds it. Only the gaps between the segments around the range are
+// fetched; those segments are then merged into one.
+func (o *LazyObject) fill(off, end int64) (lazySegment, error) {
+	i := sort.Search(len(o.segments), func(i int) bool {
+		return o.segments[i].end() >= off
+	})
+	j := i
+	for j < len(o.segments) && o.segments[j].offset <= end {
+		j++
+	}
+
+	if j == i+1 && o.segments[i].offset <= off && o.segments[i].end() >= end {
+		return o.segments[i], nil
+	}
+
+	start, stop := off, end
+	if i < j {
+		if o.segments[i].offset < start {
+			start = o.segments[i].offset
+		}
+		if e := o.segments[j-1].end(); e > stop {
+			stop = e
+		}
+	}
+
+	merged := lazySegment{
+		offset: start,
+		data:   make([]byte, stop-start),
+	}
+
+	pos := start
+	for _, s := range o.segments[i:j] {
+		if s.offset > pos {
+			if err := o.fetch(merged.data[pos-start:s.offset-start], pos); err != nil {
+				return lazySegment{}, err
+			}
+		}
+		copy(merged.data[s.offset-start:], s.data)
+		pos = s.end()
+	}
+	if pos < stop {
+		if err := o.fetch(merged.data[pos-start:], pos); err != nil {
+			return lazySegment{}, err
+		}
+	}
+
+	o.segments = append(o.segments[:i], append([]lazySegment{merged}, o.segments[j:]...)...)
+	return merged, nil
+}
+
+// fetch reads len(p) bytes at offset with a single ranged GetObject.
+func (o *LazyObject) fetch(p []byte, offset int64) error {
+	in := &s3.GetObjectInput{
+		Bucket: &o.fsys.bucket,
+		Key:    aws.String(o.name),
+		Range:  o.fsys.rangeHeader(offset, offset+int64(len(p))-1),
+	}
+	if o.eTag != "" {
+		in.IfMatch = aws.String(o.eTag)
+	}
+
+	out, err := o.fsys.getObject(context.TODO(), in)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("s3fs.LazyObject.At: %w", ErrFileChanged)
+		}
+		return err
+	}
+
+	if err := o.fsys.skipIgnoredRange(out, offset); err != nil {
+		return fmt.Errorf("s3fs.LazyObject.At: %w", err)
+	}
+	defer out.Body.Close()
+
+	if _, err := io.ReadFull(out.Body, p); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
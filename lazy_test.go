@@ -0,0 +1,80 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenLazy(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 20)
+
+	cl := newMemClient()
+	cl.put("data.bin", content)
+
+	fsys := s3fs.New(cl, "test")
+	o, err := fsys.OpenLazy("data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Size() != int64(len(content)) {
+		t.Errorf("want size %d; got %d", len(content), o.Size())
+	}
+	if n := cl.count("GetObject"); n != 0 {
+		t.Errorf("want no GetObject on open; got %d", n)
+	}
+
+	for _, read := range []struct{ off, n int64 }{
+		{0, 10},
+		{100, 10},
+		{5, 10},
+		{0, 120},
+		{2, 50},
+		{190, 20},
+	} {
+		p, err := o.At(read.off, read.n)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+
+		end := read.off + read.n
+		if end > int64(len(content)) {
+			end = int64(len(content))
+			if err != io.EOF {
+				t.Errorf("At(%d, %d): want io.EOF; got %v", read.off, read.n, err)
+			}
+		}
+		if want := content[read.off:end]; !bytes.Equal(p, want) {
+			t.Errorf("At(%d, %d): want %q; got %q", read.off, read.n, want, p)
+		}
+	}
+
+	var ranges []string
+	for _, in := range cl.getInputs() {
+		ranges = append(ranges, *in.Range)
+	}
+	// overlapping reads only fetch what was not fetched before.
+	want := []string{"bytes=0-9", "bytes=100-109", "bytes=10-14", "bytes=15-99", "bytes=110-119", "bytes=190-199"}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("want ranges %q; got %q", want, ranges)
+	}
+
+	t.Run("changed", func(t *testing.T) {
+		cl.put("data.bin", bytes.Repeat([]byte("x"), len(content)))
+		if _, err := o.At(150, 10); !errors.Is(err, s3fs.ErrFileChanged) {
+			t.Errorf("want %v; got %v", s3fs.ErrFileChanged, err)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, err := fsys.OpenLazy("missing.bin"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// ErrTooLarge is returned by ReadFileLimit when the object is larger than
+// the allowed size.
+var ErrTooLarge = errors.New("file too large")
+
+// ReadFileLimit reads the whole object name like fs.ReadFile, but fails
+// with ErrTooLarge instead of allocating more than maxBytes. The size
+// reported by S3 is checked before anything is read, and the download is
+// cut short if the object turns out to be larger anyway.
+func (f *S3FS) ReadFileLimit(name string, maxBytes int64) ([]byte, error) {
+	if maxBytes < 0 {
+		return nil, &fs.PathError{
+			Op:   "readfile",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	data, err := f.readObjectLimit(context.TODO(), name, maxBytes)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "readfile",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,46 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestReadFileLimit(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("0123456789"))
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("under limit", func(t *testing.T) {
+		for _, max := range []int64{10, 1 << 20} {
+			data, err := fsys.ReadFileLimit("file.txt", max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "0123456789" {
+				t.Errorf("want %q; got %q", "0123456789", data)
+			}
+		}
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		_, err := fsys.ReadFileLimit("file.txt", 9)
+		if !errors.Is(err, s3fs.ErrTooLarge) {
+			t.Errorf("want %v; got %v", s3fs.ErrTooLarge, err)
+		}
+
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Op != "readfile" {
+			t.Errorf("want a readfile *fs.PathError; got %#v", err)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, err := fsys.ReadFileLimit("missing.txt", 10); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
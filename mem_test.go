@@ -0,0 +1,749 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/iotest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// memObject is a single object stored in memClient.
+type memObject struct {
+	data         []byte
+	etag         string
+	lastModified time.Time
+	checksums    map[types.ChecksumAlgorithm]string
+	contentType  string
+	metadata     map[string]string
+
+	cacheControl       string
+	contentDisposition string
+	contentEncoding    string
+	expires            time.Time
+	websiteRedirect    string
+	replicationStatus  types.ReplicationStatus
+	tags               map[string]string
+	versionID          string
+}
+
+// setHeaders sets the HTTP headers of o on a HeadObject or GetObject
+// output. Unset headers are left nil like the SDK does.
+func (o *memObject) setHeaders(cacheControl, contentDisposition, contentEncoding **string, expires **time.Time) {
+	for _, h := range []struct {
+		v   string
+		out **string
+	}{
+		{o.cacheControl, cacheControl},
+		{o.contentDisposition, contentDisposition},
+		{o.contentEncoding, contentEncoding},
+	} {
+		if h.v != "" {
+			*h.out = aws.String(h.v)
+		}
+	}
+	if !o.expires.IsZero() {
+		*expires = aws.Time(o.expires)
+	}
+}
+
+// memClient is an in-memory implementation of s3fs.S3Client that mimics
+// the error shapes of the real SDK closely enough to exercise s3fs' error
+// handling without a running S3 server.
+type memClient struct {
+	mu      sync.Mutex
+	objects map[string]*memObject
+	calls   map[string]int
+	inputs  []interface{}
+	now     time.Time
+	owner   types.Owner
+
+	// ignoreRange makes GetObject answer ranged requests with the whole
+	// object, like some S3 compatible stores do.
+	ignoreRange bool
+
+	// brokenBodies is the number of following GetObjects whose body fails
+	// with a connection reset halfway through.
+	brokenBodies int
+
+	// truncatedBodies is the number of following GetObjects whose body
+	// ends early, without error, halfway through.
+	truncatedBodies int
+
+	// bandwidth, if set, limits how many bytes per second a GetObject body
+	// is read at, to simulate transfer time.
+	bandwidth int64
+
+	// hook, if set, is called before every operation. A non-nil error is
+	// returned to the caller instead of executing the operation.
+	hook func(op string, in interface{}) error
+
+	uploads map[string]map[int32][]byte
+
+	// undeletable holds the keys DeleteObjects fails to delete with
+	// AccessDenied, reported in the output like S3 does.
+	undeletable map[string]bool
+
+	// versions holds the version history of keys, newest first, for
+	// ListObjectVersions and CopyObject.
+	versions map[string][]memVersion
+}
+
+// memVersion is a version of a key in a versioned bucket.
+type memVersion struct {
+	id           string
+	data         []byte
+	deleteMarker bool
+}
+
+func newMemClient() *memClient {
+	return &memClient{
+		objects:  make(map[string]*memObject),
+		calls:    make(map[string]int),
+		now:      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		owner:    types.Owner{ID: aws.String("owner-id"), DisplayName: aws.String("owner")},
+		uploads:  make(map[string]map[int32][]byte),
+		versions: make(map[string][]memVersion),
+	}
+}
+
+// put stores data under key, replacing a previous object.
+func (c *memClient) put(key string, data []byte) *memObject {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.putLocked(key, data)
+}
+
+func (c *memClient) putLocked(key string, data []byte) *memObject {
+	sum := md5.Sum(data)
+	o := &memObject{
+		data:         append([]byte(nil), data...),
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: c.now,
+	}
+	c.objects[key] = o
+	return o
+}
+
+// versionLocked returns the version id of key as an object. Delete markers
+// are not objects.
+func (c *memClient) versionLocked(key, id string) (*memObject, bool) {
+	for _, v := range c.versions[key] {
+		if v.id != id || v.deleteMarker {
+			continue
+		}
+
+		sum := md5.Sum(v.data)
+		return &memObject{
+			data:         v.data,
+			etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+			lastModified: c.now,
+			versionID:    v.id,
+		}, true
+	}
+	return nil, false
+}
+
+func (c *memClient) get(key string) (*memObject, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.objects[key]
+	return o, ok
+}
+
+// count returns how many times op was called.
+func (c *memClient) count(op string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[op]
+}
+
+// getInputs returns all recorded GetObject inputs.
+func (c *memClient) getInputs() []*s3.GetObjectInput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ins []*s3.GetObjectInput
+	for _, in := range c.inputs {
+		if in, ok := in.(*s3.GetObjectInput); ok {
+			ins = append(ins, in)
+		}
+	}
+	return ins
+}
+
+// record counts a call of op. Like the SDK it fails if ctx is done.
+func (c *memClient) record(ctx context.Context, op string, in interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.calls[op]++
+	c.inputs = append(c.inputs, in)
+	hook := c.hook
+	c.mu.Unlock()
+
+	if hook != nil {
+		return hook(op, in)
+	}
+	return nil
+}
+
+func (c *memClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := c.record(ctx, "ListObjectsV2", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := aws.ToString(in.Prefix)
+	delim := aws.ToString(in.Delimiter)
+	start := aws.ToString(in.ContinuationToken)
+	if start == "" {
+		start = aws.ToString(in.StartAfter)
+	}
+
+	maxKeys := int(in.MaxKeys)
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+
+	keys := make([]string, 0, len(c.objects))
+	for k := range c.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectsV2Output{
+		Name:      in.Bucket,
+		Prefix:    in.Prefix,
+		Delimiter: in.Delimiter,
+		MaxKeys:   int32(maxKeys),
+	}
+
+	// marker is the continuation token pointing right after the last
+	// emitted entry.
+	var lastEntry, marker string
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) || k <= start {
+			continue
+		}
+
+		entry := k
+		isPrefix := false
+		if delim != "" {
+			if i := strings.Index(k[len(prefix):], delim); i >= 0 {
+				entry = k[:len(prefix)+i+len(delim)]
+				isPrefix = true
+			}
+		}
+
+		if entry == lastEntry {
+			continue
+		}
+
+		if len(out.Contents)+len(out.CommonPrefixes) == maxKeys {
+			out.IsTruncated = true
+			out.NextContinuationToken = aws.String(marker)
+			break
+		}
+
+		lastEntry, marker = entry, entry
+		if isPrefix {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(entry)})
+			// the next page has to skip every key under the same prefix.
+			marker = entry + "\xff"
+			continue
+		}
+
+		o := c.objects[k]
+		obj := types.Object{
+			Key:          aws.String(k),
+			Size:         int64(len(o.data)),
+			ETag:         aws.String(o.etag),
+			LastModified: aws.Time(o.lastModified),
+		}
+		if in.FetchOwner {
+			owner := c.owner
+			obj.Owner = &owner
+		}
+		out.Contents = append(out.Contents, obj)
+	}
+
+	out.KeyCount = int32(len(out.Contents) + len(out.CommonPrefixes))
+	return out, nil
+}
+
+func (c *memClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := c.record(ctx, "HeadObject", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[aws.ToString(in.Key)]
+	if in.VersionId != nil {
+		o, ok = c.versionLocked(aws.ToString(in.Key), *in.VersionId)
+	}
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NotFound{})
+	}
+
+	if in.IfMatch != nil && *in.IfMatch != o.etag {
+		return nil, apiError(http.StatusPreconditionFailed, "PreconditionFailed")
+	}
+	// HTTP dates have a resolution of seconds.
+	if in.IfModifiedSince != nil && !o.lastModified.Truncate(time.Second).After(*in.IfModifiedSince) {
+		return nil, responseError(http.StatusNotModified, nil)
+	}
+
+	out := &s3.HeadObjectOutput{
+		ContentLength: int64(len(o.data)),
+		ETag:          aws.String(o.etag),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      o.metadata,
+	}
+	if o.versionID != "" {
+		out.VersionId = aws.String(o.versionID)
+	}
+	if o.contentType != "" {
+		out.ContentType = aws.String(o.contentType)
+	}
+	o.setHeaders(&out.CacheControl, &out.ContentDisposition, &out.ContentEncoding, &out.Expires)
+	if o.websiteRedirect != "" {
+		out.WebsiteRedirectLocation = aws.String(o.websiteRedirect)
+	}
+	out.ReplicationStatus = o.replicationStatus
+
+	if in.ChecksumMode == types.ChecksumModeEnabled {
+		for algo, sum := range o.checksums {
+			sum := sum
+			switch algo {
+			case types.ChecksumAlgorithmCrc32:
+				out.ChecksumCRC32 = &sum
+			case types.ChecksumAlgorithmCrc32c:
+				out.ChecksumCRC32C = &sum
+			case types.ChecksumAlgorithmSha1:
+				out.ChecksumSHA1 = &sum
+			case types.ChecksumAlgorithmSha256:
+				out.ChecksumSHA256 = &sum
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c *memClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := c.record(ctx, "GetObject", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[aws.ToString(in.Key)]
+	if in.VersionId != nil {
+		if o, ok = c.versionLocked(aws.ToString(in.Key), *in.VersionId); !ok {
+			return nil, apiError(http.StatusNotFound, "NoSuchVersion")
+		}
+	}
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+	}
+
+	if in.IfMatch != nil && *in.IfMatch != o.etag {
+		return nil, apiError(http.StatusPreconditionFailed, "PreconditionFailed")
+	}
+	if in.IfNoneMatch != nil && *in.IfNoneMatch == o.etag {
+		return nil, apiError(http.StatusNotModified, "NotModified")
+	}
+
+	size := int64(len(o.data))
+	out := &s3.GetObjectOutput{
+		ETag:         aws.String(o.etag),
+		LastModified: aws.Time(o.lastModified),
+		Metadata:     o.metadata,
+	}
+	if o.versionID != "" {
+		out.VersionId = aws.String(o.versionID)
+	}
+	o.setHeaders(&out.CacheControl, &out.ContentDisposition, &out.ContentEncoding, &out.Expires)
+
+	data := o.data
+	if in.Range != nil && !c.ignoreRange {
+		start, end, err := parseRange(*in.Range, size)
+		if err != nil {
+			return nil, err
+		}
+		data = data[start : end+1]
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+
+	out.ContentLength = int64(len(data))
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	if c.bandwidth > 0 {
+		out.Body = io.NopCloser(&throttledReader{r: out.Body, bandwidth: c.bandwidth})
+	}
+	if c.brokenBodies > 0 {
+		c.brokenBodies--
+		out.Body = io.NopCloser(io.MultiReader(
+			bytes.NewReader(data[:len(data)/2]),
+			iotest.ErrReader(errors.New("connection reset by peer")),
+		))
+	}
+	if c.truncatedBodies > 0 {
+		c.truncatedBodies--
+		out.Body = io.NopCloser(bytes.NewReader(data[:len(data)/2]))
+	}
+	return out, nil
+}
+
+// throttledReader reads from r at no more than bandwidth bytes per second.
+type throttledReader struct {
+	r         io.Reader
+	bandwidth int64
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	time.Sleep(time.Duration(int64(n) * int64(time.Second) / r.bandwidth))
+	return n, err
+}
+
+func (c *memClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := c.record(ctx, "HeadBucket", in); err != nil {
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (c *memClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := c.record(ctx, "PutObject", in); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if in.Body != nil {
+		var err error
+		if data, err = io.ReadAll(in.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := c.putLocked(aws.ToString(in.Key), data)
+	o.contentType = aws.ToString(in.ContentType)
+	o.metadata = in.Metadata
+	o.contentEncoding = aws.ToString(in.ContentEncoding)
+	return &s3.PutObjectOutput{ETag: aws.String(o.etag)}, nil
+}
+
+func (c *memClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := c.record(ctx, "DeleteObjects", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := &s3.DeleteObjectsOutput{}
+	for _, id := range in.Delete.Objects {
+		if c.undeletable[aws.ToString(id.Key)] {
+			out.Errors = append(out.Errors, types.Error{
+				Key:     id.Key,
+				Code:    aws.String("AccessDenied"),
+				Message: aws.String("Access Denied"),
+			})
+			continue
+		}
+		delete(c.objects, aws.ToString(id.Key))
+		out.Deleted = append(out.Deleted, types.DeletedObject{Key: id.Key})
+	}
+	return out, nil
+}
+
+func (c *memClient) ListObjectVersions(ctx context.Context, in *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	if err := c.record(ctx, "ListObjectVersions", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for key := range c.versions {
+		if strings.HasPrefix(key, aws.ToString(in.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectVersionsOutput{}
+	for _, key := range keys {
+		for i, v := range c.versions[key] {
+			if v.deleteMarker {
+				out.DeleteMarkers = append(out.DeleteMarkers, types.DeleteMarkerEntry{
+					Key:       aws.String(key),
+					VersionId: aws.String(v.id),
+					IsLatest:  i == 0,
+				})
+				continue
+			}
+			out.Versions = append(out.Versions, types.ObjectVersion{
+				Key:       aws.String(key),
+				VersionId: aws.String(v.id),
+				IsLatest:  i == 0,
+				Size:      int64(len(v.data)),
+			})
+		}
+	}
+	return out, nil
+}
+
+func (c *memClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := c.record(ctx, "CopyObject", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	src, query, _ := strings.Cut(aws.ToString(in.CopySource), "?")
+	_, key, _ := strings.Cut(src, "/")
+	if query == "" {
+		o, ok := c.objects[key]
+		if !ok {
+			return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+		}
+		cp := c.putLocked(aws.ToString(in.Key), o.data)
+		cp.tags = o.tags
+		return &s3.CopyObjectOutput{CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(cp.etag)}}, nil
+	}
+
+	id := strings.TrimPrefix(query, "versionId=")
+	for _, v := range c.versions[key] {
+		if v.id != id || v.deleteMarker {
+			continue
+		}
+
+		dst := aws.ToString(in.Key)
+		o := c.putLocked(dst, v.data)
+		c.versions[dst] = append([]memVersion{{id: "copy-" + id, data: v.data}}, c.versions[dst]...)
+		return &s3.CopyObjectOutput{CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(o.etag)}}, nil
+	}
+	return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+}
+
+func (c *memClient) GetObjectTagging(ctx context.Context, in *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	if err := c.record(ctx, "GetObjectTagging", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+	}
+
+	out := &s3.GetObjectTaggingOutput{TagSet: []types.Tag{}}
+	for k, v := range o.tags {
+		out.TagSet = append(out.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return out, nil
+}
+
+func (c *memClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if err := c.record(ctx, "CreateMultipartUpload", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := strconv.Itoa(len(c.uploads) + 1)
+	c.uploads[id] = make(map[int32][]byte)
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   in.Bucket,
+		Key:      in.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+func (c *memClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if err := c.record(ctx, "UploadPart", in); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parts, ok := c.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+	parts[in.PartNumber] = data
+	return &s3.UploadPartOutput{ETag: aws.String(strconv.Itoa(int(in.PartNumber)))}, nil
+}
+
+func (c *memClient) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	if err := c.record(ctx, "UploadPartCopy", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, key, _ := strings.Cut(aws.ToString(in.CopySource), "/")
+	o, ok := c.objects[key]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+	}
+
+	data := o.data
+	if in.CopySourceRange != nil {
+		start, end, err := parseRange(*in.CopySourceRange, int64(len(o.data)))
+		if err != nil {
+			return nil, err
+		}
+		data = o.data[start : end+1]
+	}
+
+	parts, ok := c.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+	parts[in.PartNumber] = append([]byte(nil), data...)
+	return &s3.UploadPartCopyOutput{
+		CopyPartResult: &types.CopyPartResult{ETag: aws.String(strconv.Itoa(int(in.PartNumber)))},
+	}, nil
+}
+
+func (c *memClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	if err := c.record(ctx, "CompleteMultipartUpload", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parts, ok := c.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+	delete(c.uploads, aws.ToString(in.UploadId))
+
+	nums := make([]int, 0, len(parts))
+	for n := range parts {
+		nums = append(nums, int(n))
+	}
+	sort.Ints(nums)
+
+	var data []byte
+	for _, n := range nums {
+		data = append(data, parts[int32(n)]...)
+	}
+
+	o := c.putLocked(aws.ToString(in.Key), data)
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket: in.Bucket,
+		Key:    in.Key,
+		ETag:   aws.String(o.etag),
+	}, nil
+}
+
+func (c *memClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if err := c.record(ctx, "AbortMultipartUpload", in); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.uploads, aws.ToString(in.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// parseRange parses a single HTTP byte range against an object of the
+// given size and returns the inclusive start and end offsets.
+func parseRange(r string, size int64) (start, end int64, err error) {
+	spec := strings.TrimPrefix(r, "bytes=")
+	i := strings.IndexByte(spec, '-')
+	if i < 0 {
+		return 0, 0, apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+	}
+
+	first, last := spec[:i], spec[i+1:]
+	switch {
+	case first == "":
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, 0, apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		}
+		start, end = size-n, size-1
+		if start < 0 {
+			start = 0
+		}
+	default:
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return 0, 0, apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		}
+		end = size - 1
+		if last != "" {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+				return 0, 0, apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			}
+			if end > size-1 {
+				end = size - 1
+			}
+		}
+	}
+
+	if start >= size || start > end {
+		return 0, 0, apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+	}
+	return start, end, nil
+}
+
+// responseError wraps err the same way the SDK does for failed responses.
+func responseError(status int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      err,
+		},
+	}
+}
+
+// apiError returns a generic API error with code wrapped in a response
+// error with the given HTTP status.
+func apiError(status int, code string) error {
+	return responseError(status, &smithy.GenericAPIError{Code: code, Message: code})
+}
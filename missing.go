@@ -0,0 +1,29 @@
+package s3fs
+
+import (
+	"bytes"
+	"io/fs"
+)
+
+// WithMissingAsEmpty makes Open and ReadFile treat missing objects as empty
+// files instead of returning fs.ErrNotExist, which suits optional objects
+// such as configuration overrides. Stat, ReadDir and the other methods
+// still report missing objects as not existing, so callers that need to
+// tell both apart can Stat first.
+func WithMissingAsEmpty(fsys *S3FS) { fsys.missingAsEmpty = true }
+
+var _ fs.File = (*missingFile)(nil)
+
+// missingFile is the empty file opened in place of a missing object.
+type missingFile struct {
+	*bytes.Reader
+	name string
+}
+
+func newMissingFile(name string) *missingFile {
+	return &missingFile{Reader: bytes.NewReader(nil), name: name}
+}
+
+func (f *missingFile) Stat() (fs.FileInfo, error) { return fileInfo{name: f.name}, nil }
+
+func (f *missingFile) Close() error { return nil }
@@ -0,0 +1,65 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestMissingAsEmpty(t *testing.T) {
+	cl := newMemClient()
+	cl.put("config/base.json", []byte("{}"))
+	fsys := s3fs.New(cl, "test", s3fs.WithMissingAsEmpty)
+
+	f, err := fsys.Open("config/override.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("want an empty file; got %q", data)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "override.json" || fi.Size() != 0 || !fi.Mode().IsRegular() {
+		t.Errorf("want an empty regular file named override.json; got %s of size %d and mode %v", fi.Name(), fi.Size(), fi.Mode())
+	}
+
+	if data, err := fsys.ReadFile("config/override.json"); err != nil || len(data) != 0 {
+		t.Errorf("want no data and no error; got %q and %v", data, err)
+	}
+
+	// directories and existing objects are opened as usual.
+	if data, err := fs.ReadFile(fsys, "config/base.json"); err != nil || string(data) != "{}" {
+		t.Errorf("want {}; got %q and %v", data, err)
+	}
+	d, err := fsys.Open("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := d.Stat(); !fi.IsDir() {
+		t.Error("expected config to be a directory")
+	}
+	d.Close()
+
+	if _, err := fsys.Stat("config/override.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat: want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		if _, err := s3fs.New(cl, "test").Open("config/override.json"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
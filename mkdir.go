@@ -0,0 +1,143 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// MkdirAll creates the directory name along with any necessary parents by
+// writing zero-byte marker objects ("name/").
+//
+// Directories that already exist, either through a marker or through the
+// objects they contain, are left untouched, so running MkdirAll on an
+// existing tree does not issue any writes. Existence is checked with Stat,
+// through the stat cache if WithStatCache is set: MkdirAll caches the
+// directories it creates, so running it again makes no requests at all.
+func (f *S3FS) MkdirAll(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{
+			Op:   "mkdir",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if name == "." {
+		return nil
+	}
+
+	// Walk up from the deepest directory until an existing one is found;
+	// all of its parents exist as well.
+	var missing []string
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		fi, err := f.stat(context.TODO(), dir)
+		if err == nil {
+			if !fi.IsDir() {
+				return &fs.PathError{
+					Op:   "mkdir",
+					Path: dir,
+					Err:  errNotDir,
+				}
+			}
+			break
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return &fs.PathError{
+				Op:   "mkdir",
+				Path: dir,
+				Err:  err,
+			}
+		}
+		missing = append(missing, dir)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := f.putMarker(context.TODO(), missing[i]); err != nil {
+			return &fs.PathError{
+				Op:   "mkdir",
+				Path: missing[i],
+				Err:  err,
+			}
+		}
+		if f.statCache != nil {
+			f.statCache.putDir(missing[i])
+		}
+	}
+	return nil
+}
+
+// Mkdir creates the directory name by writing its marker object ("name/").
+// Unlike MkdirAll, it returns fs.ErrExist if name exists and
+// fs.ErrNotExist if its parent directory does not.
+func (f *S3FS) Mkdir(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{
+			Op:   "mkdir",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	_, err := f.stat(context.TODO(), name)
+	if err == nil {
+		return &fs.PathError{
+			Op:   "mkdir",
+			Path: name,
+			Err:  fs.ErrExist,
+		}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return &fs.PathError{
+			Op:   "mkdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if parent := path.Dir(name); parent != "." {
+		fi, err := f.stat(context.TODO(), parent)
+		if err == nil && !fi.IsDir() {
+			err = errNotDir
+		}
+		if err != nil {
+			return &fs.PathError{
+				Op:   "mkdir",
+				Path: name,
+				Err:  err,
+			}
+		}
+	}
+
+	if err := f.putMarker(context.TODO(), name); err != nil {
+		return &fs.PathError{
+			Op:   "mkdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// putMarker writes the directory marker of name.
+func (f *S3FS) putMarker(ctx context.Context, name string) error {
+	_, err := f.cl.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               &f.bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(name + "/"),
+		Body:                 strings.NewReader(""),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, f.optFns...)
+	if f.statCache != nil {
+		f.statCache.invalidate(name)
+	}
+	return err
+}
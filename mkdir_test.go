@@ -0,0 +1,180 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestMkdirAll(t *testing.T) {
+	t.Run("creates markers once", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.MkdirAll("a/b/c"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, key := range []string{"a/", "a/b/", "a/b/c/"} {
+			if _, ok := cl.get(key); !ok {
+				t.Errorf("expected marker %s to exist", key)
+			}
+		}
+
+		if n := cl.count("PutObject"); n != 3 {
+			t.Errorf("want 3 PutObjects; got %d", n)
+		}
+
+		if err := fsys.MkdirAll("a/b/c"); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := cl.count("PutObject"); n != 3 {
+			t.Errorf("expected re-run to not issue PutObjects; got %d", n-3)
+		}
+
+		fi, err := fsys.Stat("a/b/c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.IsDir() {
+			t.Error("expected a/b/c to be a directory")
+		}
+	})
+
+	t.Run("implicit directories", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("x/y/file.txt", []byte("content"))
+
+		if err := s3fs.New(cl, "test").MkdirAll("x/y/z"); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := cl.count("PutObject"); n != 1 {
+			t.Errorf("want 1 PutObject; got %d", n)
+		}
+		if _, ok := cl.get("x/y/z/"); !ok {
+			t.Error("expected marker x/y/z/ to exist")
+		}
+	})
+
+	t.Run("file in path", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("x/file.txt", []byte("content"))
+
+		err := s3fs.New(cl, "test").MkdirAll("x/file.txt/y")
+
+		var perr *fs.PathError
+		if !errors.As(err, &perr) {
+			t.Fatalf("expected *fs.PathError; got %v", err)
+		}
+		if perr.Op != "mkdir" || perr.Path != "x/file.txt" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if n := cl.count("PutObject"); n != 0 {
+			t.Errorf("want 0 PutObjects; got %d", n)
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		err := s3fs.New(newMemClient(), "test").MkdirAll("/a")
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+
+	t.Run("stat cache", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("x/y/file.txt", []byte("content"))
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Minute))
+
+		for _, name := range []string{"a/b/c", "x/y"} {
+			if err := fsys.MkdirAll(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ops := []string{"HeadObject", "ListObjectsV2", "PutObject"}
+		counts := make(map[string]int)
+		for _, op := range ops {
+			counts[op] = cl.count(op)
+		}
+
+		for _, name := range []string{"a/b/c", "x/y"} {
+			if err := fsys.MkdirAll(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, op := range ops {
+			if n := cl.count(op) - counts[op]; n != 0 {
+				t.Errorf("expected re-run to not issue %ss; got %d", op, n)
+			}
+		}
+
+		if err := fsys.RemoveAll("a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.MkdirAll("a/b/c"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cl.get("a/b/c/"); !ok {
+			t.Error("expected marker a/b/c/ to be written again after RemoveAll")
+		}
+	})
+
+	t.Run("remove all", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.MkdirAll("a/b"); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsys.RemoveAll("a"); err != nil {
+			t.Fatal(err)
+		}
+		if len(cl.objects) != 0 {
+			t.Errorf("expected the markers to be removed; got %d objects", len(cl.objects))
+		}
+		if _, err := fsys.Stat("a"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestMkdir(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("content"))
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.Mkdir("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Mkdir("a/b"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a/", "a/b/"} {
+		if _, ok := cl.get(key); !ok {
+			t.Errorf("expected marker %s to exist", key)
+		}
+	}
+
+	for name, want := range map[string]error{
+		"a":           fs.ErrExist,
+		"file.txt":    fs.ErrExist,
+		".":           fs.ErrExist,
+		"missing/dir": fs.ErrNotExist,
+		"../up":       fs.ErrInvalid,
+	} {
+		if err := fsys.Mkdir(name); !errors.Is(err, want) {
+			t.Errorf("%s: want %v; got %v", name, want, err)
+		}
+	}
+
+	var perr *fs.PathError
+	if err := fsys.Mkdir("file.txt/dir"); !errors.As(err, &perr) || perr.Op != "mkdir" {
+		t.Errorf("want a mkdir error; got %v", err)
+	}
+}
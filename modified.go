@@ -0,0 +1,167 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ListModifiedSince returns the objects whose keys start with prefix and
+// that were modified at or after since, in key order. Unlike ReadDir the
+// listing is not split at "/", so objects in all the "subdirectories" are
+// returned; the Name of the returned FileInfo is the object's full key.
+// prefix may end with "/" to only match a directory; "." lists the whole
+// bucket.
+//
+// S3 cannot filter by modification time, so all the keys below prefix are
+// still listed, but only the matching objects are kept in memory.
+// Directory markers are skipped.
+func (f *S3FS) ListModifiedSince(prefix string, since time.Time) ([]fs.FileInfo, error) {
+	fis := []fs.FileInfo{}
+	err := f.listKeys(context.TODO(), "list", prefix, func(o types.Object) {
+		if isDirMarker(o) {
+			return
+		}
+		if modTime := derefTime(o.LastModified); !modTime.Before(since) {
+			fis = append(fis, keyInfo{
+				fileInfo: fileInfo{
+					name:    aws.ToString(o.Key),
+					size:    o.Size,
+					modTime: modTime,
+					eTag:    aws.ToString(o.ETag),
+				},
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fis, nil
+}
+
+// OpenLatest opens the most recently modified object whose key starts with
+// prefix and returns it with its key. Of objects modified at the same time
+// the one with the greatest key is opened. prefix is matched like in
+// ListModifiedSince.
+func (f *S3FS) OpenLatest(prefix string) (fs.File, string, error) {
+	var (
+		latest  string
+		modTime time.Time
+	)
+	err := f.listKeys(context.TODO(), "open", prefix, func(o types.Object) {
+		if isDirMarker(o) {
+			return
+		}
+		key, mt := aws.ToString(o.Key), derefTime(o.LastModified)
+		if latest == "" || mt.After(modTime) || mt.Equal(modTime) && key > latest {
+			latest, modTime = key, mt
+		}
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if latest == "" {
+		return nil, "", &fs.PathError{
+			Op:   "open",
+			Path: prefix,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	file, err := f.Open(latest)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, latest, nil
+}
+
+// listKeys calls fn for every object whose key starts with prefix, in key
+// order. Errors are reported as op.
+func (f *S3FS) listKeys(ctx context.Context, op, prefix string, fn func(types.Object)) error {
+	if !fs.ValidPath(strings.TrimSuffix(prefix, "/")) {
+		return &fs.PathError{
+			Op:   op,
+			Path: prefix,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(strings.TrimSuffix(prefix, "/")); err != nil {
+		return &fs.PathError{
+			Op:   op,
+			Path: prefix,
+			Err:  err,
+		}
+	}
+
+	keyPrefix := prefix
+	if prefix == "." {
+		keyPrefix = ""
+	}
+
+	var (
+		token *string
+		last  time.Time
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return &fs.PathError{
+				Op:   op,
+				Path: prefix,
+				Err:  err,
+			}
+		}
+
+		if err := f.paceList(ctx, last); err != nil {
+			return &fs.PathError{
+				Op:   op,
+				Path: prefix,
+				Err:  err,
+			}
+		}
+		last = time.Now()
+
+		out, err := f.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			RequestPayer:      f.requestPayer,
+			Prefix:            aws.String(keyPrefix),
+			ContinuationToken: token,
+			MaxKeys:           f.maxKeys,
+		}, f.optFns...)
+		if err != nil {
+			return &fs.PathError{
+				Op:   op,
+				Path: prefix,
+				Err:  err,
+			}
+		}
+		f.auditList(keyPrefix, out)
+
+		for _, o := range out.Contents {
+			fn(o)
+		}
+
+		if !out.IsTruncated {
+			return nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+// isDirMarker reports whether o is a directory marker.
+func isDirMarker(o types.Object) bool {
+	return strings.HasSuffix(aws.ToString(o.Key), "/")
+}
+
+// keyInfo is the FileInfo of an object that is named by its full key.
+type keyInfo struct {
+	fileInfo
+}
+
+func (fi keyInfo) Name() string { return fi.name }
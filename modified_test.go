@@ -0,0 +1,118 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestListModifiedSince(t *testing.T) {
+	since := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cl := newMemClient()
+	for key, modTime := range map[string]time.Time{
+		"logs/a.log":         since.Add(-time.Hour),
+		"logs/b.log":         since,
+		"logs/2021/c.log":    since.Add(time.Hour),
+		"logs/2021/d.log":    since.Add(-time.Minute),
+		"logs/2021/e/f.log":  since.Add(24 * time.Hour),
+		"logs/2021/":         since.Add(time.Hour),
+		"other/g.log":        since.Add(time.Hour),
+		"logs-archive/h.log": since.Add(time.Hour),
+	} {
+		cl.put(key, []byte(key)).lastModified = modTime
+	}
+
+	// force a page per two keys.
+	cl.hook = func(op string, in interface{}) error {
+		if in, ok := in.(*s3.ListObjectsV2Input); ok {
+			in.MaxKeys = 2
+		}
+		return nil
+	}
+
+	fis, err := s3fs.New(cl, "test").ListModifiedSince("logs/", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, fi := range fis {
+		got = append(got, fi.Name())
+		if fi.ModTime().Before(since) {
+			t.Errorf("%s: modified before %v: %v", fi.Name(), since, fi.ModTime())
+		}
+	}
+	want := []string{"logs/2021/c.log", "logs/2021/e/f.log", "logs/b.log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	if n := cl.count("ListObjectsV2"); n < 3 {
+		t.Errorf("want several pages; got %d", n)
+	}
+}
+
+func TestOpenLatest(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cl := newMemClient()
+	for key, modTime := range map[string]time.Time{
+		"logs/app-1.log":   now.Add(-2 * time.Hour),
+		"logs/app-2.log":   now,
+		"logs/app-3.log":   now.Add(-time.Hour),
+		"logs/old/app.log": now.Add(-24 * time.Hour),
+		"other/app.log":    now.Add(time.Hour),
+	} {
+		cl.put(key, []byte(key)).lastModified = modTime
+	}
+	cl.hook = func(op string, in interface{}) error {
+		if in, ok := in.(*s3.ListObjectsV2Input); ok {
+			in.MaxKeys = 2
+		}
+		return nil
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	f, key, err := fsys.OpenLatest("logs/app-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if key != "logs/app-2.log" {
+		t.Errorf("want key %q; got %q", "logs/app-2.log", key)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != key {
+		t.Errorf("want content %q; got %q", key, data)
+	}
+
+	t.Run("tie", func(t *testing.T) {
+		cl.put("logs/app-0.log", nil).lastModified = now
+		_, key, err := fsys.OpenLatest("logs/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "logs/app-2.log" {
+			t.Errorf("want key %q; got %q", "logs/app-2.log", key)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, _, err := fsys.OpenLatest("missing/"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	_ fs.StatFS    = (*MultiS3FS)(nil)
+	_ fs.ReadDirFS = (*MultiS3FS)(nil)
+)
+
+// MultiClient is a S3Client that can list the buckets of the account, as
+// *s3.Client does.
+type MultiClient interface {
+	S3Client
+	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+}
+
+var _ MultiClient = (*s3.Client)(nil)
+
+// MultiS3FS is a filesystem of all the buckets of an account: the first
+// element of every name is a bucket, e.g. "bucket-a/path/to/file" is the
+// key "path/to/file" of the bucket "bucket-a", and "." lists the buckets.
+type MultiS3FS struct {
+	cl   MultiClient
+	opts []Option
+
+	mu      sync.Mutex
+	buckets map[string]*S3FS
+}
+
+// NewMulti returns a filesystem of the buckets cl has access to. The
+// filesystem of each bucket is created on first use with New and opts.
+func NewMulti(cl MultiClient, opts ...Option) *MultiS3FS {
+	return &MultiS3FS{
+		cl:      cl,
+		opts:    opts,
+		buckets: make(map[string]*S3FS),
+	}
+}
+
+// Bucket returns the filesystem of bucket. It panics if bucket is not a
+// valid bucket name, as New does.
+func (m *MultiS3FS) Bucket(bucket string) *S3FS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fsys, ok := m.buckets[bucket]
+	if !ok {
+		fsys = New(m.cl, bucket, m.opts...)
+		m.buckets[bucket] = fsys
+	}
+	return fsys
+}
+
+// Open implements fs.FS. Buckets are opened as directories.
+func (m *MultiS3FS) Open(name string) (fs.File, error) {
+	if name == "." {
+		return m.root("open")
+	}
+
+	fsys, bucket, rest, err := m.split("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "." {
+		if err := m.checkBucket("open", fsys, bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := fsys.Open(rest)
+	if err != nil {
+		return nil, fixBucketErr(bucket, err)
+	}
+	if d, ok := f.(fs.ReadDirFile); ok && rest == "." {
+		return &bucketDir{ReadDirFile: d, name: bucket}, nil
+	}
+	return f, nil
+}
+
+// Stat implements fs.StatFS.
+func (m *MultiS3FS) Stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return &fileInfo{name: ".", mode: fs.ModeDir}, nil
+	}
+
+	fsys, bucket, rest, err := m.split("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "." {
+		if err := m.checkBucket("stat", fsys, bucket); err != nil {
+			return nil, err
+		}
+		return &fileInfo{name: bucket, mode: fs.ModeDir}, nil
+	}
+
+	fi, err := fsys.Stat(rest)
+	if err != nil {
+		return nil, fixBucketErr(bucket, err)
+	}
+	return fi, nil
+}
+
+// ReadDir implements fs.ReadDirFS. ReadDir(".") lists the buckets with
+// ListBuckets.
+func (m *MultiS3FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == "." {
+		d, err := m.root("readdir")
+		if err != nil {
+			return nil, err
+		}
+		return d.ReadDir(-1)
+	}
+
+	fsys, bucket, rest, err := m.split("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "." {
+		if err := m.checkBucket("readdir", fsys, bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	des, err := fsys.ReadDir(rest)
+	if err != nil {
+		return nil, fixBucketErr(bucket, err)
+	}
+	return des, nil
+}
+
+// root returns the directory of the buckets.
+func (m *MultiS3FS) root(op string) (fs.ReadDirFile, error) {
+	out, err := m.cl.ListBuckets(context.TODO(), &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   op,
+			Path: ".",
+			Err:  err,
+		}
+	}
+
+	des := make([]fs.DirEntry, 0, len(out.Buckets))
+	for _, b := range out.Buckets {
+		des = append(des, dirEntry{fileInfo: fileInfo{
+			name:    aws.ToString(b.Name),
+			mode:    fs.ModeDir,
+			modTime: derefTime(b.CreationDate),
+		}})
+	}
+	sort.Slice(des, func(i, j int) bool { return des[i].Name() < des[j].Name() })
+
+	return &snapshotDir{
+		fileInfo: fileInfo{name: ".", mode: fs.ModeDir},
+		entries:  des,
+	}, nil
+}
+
+// split returns the filesystem of the bucket of name and the name in it.
+func (m *MultiS3FS) split(op, name string) (*S3FS, string, string, error) {
+	if !fs.ValidPath(name) {
+		return nil, "", "", &fs.PathError{
+			Op:   op,
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	bucket, rest, ok := strings.Cut(name, "/")
+	if !ok {
+		rest = "."
+	}
+	if !validBucketName(bucket) {
+		return nil, "", "", &fs.PathError{
+			Op:   op,
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+	return m.Bucket(bucket), bucket, rest, nil
+}
+
+// checkBucket returns an error matching fs.ErrNotExist if bucket does not
+// exist, and the error of CheckAccess if it cannot be accessed.
+func (m *MultiS3FS) checkBucket(op string, fsys *S3FS, bucket string) error {
+	err := fsys.CheckAccess(context.TODO())
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrNoSuchBucket) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{
+		Op:   op,
+		Path: bucket,
+		Err:  err,
+	}
+}
+
+// fixBucketErr prefixes the path of a *fs.PathError with bucket.
+func fixBucketErr(bucket string, err error) error {
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		if perr.Path == "." {
+			perr.Path = bucket
+		} else {
+			perr.Path = bucket + "/" + perr.Path
+		}
+	}
+	return err
+}
+
+// bucketDir is the root directory of a bucket, named after it.
+type bucketDir struct {
+	fs.ReadDirFile
+	name string
+}
+
+func (d *bucketDir) Stat() (fs.FileInfo, error) {
+	return &fileInfo{name: d.name, mode: fs.ModeDir}, nil
+}
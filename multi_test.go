@@ -0,0 +1,218 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// bucketsClient routes the requests of every bucket to its own memClient.
+type bucketsClient map[string]*memClient
+
+func (c bucketsClient) bucket(name *string) (*memClient, error) {
+	cl, ok := c[aws.ToString(name)]
+	if !ok {
+		return nil, apiError(http.StatusNotFound, "NoSuchBucket")
+	}
+	return cl, nil
+}
+
+func (c bucketsClient) ListBuckets(ctx context.Context, in *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	out := &s3.ListBucketsOutput{}
+	for name := range c {
+		out.Buckets = append(out.Buckets, types.Bucket{Name: aws.String(name)})
+	}
+	return out, nil
+}
+
+func (c bucketsClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		// HeadBucket responses have no body, hence no error code.
+		return nil, responseError(http.StatusNotFound, nil)
+	}
+	return cl.HeadBucket(ctx, in, optFns...)
+}
+
+func (c bucketsClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.ListObjectsV2(ctx, in, optFns...)
+}
+
+func (c bucketsClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.HeadObject(ctx, in, optFns...)
+}
+
+func (c bucketsClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.GetObject(ctx, in, optFns...)
+}
+
+func (c bucketsClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.PutObject(ctx, in, optFns...)
+}
+
+func (c bucketsClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.DeleteObjects(ctx, in, optFns...)
+}
+
+func (c bucketsClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.CreateMultipartUpload(ctx, in, optFns...)
+}
+
+func (c bucketsClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.UploadPart(ctx, in, optFns...)
+}
+
+func (c bucketsClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.CompleteMultipartUpload(ctx, in, optFns...)
+}
+
+func (c bucketsClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	cl, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return cl.AbortMultipartUpload(ctx, in, optFns...)
+}
+
+// CopyObject copies between the buckets, which share no objects.
+func (c bucketsClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	srcBucket, escaped, _ := strings.Cut(aws.ToString(in.CopySource), "/")
+	src, err := c.bucket(&srcBucket)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := c.bucket(in.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		return nil, apiError(http.StatusBadRequest, "InvalidArgument")
+	}
+
+	out, err := src.GetObject(ctx, &s3.GetObjectInput{Bucket: &srcBucket, Key: &key})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dst.record(ctx, "CopyObject", in); err != nil {
+		return nil, err
+	}
+	o := dst.put(aws.ToString(in.Key), data)
+	return &s3.CopyObjectOutput{CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(o.etag)}}, nil
+}
+
+func TestMulti(t *testing.T) {
+	a, b := newMemClient(), newMemClient()
+	a.put("path/to/file.txt", []byte("a"))
+	b.put("file.txt", []byte("bb"))
+
+	fsys := s3fs.NewMulti(bucketsClient{"bucket-a": a, "bucket-b": b})
+
+	if err := fstest.TestFS(fsys, "bucket-a/path/to/file.txt", "bucket-b/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "bucket-b/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "bb" {
+		t.Errorf("want bb; got %q", data)
+	}
+
+	des, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(des) != 2 || des[0].Name() != "bucket-a" || des[1].Name() != "bucket-b" || !des[0].IsDir() {
+		t.Errorf("want the directories bucket-a and bucket-b; got %v", des)
+	}
+
+	fi, err := fsys.Stat("bucket-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "bucket-a" || !fi.IsDir() {
+		t.Errorf("want the directory bucket-a; got %s (dir %v)", fi.Name(), fi.IsDir())
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		for name, want := range map[string]error{
+			"bucket-c":             fs.ErrNotExist,
+			"bucket-c/file.txt":    s3fs.ErrNoSuchBucket,
+			"bucket-a/missing.txt": fs.ErrNotExist,
+		} {
+			_, err := fsys.Stat(name)
+			if !errors.Is(err, want) {
+				t.Errorf("%s: want %v; got %v", name, want, err)
+			}
+
+			var perr *fs.PathError
+			if !errors.As(err, &perr) || perr.Path != name {
+				t.Errorf("%s: want the path in the error; got %v", name, err)
+			}
+		}
+
+		if _, err := fsys.ReadDir("bucket-c"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, name := range []string{"", "/bucket-a", "bucket-a/../file.txt", "bucket-a//file.txt", "Bucket-A/file.txt", "ab/file.txt", "-bucket/file.txt"} {
+			if _, err := fsys.Open(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%q: want %v; got %v", name, fs.ErrInvalid, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package s3fs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// OpenNDJSON streams the newline delimited JSON object name and calls fn
+// for every non-empty line. The object is read incrementally, so it is never
+// held in memory as a whole.
+//
+// If fn returns an error, reading stops and the error is returned as is.
+// A line that is not valid JSON stops reading with an error naming the line.
+func (f *S3FS) OpenNDJSON(name string, fn func(json.RawMessage) error) error {
+	file, err := f.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, 64*1024)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &fs.PathError{
+				Op:   "read",
+				Path: name,
+				Err:  err,
+			}
+		}
+
+		if msg := bytes.TrimSpace(b); len(msg) > 0 {
+			if !json.Valid(msg) {
+				return &fs.PathError{
+					Op:   "read",
+					Path: name,
+					Err:  fmt.Errorf("line %d: invalid json", line),
+				}
+			}
+
+			if err := fn(json.RawMessage(msg)); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package s3fs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenNDJSON(t *testing.T) {
+	// the long line spans several read chunks.
+	long := strings.Repeat("x", 200*1024)
+	lines := []string{
+		`{"id":1}`,
+		`{"id":2,"data":"` + long + `"}`,
+		``,
+		`{"id":3}`,
+	}
+
+	cl := newMemClient()
+	cl.put("events.ndjson", []byte(strings.Join(lines, "\n")))
+	cl.put("invalid.ndjson", []byte("{\"id\":1}\n{\"id\":\n"))
+
+	fsys := s3fs.New(cl, "test")
+
+	type event struct {
+		ID   int    `json:"id"`
+		Data string `json:"data"`
+	}
+
+	t.Run("all lines", func(t *testing.T) {
+		var events []event
+		err := fsys.OpenNDJSON("events.ndjson", func(msg json.RawMessage) error {
+			var e event
+			if err := json.Unmarshal(msg, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) != 3 {
+			t.Fatalf("want 3 events; got %d", len(events))
+		}
+		for i, e := range events {
+			if e.ID != i+1 {
+				t.Errorf("want id %d; got %d", i+1, e.ID)
+			}
+		}
+		if events[1].Data != long {
+			t.Errorf("expected the long line to be read whole; got %d bytes", len(events[1].Data))
+		}
+	})
+
+	t.Run("early termination", func(t *testing.T) {
+		errStop := errors.New("stop")
+
+		var n int
+		err := fsys.OpenNDJSON("events.ndjson", func(msg json.RawMessage) error {
+			n++
+			return errStop
+		})
+		if err != errStop {
+			t.Errorf("want %v; got %v", errStop, err)
+		}
+		if n != 1 {
+			t.Errorf("want 1 call; got %d", n)
+		}
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		err := fsys.OpenNDJSON("invalid.ndjson", func(json.RawMessage) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("expected error for line 2; got %v", err)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrNoChecksum is returned by ObjectChecksum when the object has no stored
+// checksum of the requested algorithm.
+var ErrNoChecksum = errors.New("no stored checksum")
+
+// ChecksumAlgorithm is an algorithm S3 stores object checksums with.
+type ChecksumAlgorithm = types.ChecksumAlgorithm
+
+// Checksum algorithms supported by S3.
+const (
+	ChecksumCRC32  = types.ChecksumAlgorithmCrc32
+	ChecksumCRC32C = types.ChecksumAlgorithmCrc32c
+	ChecksumSHA1   = types.ChecksumAlgorithmSha1
+	ChecksumSHA256 = types.ChecksumAlgorithmSha256
+)
+
+// ObjectChecksum returns the base64 encoded checksum S3 stored for the
+// object name using algo, without downloading the object. If the object
+// was uploaded without a checksum of that algorithm ErrNoChecksum is
+// returned.
+func (f *S3FS) ObjectChecksum(name string, algo ChecksumAlgorithm) (string, error) {
+	head, err := f.headObject(context.TODO(), name, func(in *s3.HeadObjectInput) {
+		in.ChecksumMode = types.ChecksumModeEnabled
+	})
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "checksum",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	var sum *string
+	switch algo {
+	case ChecksumCRC32:
+		sum = head.ChecksumCRC32
+	case ChecksumCRC32C:
+		sum = head.ChecksumCRC32C
+	case ChecksumSHA1:
+		sum = head.ChecksumSHA1
+	case ChecksumSHA256:
+		sum = head.ChecksumSHA256
+	default:
+		return "", &fs.PathError{
+			Op:   "checksum",
+			Path: name,
+			Err:  fmt.Errorf("unknown checksum algorithm %q", algo),
+		}
+	}
+
+	if aws.ToString(sum) == "" {
+		return "", &fs.PathError{
+			Op:   "checksum",
+			Path: name,
+			Err:  ErrNoChecksum,
+		}
+	}
+	return *sum, nil
+}
+
+// WebsiteRedirect returns the redirect location S3 static website hosting
+// uses for the object name (x-amz-website-redirect-location), so that a
+// handler serving the filesystem can respond with a redirect too. ok is
+// false if the object has no redirect.
+func (f *S3FS) WebsiteRedirect(name string) (location string, ok bool, err error) {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return "", false, &fs.PathError{
+			Op:   "redirect",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	location = aws.ToString(head.WebsiteRedirectLocation)
+	return location, location != "", nil
+}
+
+// headObject issues a HeadObject for the object name. Not found errors are
+// mapped to fs.ErrNotExist, and missing buckets and permissions to
+// ErrNoSuchBucket and fs.ErrPermission.
+func (f *S3FS) headObject(ctx context.Context, name string, optFns ...func(*s3.HeadObjectInput)) (*s3.HeadObjectOutput, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, fs.ErrInvalid
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return nil, err
+	}
+
+	in := &s3.HeadObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name),
+	}
+	for _, fn := range optFns {
+		fn(in)
+	}
+
+	var head *s3.HeadObjectOutput
+	err := f.retryRead(ctx, func() (err error) {
+		head, err = f.cl.HeadObject(ctx, f.headInput(in), f.optFns...)
+		return err
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, permissionErr(f.bucketErr(err))
+	}
+	return head, nil
+}
+
+// ReplicationStatus returns the replication status of the object name
+// (x-amz-replication-status): "PENDING", "COMPLETE" or "FAILED" for
+// objects that are replicated, "REPLICA" for the replicas themselves. It
+// returns an empty string if the object is not subject to replication.
+func (f *S3FS) ReplicationStatus(name string) (string, error) {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "replication",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return string(head.ReplicationStatus), nil
+}
+
+// ContentType returns the Content-Type stored with the object name. If S3
+// has none set, the type is guessed from the extension of name with
+// mime.TypeByExtension, and is empty if the extension is unknown.
+func (f *S3FS) ContentType(name string) (string, error) {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "contenttype",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if ct := aws.ToString(head.ContentType); ct != "" {
+		return ct, nil
+	}
+	return mime.TypeByExtension(path.Ext(name)), nil
+}
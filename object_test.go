@@ -0,0 +1,201 @@
+package s3fs_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestObjectChecksum(t *testing.T) {
+	content := []byte("content")
+	sum := sha256.Sum256(content)
+	want := base64.StdEncoding.EncodeToString(sum[:])
+
+	cl := newMemClient()
+	cl.put("file.txt", content).checksums = map[types.ChecksumAlgorithm]string{
+		types.ChecksumAlgorithmSha256: want,
+	}
+	cl.put("nosum.txt", content)
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("sha256", func(t *testing.T) {
+		got, err := fsys.ObjectChecksum("file.txt", s3fs.ChecksumSHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("want %s; got %s", want, got)
+		}
+
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.HeadObjectInput); ok && in.ChecksumMode != types.ChecksumModeEnabled {
+				t.Error("expected ChecksumMode to be enabled")
+			}
+		}
+		if n := cl.count("GetObject"); n != 0 {
+			t.Errorf("want 0 GetObject calls; got %d", n)
+		}
+	})
+
+	t.Run("no checksum", func(t *testing.T) {
+		for _, name := range []string{"file.txt", "nosum.txt"} {
+			_, err := fsys.ObjectChecksum(name, s3fs.ChecksumCRC32)
+			if !errors.Is(err, s3fs.ErrNoChecksum) {
+				t.Errorf("%s: want %v; got %v", name, s3fs.ErrNoChecksum, err)
+			}
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.ObjectChecksum("missing.txt", s3fs.ChecksumSHA256)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
+
+func TestWebsiteRedirect(t *testing.T) {
+	cl := newMemClient()
+	cl.put("old.html", nil).websiteRedirect = "/new.html"
+	cl.put("new.html", []byte("<html></html>"))
+
+	fsys := s3fs.New(cl, "test")
+
+	location, ok, err := fsys.WebsiteRedirect("old.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || location != "/new.html" {
+		t.Errorf("want redirect to /new.html; got %q (%v)", location, ok)
+	}
+
+	location, ok, err = fsys.WebsiteRedirect("new.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || location != "" {
+		t.Errorf("want no redirect; got %q (%v)", location, ok)
+	}
+
+	if _, _, err := fsys.WebsiteRedirect("missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestReplicationStatus(t *testing.T) {
+	cl := newMemClient()
+	cl.put("local.txt", nil)
+
+	fsys := s3fs.New(cl, "test")
+
+	for _, status := range []types.ReplicationStatus{
+		types.ReplicationStatusPending,
+		types.ReplicationStatusComplete,
+		types.ReplicationStatusFailed,
+		types.ReplicationStatusReplica,
+	} {
+		name := strings.ToLower(string(status)) + ".txt"
+		cl.put(name, nil).replicationStatus = status
+
+		got, err := fsys.ReplicationStatus(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(status) {
+			t.Errorf("want %s; got %q", status, got)
+		}
+	}
+
+	if got, err := fsys.ReplicationStatus("local.txt"); err != nil || got != "" {
+		t.Errorf("want no status; got %q (%v)", got, err)
+	}
+
+	if _, err := fsys.ReplicationStatus("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	cl := newMemClient()
+	cl.put("data.bin", nil).contentType = "application/x-custom"
+	cl.put("page.html", nil)
+	cl.put("noext", nil)
+
+	fsys := s3fs.New(cl, "test")
+
+	for name, want := range map[string]string{
+		"data.bin":  "application/x-custom",
+		"page.html": mime.TypeByExtension(".html"),
+		"noext":     "",
+	} {
+		got, err := fsys.ContentType(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: want %q; got %q", name, want, got)
+		}
+	}
+
+	if _, err := fsys.ContentType("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestObjectErrors(t *testing.T) {
+	cl := newMemClient()
+	cl.put("data.bin", []byte("data"))
+	fsys := s3fs.New(cl, "test")
+
+	methods := map[string]func(name string) error{
+		"ObjectChecksum": func(name string) error {
+			_, err := fsys.ObjectChecksum(name, s3fs.ChecksumSHA256)
+			return err
+		},
+		"WebsiteRedirect": func(name string) error {
+			_, _, err := fsys.WebsiteRedirect(name)
+			return err
+		},
+		"ReplicationStatus": func(name string) error {
+			_, err := fsys.ReplicationStatus(name)
+			return err
+		},
+		"ContentType": func(name string) error {
+			_, err := fsys.ContentType(name)
+			return err
+		},
+	}
+
+	for _, tt := range []struct {
+		code string
+		err  error
+	}{
+		{"AccessDenied", fs.ErrPermission},
+		{"NoSuchBucket", s3fs.ErrNoSuchBucket},
+	} {
+		status := http.StatusForbidden
+		if tt.code == "NoSuchBucket" {
+			status = http.StatusNotFound
+		}
+		cl.hook = func(op string, in interface{}) error {
+			return apiError(status, tt.code)
+		}
+
+		for method, fn := range methods {
+			if err := fn("data.bin"); !errors.Is(err, tt.err) {
+				t.Errorf("%s %s: want %v; got %v", method, tt.code, tt.err, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Observer is notified of the S3 requests made by the filesystem, e.g. to
+// export metrics or log them.
+type Observer interface {
+	// ObserveRequest is called once a request completed, with the name of
+	// its S3 operation, such as "GetObject" or "ListObjectsV2", the name or
+	// prefix it was made for, its duration and its error. It may be called
+	// concurrently.
+	ObserveRequest(op string, key string, dur time.Duration, err error)
+}
+
+// WithObserver makes the filesystem report every S3 request it makes to o,
+// reads and writes alike. Names and prefixes are relative to WithPrefix;
+// the key is the destination of copies, and empty for DeleteObjects and
+// HeadBucket, which are not about a single name.
+//
+// Requests retried by WithRetry are reported once per attempt, and the
+// duration of a GetObject ends with its response, before the body is read.
+func WithObserver(o Observer) Option {
+	return func(fsys *S3FS) { fsys.observer = o }
+}
+
+// observedClient reports the requests made with S3Client to observer.
+//
+// It implements the optional clients S3FS detects too; calls fail if the
+// wrapped client does not implement them.
+type observedClient struct {
+	S3Client
+	observer Observer
+}
+
+var (
+	_ versionAPIClient        = (*observedClient)(nil)
+	_ copyAPIClient           = (*observedClient)(nil)
+	_ taggingAPIClient        = (*observedClient)(nil)
+	_ uploadPartCopyAPIClient = (*observedClient)(nil)
+)
+
+func newObservedClient(cl S3Client, o Observer) *observedClient {
+	return &observedClient{S3Client: cl, observer: o}
+}
+
+// observe reports the request op for key, made since start.
+func (c *observedClient) observe(op string, key *string, start time.Time, err error) {
+	c.observer.ObserveRequest(op, aws.ToString(key), time.Since(start), err)
+}
+
+func (c *observedClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	start := time.Now()
+	out, err := c.S3Client.ListObjectsV2(ctx, params, optFns...)
+	c.observe("ListObjectsV2", params.Prefix, start, err)
+	return out, err
+}
+
+func (c *observedClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.HeadObject(ctx, params, optFns...)
+	c.observe("HeadObject", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.GetObject(ctx, params, optFns...)
+	c.observe("GetObject", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.HeadBucket(ctx, params, optFns...)
+	c.observe("HeadBucket", nil, start, err)
+	return out, err
+}
+
+func (c *observedClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.PutObject(ctx, params, optFns...)
+	c.observe("PutObject", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.DeleteObjects(ctx, params, optFns...)
+	c.observe("DeleteObjects", nil, start, err)
+	return out, err
+}
+
+func (c *observedClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.CreateMultipartUpload(ctx, params, optFns...)
+	c.observe("CreateMultipartUpload", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.UploadPart(ctx, params, optFns...)
+	c.observe("UploadPart", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.CompleteMultipartUpload(ctx, params, optFns...)
+	c.observe("CompleteMultipartUpload", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	start := time.Now()
+	out, err := c.S3Client.AbortMultipartUpload(ctx, params, optFns...)
+	c.observe("AbortMultipartUpload", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	cl, ok := c.S3Client.(versionAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement ListObjectVersions", c.S3Client)
+	}
+
+	start := time.Now()
+	out, err := cl.ListObjectVersions(ctx, params, optFns...)
+	c.observe("ListObjectVersions", params.Prefix, start, err)
+	return out, err
+}
+
+func (c *observedClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	cl, ok := c.S3Client.(copyAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement CopyObject", c.S3Client)
+	}
+
+	start := time.Now()
+	out, err := cl.CopyObject(ctx, params, optFns...)
+	c.observe("CopyObject", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	cl, ok := c.S3Client.(uploadPartCopyAPIClient)
+	if !ok {
+		// CopyRange falls back to copying through the client.
+		return nil, errCopyUnsupported
+	}
+
+	start := time.Now()
+	out, err := cl.UploadPartCopy(ctx, params, optFns...)
+	c.observe("UploadPartCopy", params.Key, start, err)
+	return out, err
+}
+
+func (c *observedClient) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	cl, ok := c.S3Client.(taggingAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement GetObjectTagging", c.S3Client)
+	}
+
+	start := time.Now()
+	out, err := cl.GetObjectTagging(ctx, params, optFns...)
+	c.observe("GetObjectTagging", params.Key, start, err)
+	return out, err
+}
@@ -0,0 +1,145 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenRange opens the bytes [start, end) of the object name as a file of
+// their own, read with a single ranged GetObject, e.g. to read a column
+// chunk of a columnar file without sharing a file and its offset between
+// concurrent readers. The file cannot Seek and its Stat reports the size
+// of the range.
+//
+// An end of -1, or past the end of the object, reads up to the end of the
+// object. It returns fs.ErrInvalid if start is negative, end is before
+// start or start is past the end of the object.
+func (f *S3FS) OpenRange(name string, start, end int64) (fs.File, error) {
+	if !fs.ValidPath(name) || name == "." || start < 0 || (end >= 0 && end < start) || end < -1 {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	ctx := context.TODO()
+	var (
+		rf  *rangeFile
+		err error
+	)
+	if end == start {
+		rf, err = f.openEmptyRange(ctx, name, start)
+	} else {
+		rf, err = f.openRange(ctx, name, start, end)
+		// the first byte of an empty object is not satisfiable either.
+		if start == 0 && httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable {
+			rf, err = f.openEmptyRange(ctx, name, start)
+		}
+	}
+	if err != nil {
+		switch {
+		case httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable:
+			err = fs.ErrInvalid
+		case isNotFoundErr(err):
+			err = fs.ErrNotExist
+		case errors.Is(err, fs.ErrInvalid), errors.Is(err, fs.ErrNotExist):
+		default:
+			err = permissionErr(f.bucketErr(err))
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return rf, nil
+}
+
+// openRange gets the non-empty range [start, end) of name; end is -1 for
+// the end of the object.
+func (f *S3FS) openRange(ctx context.Context, name string, start, end int64) (*rangeFile, error) {
+	last := int64(-1)
+	if end >= 0 {
+		last = end - 1
+	}
+	out, err := f.getObject(ctx, &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name),
+		Range:  f.rangeHeader(start, last),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	size := out.ContentLength
+	if out.ContentRange == nil {
+		// the whole object was returned, see skipIgnoredRange.
+		size -= start
+	}
+	if err := f.skipIgnoredRange(out, start); err != nil {
+		return nil, err
+	}
+	if end >= 0 && size > end-start {
+		size = end - start
+	}
+
+	return &rangeFile{
+		Reader: io.LimitReader(out.Body, size),
+		body:   out.Body,
+		info: &fileInfo{
+			name:    path.Base(name),
+			size:    size,
+			modTime: derefTime(out.LastModified),
+			eTag:    aws.ToString(out.ETag),
+		},
+	}, nil
+}
+
+// openEmptyRange checks that name has at least start bytes, as an empty
+// range cannot be requested with GetObject.
+func (f *S3FS) openEmptyRange(ctx context.Context, name string, start int64) (*rangeFile, error) {
+	head, err := f.headObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if start > head.ContentLength {
+		return nil, fs.ErrInvalid
+	}
+
+	fi := headFileInfo(path.Base(name), head)
+	fi.size = 0
+	return &rangeFile{
+		Reader: eofReader{},
+		body:   io.NopCloser(nil),
+		info:   fi,
+	}, nil
+}
+
+var _ fs.File = (*rangeFile)(nil)
+
+// rangeFile is a file of a byte range of an object.
+type rangeFile struct {
+	io.Reader
+	body io.ReadCloser
+	info *fileInfo
+}
+
+func (f *rangeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *rangeFile) Close() error { return f.body.Close() }
@@ -0,0 +1,113 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenRange(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("0123456789"))
+
+	fixtures := []struct {
+		desc       string
+		start, end int64
+		want       string
+	}{
+		{desc: "middle", start: 2, end: 5, want: "234"},
+		{desc: "start", start: 0, end: 3, want: "012"},
+		{desc: "to end", start: 7, end: -1, want: "789"},
+		{desc: "past end", start: 7, end: 100, want: "789"},
+		{desc: "whole", start: 0, end: -1, want: "0123456789"},
+		{desc: "empty", start: 4, end: 4, want: ""},
+		{desc: "empty at end", start: 10, end: 10, want: ""},
+	}
+
+	for _, ignoreRange := range []bool{false, true} {
+		cl.ignoreRange = ignoreRange
+		opts := []s3fs.Option{}
+		if ignoreRange {
+			opts = append(opts, s3fs.WithRangeFallback)
+		}
+		fsys := s3fs.New(cl, "test", opts...)
+
+		for _, f := range fixtures {
+			desc := f.desc
+			if ignoreRange {
+				desc += " ignored range"
+			}
+			t.Run(desc, func(t *testing.T) {
+				before := cl.count("GetObject")
+				file, err := fsys.OpenRange("dir/file.txt", f.start, f.end)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+
+				data, err := io.ReadAll(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != f.want {
+					t.Errorf("want %q; got %q", f.want, data)
+				}
+
+				fi, err := file.Stat()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Name() != "file.txt" || fi.Size() != int64(len(f.want)) {
+					t.Errorf("want file.txt of %d bytes; got %s of %d", len(f.want), fi.Name(), fi.Size())
+				}
+
+				if n := cl.count("GetObject") - before; f.start != f.end && n != 1 {
+					t.Errorf("want a single GetObject; got %d", n)
+				}
+			})
+		}
+	}
+	cl.ignoreRange = false
+
+	t.Run("invalid", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test")
+		for _, r := range [][2]int64{{-1, 3}, {5, 2}, {0, -2}, {11, 20}, {11, 11}, {10, -1}} {
+			if _, err := fsys.OpenRange("dir/file.txt", r[0], r[1]); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%v: want %v; got %v", r, fs.ErrInvalid, err)
+			}
+		}
+	})
+
+	t.Run("empty object", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("empty.txt", nil)
+		fsys := s3fs.New(cl, "test")
+
+		for _, r := range [][2]int64{{0, -1}, {0, 10}, {0, 0}} {
+			file, err := fsys.OpenRange("empty.txt", r[0], r[1])
+			if err != nil {
+				t.Fatalf("%v: %v", r, err)
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil || len(data) != 0 {
+				t.Errorf("%v: want no data; got %q, %v", r, data, err)
+			}
+		}
+		if _, err := fsys.OpenRange("empty.txt", 1, -1); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test")
+		for _, r := range [][2]int64{{0, 3}, {0, 0}} {
+			if _, err := fsys.OpenRange("dir/missing.txt", r[0], r[1]); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("%v: want %v; got %v", r, fs.ErrNotExist, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,518 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestRequestOptions(t *testing.T) {
+	var req *http.Request
+	cl := s3.New(s3.Options{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+			req = r
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Length": []string{"4"}},
+				Body:       io.NopCloser(strings.NewReader("data")),
+			}, nil
+		}),
+	})
+
+	region := func(r string) func(*s3.Options) {
+		return func(o *s3.Options) { o.Region = r }
+	}
+
+	fsys := s3fs.New(cl, "bucket", s3fs.WithRequestOptions(region("us-west-2")))
+
+	fixtures := []struct {
+		desc   string
+		open   func() (io.Closer, error)
+		region string
+	}{
+		{
+			desc:   "fs-wide",
+			open:   func() (io.Closer, error) { return fsys.Open("file.txt") },
+			region: "us-west-2",
+		},
+		{
+			desc:   "per-call",
+			open:   func() (io.Closer, error) { return fsys.OpenWith("file.txt", region("eu-west-1")) },
+			region: "eu-west-1",
+		},
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.desc, func(t *testing.T) {
+			file, err := f.open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if !strings.Contains(req.URL.Host, f.region) {
+				t.Errorf("want host in region %s; got %q", f.region, req.URL.Host)
+			}
+		})
+	}
+}
+
+func TestStorageClass(t *testing.T) {
+	cl := newMemClient()
+
+	var classes []types.StorageClass
+	cl.hook = func(op string, in interface{}) error {
+		if in, ok := in.(*s3.PutObjectInput); ok {
+			classes = append(classes, in.StorageClass)
+		}
+		return nil
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithStorageClass(types.StorageClassStandardIa))
+	if err := fsys.MkdirAll("a/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(classes) != 2 {
+		t.Fatalf("want 2 PutObjects; got %d", len(classes))
+	}
+	for _, c := range classes {
+		if c != types.StorageClassStandardIa {
+			t.Errorf("want storage class %s; got %q", types.StorageClassStandardIa, c)
+		}
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected WithStorageClass to panic")
+			}
+		}()
+		s3fs.WithStorageClass("FAST_AND_CHEAP")
+	})
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	cl := newMemClient()
+
+	type sse struct {
+		alg   types.ServerSideEncryption
+		keyID string
+	}
+	got := make(map[string]sse)
+	cl.hook = func(op string, in interface{}) error {
+		switch in := in.(type) {
+		case *s3.PutObjectInput:
+			got[op+" "+aws.ToString(in.Key)] = sse{in.ServerSideEncryption, aws.ToString(in.SSEKMSKeyId)}
+		case *s3.CreateMultipartUploadInput:
+			got[op+" "+aws.ToString(in.Key)] = sse{in.ServerSideEncryption, aws.ToString(in.SSEKMSKeyId)}
+		case *s3.CopyObjectInput:
+			got[op+" "+aws.ToString(in.Key)] = sse{in.ServerSideEncryption, aws.ToString(in.SSEKMSKeyId)}
+			if in.StorageClass != types.StorageClassGlacierIr {
+				t.Errorf("%s %s: want storage class %s; got %q", op, aws.ToString(in.Key), types.StorageClassGlacierIr, in.StorageClass)
+			}
+		}
+		return nil
+	}
+
+	fsys := s3fs.New(cl, "test",
+		s3fs.WithServerSideEncryption(types.ServerSideEncryptionAwsKms, "key-id"),
+		s3fs.WithStorageClass(types.StorageClassGlacierIr),
+	)
+	if err := fsys.WriteFile("small.txt", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	// large enough for a multipart upload.
+	if err := fsys.WriteFile("large.bin", make([]byte, 6<<20)); err != nil {
+		t.Fatal(err)
+	}
+
+	// copies are written with the same settings.
+	if err := fsys.Rename("small.txt", "renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	trash := s3fs.New(cl, "test",
+		s3fs.WithServerSideEncryption(types.ServerSideEncryptionAwsKms, "key-id"),
+		s3fs.WithStorageClass(types.StorageClassGlacierIr),
+		s3fs.WithTrashPrefix(".trash"),
+	)
+	if err := trash.Remove("renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	cl.versions["restored.txt"] = []memVersion{
+		{id: "v2", deleteMarker: true},
+		{id: "v1", data: []byte("data")},
+	}
+	if err := fsys.RestoreVersion("restored.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sse{types.ServerSideEncryptionAwsKms, "key-id"}
+	for _, op := range []string{
+		"PutObject small.txt",
+		"CreateMultipartUpload large.bin",
+		"CopyObject renamed.txt",
+		"CopyObject .trash/renamed.txt",
+		"CopyObject restored.txt",
+	} {
+		if got[op] != want {
+			t.Errorf("%s: want %+v; got %+v", op, want, got[op])
+		}
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, fn := range []func(){
+			func() { s3fs.WithServerSideEncryption("rot13", "") },
+			func() { s3fs.WithServerSideEncryption(types.ServerSideEncryptionAes256, "key-id") },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("expected WithServerSideEncryption to panic")
+					}
+				}()
+				fn()
+			}()
+		}
+	})
+}
+
+func TestEndpointPerOperation(t *testing.T) {
+	hosts := make(map[string]string)
+	cl := s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		UsePathStyle: true,
+		HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Query().Get("list-type") == "2" {
+				hosts["ListObjectsV2"] = r.URL.Host
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/xml"}},
+					Body:       io.NopCloser(strings.NewReader("<ListBucketResult></ListBucketResult>")),
+				}, nil
+			}
+
+			hosts[r.Method] = r.URL.Host
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Length": []string{"4"}},
+				Body:       io.NopCloser(strings.NewReader("data")),
+			}, nil
+		}),
+	})
+
+	endpoints := map[string]string{
+		"GetObject":     "https://reads.example.com",
+		"ListObjectsV2": "http://listings.example.com:8080",
+	}
+	fsys := s3fs.New(cl, "bucket", s3fs.WithEndpointPerOperation(func(op string) string {
+		return endpoints[op]
+	}))
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := fsys.ReadDir("."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	for op, want := range map[string]string{
+		"GET":           "reads.example.com",
+		"ListObjectsV2": "listings.example.com:8080",
+		"HEAD":          "s3.us-east-1.amazonaws.com",
+	} {
+		if hosts[op] != want {
+			t.Errorf("%s: want host %q; got %q", op, want, hosts[op])
+		}
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		fsys := s3fs.New(cl, "bucket", s3fs.WithEndpointPerOperation(func(string) string {
+			return "reads.example.com"
+		}))
+		_, err := fsys.Open("file.txt")
+		if err == nil || !strings.Contains(err.Error(), "invalid endpoint") {
+			t.Errorf("want invalid endpoint error; got %v", err)
+		}
+	})
+}
+
+func TestExpectedBucketOwner(t *testing.T) {
+	owners := make(map[string]string)
+	cl := s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		UsePathStyle: true,
+		HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+			op := r.Method
+			if r.URL.Query().Get("list-type") == "2" {
+				op = "ListObjectsV2"
+			}
+			owner := r.Header.Get("X-Amz-Expected-Bucket-Owner")
+			owners[op] = owner
+
+			if owner != "111122223333" {
+				return &http.Response{
+					StatusCode: http.StatusForbidden,
+					Header:     http.Header{"Content-Type": []string{"application/xml"}},
+					Body:       io.NopCloser(strings.NewReader("<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")),
+				}, nil
+			}
+
+			if op == "ListObjectsV2" {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/xml"}},
+					Body:       io.NopCloser(strings.NewReader("<ListBucketResult><Contents><Key>file.txt</Key></Contents></ListBucketResult>")),
+				}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Length": []string{"4"}},
+				Body:       io.NopCloser(strings.NewReader("data")),
+			}, nil
+		}),
+	})
+
+	fsys := s3fs.New(cl, "bucket", s3fs.WithExpectedBucketOwner("111122223333"))
+	if _, err := fsys.ReadFile("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadDir("."); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range []string{"GET", "HEAD", "ListObjectsV2"} {
+		if owners[op] != "111122223333" {
+			t.Errorf("%s: want expected owner 111122223333; got %q", op, owners[op])
+		}
+	}
+
+	t.Run("mismatch", func(t *testing.T) {
+		fsys := s3fs.New(cl, "bucket", s3fs.WithExpectedBucketOwner("444455556666"))
+
+		_, err := fsys.Open("file.txt")
+		if !s3fs.IsPermission(err) {
+			t.Errorf("want permission error; got %v", err)
+		}
+		if err == nil || !strings.Contains(err.Error(), "not be owned by account 444455556666") {
+			t.Errorf("want error to name the expected owner; got %v", err)
+		}
+	})
+}
+
+func TestSSECustomerKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	sum := md5.Sum(key)
+
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("data"))
+	fsys := s3fs.New(cl, "bucket",
+		s3fs.WithSSECustomerKey("AES256", key),
+		s3fs.WithListPrefetchMetadata(1),
+	)
+
+	if _, err := fsys.ReadFile("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [3]string{"AES256", base64.StdEncoding.EncodeToString(key), base64.StdEncoding.EncodeToString(sum[:])}
+	var gets, heads int
+	for _, in := range cl.inputs {
+		var got [3]string
+		switch in := in.(type) {
+		case *s3.GetObjectInput:
+			gets++
+			got = [3]string{aws.ToString(in.SSECustomerAlgorithm), aws.ToString(in.SSECustomerKey), aws.ToString(in.SSECustomerKeyMD5)}
+		case *s3.HeadObjectInput:
+			heads++
+			got = [3]string{aws.ToString(in.SSECustomerAlgorithm), aws.ToString(in.SSECustomerKey), aws.ToString(in.SSECustomerKeyMD5)}
+		default:
+			continue
+		}
+		if got != want {
+			t.Errorf("%T: want SSE-C algorithm, key and MD5 %q; got %q", in, want, got)
+		}
+	}
+	// Stat and the prefetched metadata of ReadDir make two HeadObjects.
+	if gets == 0 || heads < 2 {
+		t.Errorf("want GetObjects and at least 2 HeadObjects; got %d and %d", gets, heads)
+	}
+
+	t.Run("invalid key", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		s3fs.WithSSECustomerKey("AES256", key[:16])
+	})
+}
+
+func TestRequestPayer(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("data"))
+	fsys := s3fs.New(cl, "bucket", s3fs.WithRequestPayer)
+
+	if _, err := fsys.ReadFile("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("dir/other.txt", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename("dir/other.txt", "dir/renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, in := range cl.inputs {
+		v := reflect.ValueOf(in).Elem()
+		op := strings.TrimSuffix(v.Type().Name(), "Input")
+		seen[op] = true
+		if payer := v.FieldByName("RequestPayer"); payer.IsValid() && payer.Interface() != types.RequestPayerRequester {
+			t.Errorf("%s: want request payer requester; got %q", op, payer.Interface())
+		}
+	}
+	for _, op := range []string{"GetObject", "HeadObject", "ListObjectsV2", "PutObject", "CopyObject", "DeleteObjects"} {
+		if !seen[op] {
+			t.Errorf("expected a %s; got %v", op, seen)
+		}
+	}
+}
+
+type observerFunc func(op, key string, dur time.Duration, err error)
+
+func (fn observerFunc) ObserveRequest(op, key string, dur time.Duration, err error) {
+	fn(op, key, dur, err)
+}
+
+func TestObserver(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir/file.txt", []byte("data"))
+
+	var got []string
+	var failed []string
+	fsys := s3fs.New(cl, "bucket", s3fs.WithObserver(observerFunc(func(op, key string, dur time.Duration, err error) {
+		got = append(got, op+" "+key)
+		if err != nil {
+			failed = append(failed, op+" "+key)
+		}
+	})))
+
+	if _, err := fsys.Stat("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadFile("dir/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.ReadDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Stat("missing.txt"); err == nil {
+		t.Fatal("expected an error")
+	}
+	f, err := fsys.OpenConcat("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// only reads fail.
+	failedReads := failed
+	failed = nil
+
+	if err := fsys.WriteFile("dir/other.txt", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename("dir/other.txt", "dir/renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"HeadObject dir/file.txt",
+		"GetObject dir/file.txt",
+		"ListObjectsV2 dir/",
+		"HeadObject missing.txt",
+		"ListObjectsV2 dir/file",
+		"PutObject dir/other.txt",
+		"CopyObject dir/renamed.txt",
+		"DeleteObjects ",
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == w
+		}
+		if !found {
+			t.Errorf("expected %q to be observed; got %q", w, got)
+		}
+	}
+	// HeadObject "dir" fails too: ReadDir checks that it is not a file.
+	if want := []string{"HeadObject dir", "HeadObject missing.txt"}; !reflect.DeepEqual(failedReads, want) {
+		t.Errorf("want failed requests %q; got %q", want, failedReads)
+	}
+	if len(failed) != 0 {
+		t.Errorf("want no failed writes; got %q", failed)
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data/file.txt", []byte("data"))
+
+		var got []string
+		fsys := s3fs.New(cl, "bucket", s3fs.WithPrefix("data"), s3fs.WithObserver(observerFunc(func(op, key string, dur time.Duration, err error) {
+			got = append(got, op+" "+key)
+		})))
+		if _, err := fsys.ReadFile("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"GetObject file.txt"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+	})
+}
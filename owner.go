@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithExpectedBucketOwner makes S3 reject the GetObject, HeadObject and
+// ListObjectsV2 requests of the filesystem if the bucket is not owned by
+// the AWS account accountID. This guards against reading from a bucket
+// that was deleted and recreated by another account under the same name.
+//
+// S3 responds to a mismatched owner with AccessDenied, so IsPermission
+// reports true for these errors. Only clients built with the s3 package
+// support this option.
+func WithExpectedBucketOwner(accountID string) Option {
+	return WithRequestOptions(func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(expectedBucketOwner(accountID), middleware.Before)
+		})
+	})
+}
+
+// expectedBucketOwner sets the ExpectedBucketOwner of the requests that
+// support it to accountID.
+func expectedBucketOwner(accountID string) middleware.InitializeMiddleware {
+	return middleware.InitializeMiddlewareFunc("s3fs.ExpectedBucketOwner", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		switch params := in.Parameters.(type) {
+		case *s3.GetObjectInput:
+			params.ExpectedBucketOwner = &accountID
+		case *s3.HeadObjectInput:
+			params.ExpectedBucketOwner = &accountID
+		case *s3.ListObjectsV2Input:
+			params.ExpectedBucketOwner = &accountID
+		default:
+			return next.HandleInitialize(ctx, in)
+		}
+
+		out, md, err := next.HandleInitialize(ctx, in)
+		if err != nil && IsPermission(err) {
+			err = fmt.Errorf("s3fs: access denied, the bucket may not be owned by account %s: %w", accountID, err)
+		}
+		return out, md, err
+	})
+}
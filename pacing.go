@@ -0,0 +1,37 @@
+package s3fs
+
+import (
+	"context"
+	"time"
+)
+
+// WithListPacing makes successive ListObjectsV2 calls of a paginated listing
+// at least minInterval apart. It rate-limits the enumeration of large
+// directories on shared buckets, where it could otherwise cause throttling.
+func WithListPacing(minInterval time.Duration) Option {
+	return func(fsys *S3FS) { fsys.listPacing = minInterval }
+}
+
+// paceList waits until the pacing interval since last, the start of the
+// previous page's request, has passed. It returns early with ctx's error if
+// ctx is done first.
+func (f *S3FS) paceList(ctx context.Context, last time.Time) error {
+	if f.listPacing <= 0 || last.IsZero() {
+		return nil
+	}
+
+	wait := time.Until(last.Add(f.listPacing))
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
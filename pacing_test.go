@@ -0,0 +1,51 @@
+package s3fs_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestListPacing(t *testing.T) {
+	const interval = 20 * time.Millisecond
+
+	cl := newMemClient()
+	// three pages of 1000 keys.
+	for i := 0; i < 2500; i++ {
+		cl.put(fmt.Sprintf("file%04d", i), nil)
+	}
+
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	cl.hook = func(op string, in interface{}) error {
+		if op == "ListObjectsV2" {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}
+		return nil
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithListPacing(interval))
+	des, err := fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(des) != 2500 {
+		t.Errorf("want 2500 entries; got %d", len(des))
+	}
+
+	if len(times) != 3 {
+		t.Fatalf("want 3 ListObjectsV2 calls; got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < interval {
+			t.Errorf("page %d: want at least %v between calls; got %v", i, interval, d)
+		}
+	}
+}
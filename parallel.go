@@ -0,0 +1,108 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// minParallelChunk is the smallest range a Read is split into with
+// WithParallelRangeReads; smaller reads are not worth the extra requests.
+const minParallelChunk = 1 << 20
+
+// WithParallelRangeReads splits a single Read of at least 2 MiB into up to
+// concurrency ranged GetObjects made in parallel and assembles them in
+// order, which cuts the latency of large reads. The following Read
+// continues from the end of the range with a new GetObject.
+//
+// If one of the ranges fails the Read returns the error without moving the
+// offset of the file. A concurrency below 2 disables splitting.
+func WithParallelRangeReads(concurrency int) Option {
+	return func(fsys *S3FS) { fsys.parallelReads = concurrency }
+}
+
+// readParallel reads p at the current offset with concurrent ranged
+// GetObjects.
+func (f *file) readParallel(p []byte) (int, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	n := len(p)
+	if remaining := stat.Size() - f.offset; remaining < int64(n) {
+		n = int(remaining)
+	}
+	if n < 2*minParallelChunk {
+		return f.readBody(p)
+	}
+
+	chunk := (n + f.fsys.parallelReads - 1) / f.fsys.parallelReads
+	if chunk < minParallelChunk {
+		chunk = minParallelChunk
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+		go func(p []byte, offset int64) {
+			defer wg.Done()
+
+			read, err := f.readRange(p, offset)
+			if err == nil || errors.Is(err, io.EOF) {
+				err = nil
+				if read < len(p) {
+					// the object is shorter than its size.
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			if err != nil {
+				mu.Lock()
+				if rerr == nil {
+					rerr = err
+				}
+				mu.Unlock()
+			}
+		}(p[start:end], f.offset+int64(start))
+	}
+	wg.Wait()
+
+	if rerr != nil {
+		return 0, rerr
+	}
+
+	// the body is behind the data read; reopen it lazily at the new offset.
+	if err := f.ReadCloser.Close(); err != nil {
+		return 0, err
+	}
+	f.ReadCloser = reopenBody{f}
+	f.offset += int64(n)
+	return n, nil
+}
+
+// reopenBody is the body of a file after a parallel Read. It is replaced
+// by a body at the file's offset on the first Read.
+type reopenBody struct{ f *file }
+
+func (b reopenBody) Read(p []byte) (int, error) {
+	stat, err := b.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := b.f.openAt(b.f.offset, stat.Size()); err != nil {
+		return 0, err
+	}
+	return b.f.ReadCloser.Read(p)
+}
+
+func (reopenBody) Close() error { return nil }
@@ -0,0 +1,129 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestParallelRangeReads(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4<<20/16+7)
+
+	rangedGets := func(cl *memClient) (n int) {
+		for _, in := range cl.getInputs() {
+			if in.Range != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("read", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("big.bin", content)
+
+		f, err := s3fs.New(cl, "test", s3fs.WithParallelRangeReads(4)).Open("big.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		head := make([]byte, 100)
+		if _, err := io.ReadFull(f, head); err != nil {
+			t.Fatal(err)
+		}
+
+		p := make([]byte, 3<<20)
+		n, err := f.Read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(p) || !bytes.Equal(p, content[100:100+len(p)]) {
+			t.Fatalf("want %d bytes at 100; got %d", len(p), n)
+		}
+		if got := rangedGets(cl); got != 3 {
+			t.Errorf("want 3 ranged GetObjects; got %d", got)
+		}
+
+		rest, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, content[100+len(p):]) {
+			t.Errorf("want the remaining %d bytes; got %d", len(content)-100-len(p), len(rest))
+		}
+	})
+
+	t.Run("failed range", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("big.bin", content)
+
+		fail := true
+		cl.hook = func(op string, in interface{}) error {
+			if in, ok := in.(*s3.GetObjectInput); ok && fail && in.Range != nil && strings.HasPrefix(*in.Range, "bytes=1048576-") {
+				return errors.New("connection reset")
+			}
+			return nil
+		}
+
+		f, err := s3fs.New(cl, "test", s3fs.WithParallelRangeReads(4)).Open("big.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		p := make([]byte, 3<<20)
+		if n, err := f.Read(p); err == nil || n != 0 {
+			t.Fatalf("want error and no bytes read; got %d, %v", n, err)
+		}
+
+		fail = false
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Errorf("want the whole content after the failed read; got %d bytes", len(data))
+		}
+	})
+}
+
+func BenchmarkParallelRangeReads(b *testing.B) {
+	content := make([]byte, 100<<20)
+
+	for _, bm := range []struct {
+		desc string
+		opts []s3fs.Option
+	}{
+		{desc: "serial"},
+		{desc: "parallel", opts: []s3fs.Option{s3fs.WithParallelRangeReads(8)}},
+	} {
+		b.Run(bm.desc, func(b *testing.B) {
+			cl := newMemClient()
+			cl.put("big.bin", content)
+			cl.bandwidth = 1 << 30
+
+			fsys := s3fs.New(cl, "test", bm.opts...)
+			p := make([]byte, len(content))
+
+			b.SetBytes(int64(len(content)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				f, err := fsys.Open("big.bin")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.ReadFull(f, p); err != nil {
+					b.Fatal(err)
+				}
+				f.Close()
+			}
+		})
+	}
+}
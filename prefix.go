@@ -0,0 +1,260 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithPrefix roots the filesystem at the key prefix, e.g. "data/v2": every
+// name is relative to "data/v2/", "." lists the contents of the prefix and
+// listings, FileInfo and DirEntry never show it. Unlike Sub the whole
+// *S3FS is scoped, including RemoveAll, Create and the other methods that
+// are not part of io/fs.
+//
+// Leading and trailing slashes are trimmed; an empty prefix changes
+// nothing. It panics if prefix is not a valid path.
+func WithPrefix(prefix string) Option {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" && !fs.ValidPath(prefix) {
+		panic("s3fs: invalid prefix " + strconv.Quote(prefix))
+	}
+	return func(fsys *S3FS) { fsys.prefix = prefix }
+}
+
+// prefixClient adds prefix to the keys sent to S3 and removes it from the
+// keys S3 returns, so that the rest of S3FS only deals with names relative
+// to the prefix.
+//
+// It implements the optional clients S3FS detects too; calls fail if the
+// wrapped client does not implement them.
+type prefixClient struct {
+	S3Client
+	// prefix is the key prefix, ending with "/".
+	prefix string
+}
+
+var (
+	_ versionAPIClient        = (*prefixClient)(nil)
+	_ copyAPIClient           = (*prefixClient)(nil)
+	_ taggingAPIClient        = (*prefixClient)(nil)
+	_ uploadPartCopyAPIClient = (*prefixClient)(nil)
+)
+
+func newPrefixClient(cl S3Client, prefix string) *prefixClient {
+	return &prefixClient{S3Client: cl, prefix: prefix + "/"}
+}
+
+// bucketClient returns the client of f without the prefix of WithPrefix and
+// the Observer of WithObserver, and the key of name in the bucket.
+func (f *S3FS) bucketClient(name string) (S3Client, string) {
+	cl := f.cl
+	if oc, ok := cl.(*observedClient); ok {
+		cl = oc.S3Client
+	}
+	if pc, ok := cl.(*prefixClient); ok {
+		return pc.S3Client, pc.prefix + name
+	}
+	return cl, name
+}
+
+// key returns the key of name.
+func (c *prefixClient) key(name *string) *string {
+	if name == nil {
+		return nil
+	}
+	return aws.String(c.prefix + *name)
+}
+
+// name returns the name of key, which is relative to the prefix.
+func (c *prefixClient) name(key *string) *string {
+	if key == nil {
+		return nil
+	}
+	return aws.String(strings.TrimPrefix(*key, c.prefix))
+}
+
+func (c *prefixClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	in := *params
+	in.Prefix = aws.String(c.prefix + aws.ToString(in.Prefix))
+	in.StartAfter = c.key(in.StartAfter)
+
+	out, err := c.S3Client.ListObjectsV2(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Prefix = c.name(out.Prefix)
+	out.StartAfter = c.name(out.StartAfter)
+	for i := range out.Contents {
+		out.Contents[i].Key = c.name(out.Contents[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = c.name(out.CommonPrefixes[i].Prefix)
+	}
+	return out, nil
+}
+
+func (c *prefixClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+	return c.S3Client.HeadObject(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+	return c.S3Client.GetObject(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+	return c.S3Client.PutObject(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	in := *params
+	if params.Delete != nil {
+		del := *params.Delete
+		del.Objects = make([]types.ObjectIdentifier, len(params.Delete.Objects))
+		for i, id := range params.Delete.Objects {
+			id.Key = c.key(id.Key)
+			del.Objects[i] = id
+		}
+		in.Delete = &del
+	}
+
+	out, err := c.S3Client.DeleteObjects(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range out.Deleted {
+		out.Deleted[i].Key = c.name(out.Deleted[i].Key)
+	}
+	for i := range out.Errors {
+		out.Errors[i].Key = c.name(out.Errors[i].Key)
+	}
+	return out, nil
+}
+
+func (c *prefixClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+
+	out, err := c.S3Client.CreateMultipartUpload(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Key = c.name(out.Key)
+	return out, nil
+}
+
+func (c *prefixClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+	return c.S3Client.UploadPart(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+
+	out, err := c.S3Client.CompleteMultipartUpload(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Key = c.name(out.Key)
+	return out, nil
+}
+
+func (c *prefixClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	in := *params
+	in.Key = c.key(in.Key)
+	return c.S3Client.AbortMultipartUpload(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	cl, ok := c.S3Client.(versionAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement ListObjectVersions", c.S3Client)
+	}
+
+	in := *params
+	in.Prefix = aws.String(c.prefix + aws.ToString(in.Prefix))
+	in.KeyMarker = c.key(in.KeyMarker)
+
+	out, err := cl.ListObjectVersions(ctx, &in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Prefix = c.name(out.Prefix)
+	out.KeyMarker = c.name(out.KeyMarker)
+	out.NextKeyMarker = c.name(out.NextKeyMarker)
+	for i := range out.Versions {
+		out.Versions[i].Key = c.name(out.Versions[i].Key)
+	}
+	for i := range out.DeleteMarkers {
+		out.DeleteMarkers[i].Key = c.name(out.DeleteMarkers[i].Key)
+	}
+	for i := range out.CommonPrefixes {
+		out.CommonPrefixes[i].Prefix = c.name(out.CommonPrefixes[i].Prefix)
+	}
+	return out, nil
+}
+
+func (c *prefixClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	cl, ok := c.S3Client.(copyAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement CopyObject", c.S3Client)
+	}
+
+	in := *params
+	in.Key = c.key(in.Key)
+	in.CopySource = c.copySource(in.CopySource)
+	return cl.CopyObject(ctx, &in, optFns...)
+}
+
+func (c *prefixClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	cl, ok := c.S3Client.(uploadPartCopyAPIClient)
+	if !ok {
+		// CopyRange falls back to copying through the client.
+		return nil, errCopyUnsupported
+	}
+
+	in := *params
+	in.Key = c.key(in.Key)
+	in.CopySource = c.copySource(in.CopySource)
+	return cl.UploadPartCopy(ctx, &in, optFns...)
+}
+
+// copySource adds the prefix to the key of src, which is "bucket/key" with
+// key URL escaped.
+func (c *prefixClient) copySource(src *string) *string {
+	if src == nil {
+		return nil
+	}
+	bucket, key, _ := strings.Cut(*src, "/")
+	prefix := strings.ReplaceAll(url.PathEscape(c.prefix), "%2F", "/")
+	return aws.String(bucket + "/" + prefix + key)
+}
+
+func (c *prefixClient) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	cl, ok := c.S3Client.(taggingAPIClient)
+	if !ok {
+		return nil, fmt.Errorf("s3fs: %T does not implement GetObjectTagging", c.S3Client)
+	}
+
+	in := *params
+	in.Key = c.key(in.Key)
+	return cl.GetObjectTagging(ctx, &in, optFns...)
+}
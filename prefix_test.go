@@ -0,0 +1,107 @@
+package s3fs_test
+
+import (
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWithPrefix(t *testing.T) {
+	newFS := func(t *testing.T, prefix string) (*memClient, *s3fs.S3FS) {
+		t.Helper()
+
+		cl := newMemClient()
+		cl.put("data/v2/a.txt", []byte("a"))
+		cl.put("data/v2/dir/b.txt", []byte("bb"))
+		cl.put("data/v1/old.txt", []byte("old"))
+		cl.put("other.txt", []byte("other"))
+		return cl, s3fs.New(cl, "test", s3fs.WithPrefix(prefix))
+	}
+
+	t.Run("fstest", func(t *testing.T) {
+		_, fsys := newFS(t, "/data/v2/")
+		if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("relative names", func(t *testing.T) {
+		_, fsys := newFS(t, "data/v2")
+
+		des, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		if want := []string{"a.txt", "dir"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("want %q; got %q", want, names)
+		}
+
+		fi, err := fsys.Stat("dir/b.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Name() != "b.txt" || fi.Size() != 2 {
+			t.Errorf("want b.txt of size 2; got %s of size %d", fi.Name(), fi.Size())
+		}
+
+		matches, err := fsys.Glob("*/*.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"dir/b.txt"}; !reflect.DeepEqual(matches, want) {
+			t.Errorf("want %q; got %q", want, matches)
+		}
+
+		if _, err := fsys.Stat("data/v2/a.txt"); !s3fs.IsNotExist(err) {
+			t.Errorf("want a not exist error; got %v", err)
+		}
+	})
+
+	t.Run("write and remove", func(t *testing.T) {
+		cl, fsys := newFS(t, "data/v2")
+
+		if err := fsys.WriteFile("new.txt", []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cl.get("data/v2/new.txt"); !ok {
+			t.Error("expected data/v2/new.txt to be written")
+		}
+
+		if err := fsys.RemoveAll("dir"); err != nil {
+			t.Fatal(err)
+		}
+
+		var keys []string
+		for key := range cl.objects {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if want := []string{"data/v1/old.txt", "data/v2/a.txt", "data/v2/new.txt", "other.txt"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("want keys %q; got %q", want, keys)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, fsys := newFS(t, "/")
+		if _, err := fs.Stat(fsys, "data/v2/a.txt"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		s3fs.WithPrefix("data/../v2")
+	})
+}
@@ -0,0 +1,117 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// PresignGetObject returns a URL that downloads the object name without
+// credentials until expires has passed, e.g. to redirect HTTP clients to S3
+// instead of proxying the content. It returns fs.ErrNotExist if there is no
+// such object.
+//
+// The URL is signed with the credentials of the client, which must be the
+// *s3.Client the filesystem was created with. SigV4 URLs are valid for at
+// most 7 days, and for 15 minutes if expires is 0.
+func (f *S3FS) PresignGetObject(name string, expires time.Duration) (string, error) {
+	if _, err := f.headObject(context.TODO(), name); err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	cl, key, err := f.presignClient(name)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	req, err := cl.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return req.URL, nil
+}
+
+// PresignPutObject is like PresignGetObject, but returns a URL that uploads
+// the body of a PUT request as the object name, replacing it if it exists.
+//
+// With WithStorageClass and WithServerSideEncryption the URL is signed for
+// their headers, X-Amz-Storage-Class, X-Amz-Server-Side-Encryption and
+// X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id: the PUT must send them with
+// the values of the options, or S3 rejects its signature.
+func (f *S3FS) PresignPutObject(name string, expires time.Duration) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	cl, key, err := f.presignClient(name)
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	req, err := cl.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:               &f.bucket,
+		Key:                  aws.String(key),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", &fs.PathError{
+			Op:   "presign",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return req.URL, nil
+}
+
+// presignClient returns a presign client of the *s3.Client of the
+// filesystem and the key of name.
+func (f *S3FS) presignClient(name string) (*s3.PresignClient, string, error) {
+	// the presigned request is not sent through the client, so the prefix
+	// is added here.
+	cl, key := f.bucketClient(name)
+
+	s3cl, ok := cl.(*s3.Client)
+	if !ok {
+		return nil, "", fmt.Errorf("s3fs: cannot presign URLs with %T, only with *s3.Client", cl)
+	}
+	return s3.NewPresignClient(s3cl, func(o *s3.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, f.optFns...)
+	}), key, nil
+}
@@ -0,0 +1,127 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestPresign(t *testing.T) {
+	newFS := func(opts ...s3fs.Option) *s3fs.S3FS {
+		cl := s3.New(s3.Options{
+			Region: "us-east-1",
+			Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+				return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+			}),
+			UsePathStyle: true,
+			HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+				status := http.StatusOK
+				if strings.HasSuffix(r.URL.Path, "/missing.txt") {
+					status = http.StatusNotFound
+				}
+				return &http.Response{
+					StatusCode: status,
+					Header:     http.Header{"Content-Length": []string{"7"}},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}),
+		})
+		return s3fs.New(cl, "test", opts...)
+	}
+
+	check := func(t *testing.T, rawurl, path string) {
+		t.Helper()
+
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Path != path {
+			t.Errorf("want path %s; got %s", path, u.Path)
+		}
+		q := u.Query()
+		if got := q.Get("X-Amz-Expires"); got != "3600" {
+			t.Errorf("want expiry 3600; got %s", got)
+		}
+		if q.Get("X-Amz-Signature") == "" {
+			t.Error("expected a signature")
+		}
+	}
+
+	get, err := newFS().PresignGetObject("dir/file.txt", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, get, "/test/dir/file.txt")
+
+	put, err := newFS().PresignPutObject("dir/new.txt", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, put, "/test/dir/new.txt")
+
+	t.Run("prefix", func(t *testing.T) {
+		get, err := newFS(s3fs.WithPrefix("data")).PresignGetObject("file.txt", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, get, "/test/data/file.txt")
+	})
+
+	t.Run("storage headers", func(t *testing.T) {
+		put, err := newFS(
+			s3fs.WithStorageClass(types.StorageClassStandardIa),
+			s3fs.WithServerSideEncryption(types.ServerSideEncryptionAwsKms, "key-id"),
+		).PresignPutObject("dir/new.txt", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := url.Parse(put)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed := strings.Split(u.Query().Get("X-Amz-SignedHeaders"), ";")
+		for _, h := range []string{"x-amz-storage-class", "x-amz-server-side-encryption", "x-amz-server-side-encryption-aws-kms-key-id"} {
+			found := false
+			for _, s := range signed {
+				found = found || s == h
+			}
+			if !found {
+				t.Errorf("want %s to be signed; got %q", h, signed)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := newFS().PresignGetObject("missing.txt", time.Hour); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+		for _, name := range []string{".", "../up", "/abs"} {
+			if _, err := newFS().PresignPutObject(name, time.Hour); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%s: want %v; got %v", name, fs.ErrInvalid, err)
+			}
+			if _, err := newFS().PresignGetObject(name, time.Hour); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%s: want %v; got %v", name, fs.ErrInvalid, err)
+			}
+		}
+
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		if _, err := s3fs.New(cl, "test").PresignGetObject("file.txt", time.Hour); err == nil {
+			t.Error("expected an error for a client that is not a *s3.Client")
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// maxDeleteKeys is the maximum number of keys a single DeleteObjects accepts.
+const maxDeleteKeys = 1000
+
+// PruneEmptyDirs deletes the directory markers (zero-byte "dir/" objects)
+// below the directory name that have no children left, e.g. after their
+// contents were deleted. It returns the number of markers deleted.
+//
+// Markers whose only children are empty directories are pruned as well.
+// The marker of name itself is kept.
+func (f *S3FS) PruneEmptyDirs(name string) (int, error) {
+	if !fs.ValidPath(name) {
+		return 0, &fs.PathError{
+			Op:   "prune",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return 0, &fs.PathError{
+			Op:   "prune",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+
+	var (
+		keys    []string
+		markers = make(map[string]bool)
+		token   *string
+		last    time.Time
+	)
+	for {
+		if err := f.paceList(context.TODO(), last); err != nil {
+			return 0, &fs.PathError{
+				Op:   "prune",
+				Path: name,
+				Err:  err,
+			}
+		}
+		last = time.Now()
+
+		out, err := f.cl.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			RequestPayer:      f.requestPayer,
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+			MaxKeys:           f.maxKeys,
+		}, f.optFns...)
+		if err != nil {
+			return 0, &fs.PathError{
+				Op:   "prune",
+				Path: name,
+				Err:  err,
+			}
+		}
+		f.auditList(prefix, out)
+
+		for _, o := range out.Contents {
+			key := aws.ToString(o.Key)
+			if key == prefix {
+				continue
+			}
+
+			keys = append(keys, key)
+			if strings.HasSuffix(key, "/") && o.Size == 0 {
+				markers[key] = true
+			}
+		}
+
+		if !out.IsTruncated {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+
+	// children counts the keys below every marker.
+	children := make(map[string]int, len(markers))
+	for _, key := range keys {
+		for _, m := range markerParents(key, prefix, markers) {
+			children[m]++
+		}
+	}
+
+	var empty []string
+	for m := range markers {
+		empty = append(empty, m)
+	}
+	// the deepest markers come first, so that a marker whose children were
+	// all pruned is pruned too.
+	sort.Slice(empty, func(i, j int) bool {
+		if di, dj := strings.Count(empty[i], "/"), strings.Count(empty[j], "/"); di != dj {
+			return di > dj
+		}
+		return empty[i] < empty[j]
+	})
+
+	var pruned []string
+	for _, m := range empty {
+		if children[m] > 0 {
+			continue
+		}
+
+		pruned = append(pruned, m)
+		for _, p := range markerParents(m, prefix, markers) {
+			children[p]--
+		}
+	}
+
+	if err := f.deleteKeys(context.TODO(), pruned); err != nil {
+		return 0, &fs.PathError{
+			Op:   "prune",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return len(pruned), nil
+}
+
+// markerParents returns the markers among the parent directories of key
+// below prefix.
+func markerParents(key, prefix string, markers map[string]bool) []string {
+	var parents []string
+	rest := strings.TrimSuffix(key, "/")
+	for i := len(prefix); i < len(rest); i++ {
+		if rest[i] != '/' {
+			continue
+		}
+		if p := rest[:i+1]; markers[p] {
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
+
+// deleteKeys deletes keys with as few DeleteObjects calls as possible. The
+// objects that could not be deleted are reported by a *DeleteError once
+// all the batches were sent; a failed DeleteObjects call stops at once.
+func (f *S3FS) deleteKeys(ctx context.Context, keys []string) error {
+	var derr *DeleteError
+	for len(keys) > 0 {
+		n := min(len(keys), maxDeleteKeys)
+
+		ids := make([]types.ObjectIdentifier, n)
+		for i, key := range keys[:n] {
+			ids[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+
+		out, err := f.cl.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket:       &f.bucket,
+			RequestPayer: f.requestPayer,
+			Delete: &types.Delete{
+				Objects: ids,
+				Quiet:   true,
+			},
+		}, f.optFns...)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range out.Errors {
+			if derr == nil {
+				derr = &DeleteError{Errors: make(map[string]error)}
+			}
+			derr.Errors[aws.ToString(e.Key)] = &smithy.GenericAPIError{
+				Code:    aws.ToString(e.Code),
+				Message: aws.ToString(e.Message),
+			}
+		}
+
+		keys = keys[n:]
+	}
+
+	if derr != nil {
+		return derr
+	}
+	return nil
+}
+
+// DeleteError is returned when some of the objects of a batch delete could
+// not be deleted.
+type DeleteError struct {
+	// Errors holds the error S3 returned for every object that was not
+	// deleted, by key.
+	Errors map[string]error
+}
+
+func (e *DeleteError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msg := fmt.Sprintf("s3fs: delete %s: %v", keys[0], e.Errors[keys[0]])
+	if len(keys) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(keys)-1)
+	}
+	return msg
+}
@@ -0,0 +1,46 @@
+package s3fs_test
+
+import (
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestPruneEmptyDirs(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"data/",
+		"data/empty/",
+		"data/nested/",
+		"data/nested/empty/",
+		"data/full/",
+		"data/full/file.txt",
+		"other/",
+	} {
+		cl.put(key, nil)
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	n, err := fsys.PruneEmptyDirs("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("want 3 pruned markers; got %d", n)
+	}
+
+	for key, kept := range map[string]bool{
+		"data/":              true,
+		"data/empty/":        false,
+		"data/nested/":       false,
+		"data/nested/empty/": false,
+		"data/full/":         true,
+		"data/full/file.txt": true,
+		"other/":             true,
+	} {
+		if _, ok := cl.get(key); ok != kept {
+			t.Errorf("%s: want kept %v; got %v", key, kept, ok)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package s3fs
+
+import (
+	"container/list"
+	"io/fs"
+	"sync"
+)
+
+// WithRangeCache enables an in-memory LRU cache of byte ranges read with
+// ReadAt. Repeated reads of the same window of an object (e.g. Parquet
+// footers) are served from memory instead of S3. maxBytes bounds the total
+// size of the cached data.
+//
+// Entries are keyed by the ETag of the object, so files opened on different
+// versions of an object never share ranges; the ranges of versions that are
+// no longer read age out of the cache like any other.
+//
+// ReadAt is only exposed on files when WithReadSeeker is used.
+func WithRangeCache(maxBytes int64) Option {
+	return func(fsys *S3FS) {
+		if maxBytes > 0 {
+			fsys.rangeCache = newRangeCache(maxBytes)
+		}
+	}
+}
+
+type rangeKey struct {
+	name   string
+	eTag   string
+	offset int64
+	len    int
+}
+
+type rangeEntry struct {
+	key  rangeKey
+	data []byte
+}
+
+// rangeCache is a concurrency safe LRU cache of byte ranges.
+type rangeCache struct {
+	mu      sync.Mutex
+	max     int64
+	size    int64
+	ll      *list.List
+	entries map[rangeKey]*list.Element
+}
+
+func newRangeCache(maxBytes int64) *rangeCache {
+	return &rangeCache{
+		max:     maxBytes,
+		ll:      list.New(),
+		entries: make(map[rangeKey]*list.Element),
+	}
+}
+
+func (c *rangeCache) get(name, eTag string, offset int64, n int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[rangeKey{name, eTag, offset, n}]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*rangeEntry).data, true
+}
+
+func (c *rangeCache) put(name, eTag string, offset int64, n int, data []byte) {
+	if int64(len(data)) > c.max {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := rangeKey{name, eTag, offset, n}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.ll.PushFront(&rangeEntry{
+		key:  key,
+		data: append([]byte(nil), data...),
+	})
+	c.size += int64(len(data))
+
+	for c.size > c.max {
+		c.removeLocked(c.ll.Back())
+	}
+}
+
+// attach makes f serve ReadAt through the cache.
+func (c *rangeCache) attach(f fs.File) {
+	if f, ok := f.(*file); ok {
+		f.rangeCache = c
+	}
+}
+
+func (c *rangeCache) removeLocked(e *list.Element) {
+	entry := c.ll.Remove(e).(*rangeEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
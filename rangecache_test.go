@@ -0,0 +1,208 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRangeCache(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	rangedGets := func(cl *memClient) (n int) {
+		for _, in := range cl.getInputs() {
+			if in.Range != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	readFooter := func(t *testing.T, fsys *s3fs.S3FS) []byte {
+		t.Helper()
+
+		f, err := fsys.Open("data.parquet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		p := make([]byte, 8)
+		n, err := f.(io.ReaderAt).ReadAt(p, int64(len(content)-len(p)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p[:n]
+	}
+
+	t.Run("footer is fetched once", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.parquet", content)
+
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(1<<20))
+		for i := 0; i < 3; i++ {
+			if got := readFooter(t, fsys); !bytes.Equal(got, content[len(content)-8:]) {
+				t.Fatalf("want %q; got %q", content[len(content)-8:], got)
+			}
+		}
+
+		if n := rangedGets(cl); n != 1 {
+			t.Errorf("want 1 ranged GetObject; got %d", n)
+		}
+	})
+
+	t.Run("etag change invalidates", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.parquet", content)
+
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(1<<20))
+		readFooter(t, fsys)
+
+		changed := bytes.ToUpper(bytes.Repeat([]byte("abcdefghij"), 100))
+		cl.put("data.parquet", changed)
+
+		if _, err := fsys.Stat("data.parquet"); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := readFooter(t, fsys); !bytes.Equal(got, changed[len(changed)-8:]) {
+			t.Fatalf("want %q; got %q", changed[len(changed)-8:], got)
+		}
+
+		if n := rangedGets(cl); n != 2 {
+			t.Errorf("want 2 ranged GetObjects; got %d", n)
+		}
+	})
+
+	t.Run("interleaved etags", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.parquet", content)
+
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(1<<20))
+		f1, err := fsys.Open("data.parquet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f1.Close()
+
+		p := make([]byte, 8)
+		off := int64(len(content) - len(p))
+		if _, err := f1.(io.ReaderAt).ReadAt(p, off); err != nil {
+			t.Fatal(err)
+		}
+
+		changed := bytes.ToUpper(bytes.Repeat([]byte("abcdefghij"), 100))
+		cl.put("data.parquet", changed)
+
+		f2, err := fsys.Open("data.parquet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f2.Close()
+
+		for i := 0; i < 2; i++ {
+			for _, f := range []struct {
+				file fs.File
+				want []byte
+			}{
+				{f1, content[off:]},
+				{f2, changed[off:]},
+			} {
+				if _, err := f.file.(io.ReaderAt).ReadAt(p, off); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(p, f.want) {
+					t.Fatalf("want %q; got %q", f.want, p)
+				}
+			}
+		}
+
+		// f1 keeps reading its version from the cache.
+		if n := rangedGets(cl); n != 2 {
+			t.Errorf("want 2 ranged GetObjects; got %d", n)
+		}
+	})
+
+	t.Run("lru eviction", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.parquet", content)
+
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(8))
+		f, err := fsys.Open("data.parquet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		r := f.(io.ReaderAt)
+		p := make([]byte, 8)
+		for _, off := range []int64{0, 100, 0} {
+			if _, err := r.ReadAt(p, off); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(p, content[off:off+8]) {
+				t.Fatalf("want %q; got %q", content[off:off+8], p)
+			}
+		}
+
+		if n := rangedGets(cl); n != 3 {
+			t.Errorf("want 3 ranged GetObjects; got %d", n)
+		}
+	})
+}
+
+func TestPartAlignedReads(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10)
+
+	cl := newMemClient()
+	cl.put("data.orc", content)
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithPartAlignedReads(16))
+	f, err := fsys.Open("data.orc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r := f.(io.ReaderAt)
+	for _, read := range []struct{ off, n int64 }{
+		{10, 10},
+		{20, 8},
+		{30, 6},
+		{90, 20},
+		// backward reads of kept parts.
+		{0, 10},
+		{40, 8},
+	} {
+		p := make([]byte, read.n)
+		n, err := r.ReadAt(p, read.off)
+		if err != nil && err != io.EOF {
+			t.Fatal(err)
+		}
+
+		end := read.off + read.n
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		want := content[read.off:end]
+		if !bytes.Equal(p[:n], want) {
+			t.Errorf("ReadAt(%d, %d): want %q; got %q", read.off, read.n, want, p[:n])
+		}
+	}
+
+	var ranges []string
+	for _, in := range cl.getInputs() {
+		if in.Range != nil {
+			ranges = append(ranges, *in.Range)
+		}
+	}
+	// only the parts that were not read before are requested.
+	want := []string{"bytes=0-31", "bytes=32-47", "bytes=80-99"}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("want ranges %q; got %q", want, ranges)
+	}
+}
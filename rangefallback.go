@@ -0,0 +1,68 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrRangeIgnored is returned when a ranged GetObject was answered with the
+// whole object, which some S3 compatible stores do for some objects, and
+// WithRangeFallback is not used.
+var ErrRangeIgnored = errors.New("range request ignored by server")
+
+// WithRangeFallback makes reads cope with servers ignoring the Range of a
+// GetObject: instead of failing with ErrRangeIgnored, the bytes before the
+// requested offset are read and discarded. This keeps reads correct at the
+// cost of downloading the skipped part of the object.
+func WithRangeFallback(fsys *S3FS) { fsys.rangeFallback = true }
+
+// WithRangeFormatter sets the function formatting the Range header of the
+// ranged GetObjects made by the filesystem, for servers that mishandle the
+// standard format. end is inclusive, or -1 for a range reaching the end of
+// the object. The default formats "bytes=start-end" and "bytes=start-".
+func WithRangeFormatter(fn func(start, end int64) string) Option {
+	return func(fsys *S3FS) { fsys.rangeFormatter = fn }
+}
+
+// rangeHeader returns the Range header of the bytes from start to end,
+// inclusive; an end of -1 means the end of the object.
+func (f *S3FS) rangeHeader(start, end int64) *string {
+	var r string
+	switch {
+	case f.rangeFormatter != nil:
+		r = f.rangeFormatter(start, end)
+	case end < 0:
+		r = fmt.Sprintf("bytes=%d-", start)
+	default:
+		r = fmt.Sprintf("bytes=%d-%d", start, end)
+	}
+	return &r
+}
+
+// skipIgnoredRange checks that the response to a GetObject for the range
+// starting at offset actually starts there. A satisfied range always has a
+// Content-Range; without it the body holds the whole object, and the bytes
+// before offset are skipped if WithRangeFallback is used. The body is closed
+// if an error is returned.
+func (f *S3FS) skipIgnoredRange(out *s3.GetObjectOutput, offset int64) error {
+	if offset == 0 || out.ContentRange != nil {
+		return nil
+	}
+
+	if !f.rangeFallback {
+		out.Body.Close()
+		return ErrRangeIgnored
+	}
+
+	if _, err := io.CopyN(io.Discard, out.Body, offset); err != nil {
+		out.Body.Close()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
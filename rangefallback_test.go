@@ -0,0 +1,125 @@
+package s3fs_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRangeFallback(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+
+	cl := newMemClient()
+	cl.put("file.txt", content)
+	cl.ignoreRange = true
+
+	seekRead := func(fsys *s3fs.S3FS) ([]byte, error) {
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if _, err := f.(io.Seeker).Seek(10, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.ReadAll(f)
+	}
+
+	readAt := func(fsys *s3fs.S3FS) ([]byte, error) {
+		f, err := fsys.Open("file.txt")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		p := make([]byte, 5)
+		n, err := f.(io.ReaderAt).ReadAt(p, 12)
+		return p[:n], err
+	}
+
+	t.Run("error", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(1<<10))
+
+		if _, err := seekRead(fsys); !errors.Is(err, s3fs.ErrRangeIgnored) {
+			t.Errorf("Seek: want %v; got %v", s3fs.ErrRangeIgnored, err)
+		}
+		if _, err := readAt(fsys); !errors.Is(err, s3fs.ErrRangeIgnored) {
+			t.Errorf("ReadAt: want %v; got %v", s3fs.ErrRangeIgnored, err)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeCache(1<<10), s3fs.WithRangeFallback)
+
+		data, err := seekRead(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(content[10:]) {
+			t.Errorf("Seek: want %q; got %q", content[10:], data)
+		}
+
+		data, err = readAt(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(content[12:17]) {
+			t.Errorf("ReadAt: want %q; got %q", content[12:17], data)
+		}
+	})
+}
+
+func TestRangeFormatter(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+
+	cl := newMemClient()
+	cl.put("file.txt", content)
+
+	// a server that only understands closed ranges.
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker, s3fs.WithRangeFormatter(func(start, end int64) string {
+		if end < 0 {
+			end = 1<<40 - 1
+		}
+		return fmt.Sprintf("bytes=%d-%d", start, end)
+	}))
+
+	f, err := fsys.Open("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.(io.Seeker).Seek(10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "abcdefghij" {
+		t.Errorf("want abcdefghij; got %q", data)
+	}
+
+	p := make([]byte, 4)
+	if _, err := f.(io.ReaderAt).ReadAt(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != "2345" {
+		t.Errorf("want 2345; got %q", p)
+	}
+
+	var ranges []string
+	for _, in := range cl.getInputs() {
+		if in.Range != nil {
+			ranges = append(ranges, *in.Range)
+		}
+	}
+	if want := []string{"bytes=10-1099511627775", "bytes=2-5"}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("want ranges %q; got %q", want, ranges)
+	}
+}
@@ -0,0 +1,106 @@
+package s3fs
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+var (
+	_ fs.StatFS     = (*Recorder)(nil)
+	_ fs.ReadDirFS  = (*Recorder)(nil)
+	_ fs.ReadFileFS = (*Recorder)(nil)
+)
+
+// Recorder is a filesystem that records the keys of the files accessed
+// through it. It is meant for building minimal test fixtures: run the code
+// under test once against the real bucket, then seed a fake client with
+// the keys returned by AccessedKeys.
+type Recorder struct {
+	fsys fs.FS
+
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// NewRecorder returns a Recorder wrapping fsys.
+func NewRecorder(fsys fs.FS) *Recorder {
+	return &Recorder{
+		fsys: fsys,
+		keys: make(map[string]bool),
+	}
+}
+
+// AccessedKeys returns the keys of the files that were successfully
+// opened, statted, read or returned by ReadDir so far, sorted. Directories
+// are not keys and are left out.
+func (r *Recorder) AccessedKeys() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]string, 0, len(r.keys))
+	for key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *Recorder) record(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[name] = true
+}
+
+// Open implements fs.FS.
+func (r *Recorder) Open(name string) (fs.File, error) {
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isDir := f.(fs.ReadDirFile); !isDir {
+		r.record(name)
+	}
+	return f, nil
+}
+
+// Stat implements fs.StatFS.
+func (r *Recorder) Stat(name string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(r.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		r.record(name)
+	}
+	return fi, nil
+}
+
+// ReadDir implements fs.ReadDirFS. The files in the directory are recorded.
+func (r *Recorder) ReadDir(name string) ([]fs.DirEntry, error) {
+	des, err := fs.ReadDir(r.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, de := range des {
+		if !de.IsDir() {
+			r.record(path.Join(name, de.Name()))
+		}
+	}
+	return des, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (r *Recorder) ReadFile(name string) ([]byte, error) {
+	data, err := fs.ReadFile(r.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record(name)
+	return data, nil
+}
@@ -0,0 +1,43 @@
+package s3fs_test
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRecorder(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "dir/sub/e.txt", "other/f.txt"} {
+		cl.put(key, []byte(key))
+	}
+
+	rec := s3fs.NewRecorder(s3fs.New(cl, "test"))
+
+	f, err := rec.Open("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := fs.Stat(rec, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(rec, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Open("missing.txt"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, err := fs.ReadDir(rec, "dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt"}
+	if got := rec.AccessedKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
@@ -0,0 +1,101 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// RemoveIfMatch deletes the object name only if its ETag is etag, and
+// fails with ErrPreconditionFailed otherwise. The quotes S3 puts around
+// ETags are optional.
+//
+// S3 has no conditional delete, so the ETag is checked with a HeadObject
+// first; an object replaced between the check and the delete is still
+// deleted.
+func (f *S3FS) RemoveIfMatch(name, etag string) error {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if strings.Trim(aws.ToString(head.ETag), `"`) != strings.Trim(etag, `"`) {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  ErrPreconditionFailed,
+		}
+	}
+
+	if err := f.removeKeys(context.TODO(), []string{name}); err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if f.statCache != nil {
+		f.statCache.invalidate(name)
+	}
+	return nil
+}
+
+// RemoveAll deletes name and everything under it, like os.RemoveAll: the
+// object name if there is one and all the objects whose keys start with
+// name+"/", directory markers included. It returns nil if nothing exists.
+//
+// Objects are deleted with DeleteObjects in batches of 1000 keys. Objects
+// that could not be deleted are reported together by a *DeleteError. With
+// WithTrashPrefix they are moved to the trash instead.
+func (f *S3FS) RemoveAll(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	// deleting a key that does not exist is not an error, so name is
+	// deleted without checking whether it is an object.
+	keys := []string{name}
+	err := f.listKeys(context.TODO(), "remove", name+"/", func(o types.Object) {
+		keys = append(keys, aws.ToString(o.Key))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = f.removeKeys(context.TODO(), keys)
+
+	if f.statCache != nil {
+		for _, key := range keys {
+			f.statCache.invalidate(strings.TrimSuffix(key, "/"))
+		}
+	}
+
+	if err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
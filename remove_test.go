@@ -0,0 +1,115 @@
+package s3fs_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRemoveIfMatch(t *testing.T) {
+	cl := newMemClient()
+	o := cl.put("file.txt", []byte("content"))
+
+	fsys := s3fs.New(cl, "test")
+
+	err := fsys.RemoveIfMatch("file.txt", `"0123456789abcdef"`)
+	if !errors.Is(err, s3fs.ErrPreconditionFailed) {
+		t.Errorf("want %v; got %v", s3fs.ErrPreconditionFailed, err)
+	}
+	if _, ok := cl.get("file.txt"); !ok {
+		t.Fatal("expected the object to be kept")
+	}
+
+	if err := fsys.RemoveIfMatch("file.txt", strings.Trim(o.etag, `"`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cl.get("file.txt"); ok {
+		t.Error("expected the object to be deleted")
+	}
+
+	if err := fsys.RemoveIfMatch("file.txt", o.etag); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	t.Run("prefix", func(t *testing.T) {
+		cl := newMemClient()
+		for i := 0; i < 1500; i++ {
+			cl.put(fmt.Sprintf("dir/sub/%04d.txt", i), nil)
+		}
+		cl.put("dir/", nil)
+		cl.put("dir", []byte("object"))
+		cl.put("dir.txt", nil)
+		cl.put("dirty/file.txt", nil)
+
+		fsys := s3fs.New(cl, "test")
+		if err := fsys.RemoveAll("dir"); err != nil {
+			t.Fatal(err)
+		}
+
+		var keys []string
+		for key := range cl.objects {
+			keys = append(keys, key)
+		}
+		if len(keys) != 2 {
+			t.Errorf("want only dir.txt and dirty/file.txt to be kept; got %q", keys)
+		}
+
+		for _, in := range cl.inputs {
+			if in, ok := in.(*s3.DeleteObjectsInput); ok && len(in.Delete.Objects) > 1000 {
+				t.Errorf("want at most 1000 keys per DeleteObjects; got %d", len(in.Delete.Objects))
+			}
+		}
+		if n := cl.count("DeleteObjects"); n != 2 {
+			t.Errorf("want 2 DeleteObjects calls; got %d", n)
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(), "test")
+		if err := fsys.RemoveAll("dir"); err != nil {
+			t.Errorf("want nil; got %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("dir/a.txt", nil)
+		cl.put("dir/b.txt", nil)
+		cl.put("dir/c.txt", nil)
+		cl.undeletable = map[string]bool{"dir/a.txt": true, "dir/c.txt": true}
+
+		fsys := s3fs.New(cl, "test")
+		err := fsys.RemoveAll("dir")
+
+		var derr *s3fs.DeleteError
+		if !errors.As(err, &derr) {
+			t.Fatalf("want a *DeleteError; got %v", err)
+		}
+		if len(derr.Errors) != 2 || derr.Errors["dir/a.txt"] == nil || derr.Errors["dir/c.txt"] == nil {
+			t.Errorf("want errors for dir/a.txt and dir/c.txt; got %v", derr.Errors)
+		}
+		if !s3fs.IsPermission(derr.Errors["dir/a.txt"]) {
+			t.Errorf("want a permission error; got %v", derr.Errors["dir/a.txt"])
+		}
+		if _, ok := cl.get("dir/b.txt"); ok {
+			t.Error("expected dir/b.txt to be deleted")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(), "test")
+		for _, name := range []string{".", "../dir", "dir/"} {
+			if err := fsys.RemoveAll(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%s: want %v; got %v", name, fs.ErrInvalid, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Rename moves the object oldName to newName, replacing it if it exists.
+// If oldName is a directory, every object under it is moved under newName.
+// Renaming a name to itself does nothing, once it was checked to exist.
+// S3 cannot rename objects, so each one is copied with CopyObject and the
+// originals are deleted once all copies succeeded: a failed copy leaves
+// every original in place, though some copies may already exist.
+//
+// The client must implement CopyObject, as *s3.Client does, and objects
+// larger than 5 GiB cannot be renamed.
+func (f *S3FS) Rename(oldName, newName string) error {
+	for _, name := range []string{oldName, newName} {
+		if !fs.ValidPath(name) || name == "." {
+			return &fs.PathError{
+				Op:   "rename",
+				Path: name,
+				Err:  fs.ErrInvalid,
+			}
+		}
+
+		if err := f.validateKey(name); err != nil {
+			return &fs.PathError{
+				Op:   "rename",
+				Path: name,
+				Err:  err,
+			}
+		}
+	}
+
+	ctx := context.TODO()
+	keys, err := f.renameKeys(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if oldName == newName {
+		// copying the objects onto themselves and deleting them would
+		// remove them.
+		return nil
+	}
+
+	for _, key := range keys {
+		dst := newName + strings.TrimPrefix(key, oldName)
+		if err := f.copyObject(ctx, key, dst); err != nil {
+			if isNotFoundErr(err) {
+				err = fs.ErrNotExist
+			}
+			return &fs.PathError{
+				Op:   "rename",
+				Path: oldName,
+				Err:  err,
+			}
+		}
+
+		if f.statCache != nil {
+			f.statCache.invalidate(strings.TrimSuffix(dst, "/"))
+		}
+	}
+
+	err = f.deleteKeys(ctx, keys)
+	if f.statCache != nil {
+		for _, key := range keys {
+			f.statCache.invalidate(strings.TrimSuffix(key, "/"))
+		}
+	}
+	if err != nil {
+		return &fs.PathError{
+			Op:   "rename",
+			Path: oldName,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// renameKeys returns the keys Rename moves: name if it is an object, or
+// else every key under the directory name, directory markers included.
+func (f *S3FS) renameKeys(ctx context.Context, name string) ([]string, error) {
+	_, err := f.headObject(ctx, name)
+	if err == nil {
+		return []string{name}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{
+			Op:   "rename",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	var keys []string
+	err = f.listKeys(ctx, "rename", name+"/", func(o types.Object) {
+		keys = append(keys, aws.ToString(o.Key))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, &fs.PathError{
+			Op:   "rename",
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,132 @@
+package s3fs_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRename(t *testing.T) {
+	keys := func(cl *memClient) []string {
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+
+		var keys []string
+		for key := range cl.objects {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	t.Run("file", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("old.txt", []byte("content"))
+		cl.put("new.txt", []byte("replaced"))
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.Rename("old.txt", "new.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"new.txt"}; !reflect.DeepEqual(keys(cl), want) {
+			t.Errorf("want keys %q; got %q", want, keys(cl))
+		}
+		if data, _ := fsys.ReadFile("new.txt"); string(data) != "content" {
+			t.Errorf("want content; got %q", data)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		cl := newMemClient()
+		for i := 0; i < 1200; i++ {
+			cl.put(fmt.Sprintf("dir/%04d.txt", i), nil)
+		}
+		cl.put("dir/sub/", nil)
+		cl.put("dir.txt", nil)
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.Rename("dir", "moved/dir"); err != nil {
+			t.Fatal(err)
+		}
+
+		got := keys(cl)
+		if len(got) != 1202 || got[0] != "dir.txt" || got[1] != "moved/dir/0000.txt" || got[1201] != "moved/dir/sub/" {
+			t.Errorf("want dir.txt and 1201 keys under moved/dir; got %d keys from %q to %q", len(got), got[0], got[len(got)-1])
+		}
+	})
+
+	t.Run("same name", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		cl.put("dir/a.txt", []byte("a"))
+		fsys := s3fs.New(cl, "test")
+
+		for _, name := range []string{"file.txt", "dir"} {
+			if err := fsys.Rename(name, name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if want := []string{"dir/a.txt", "file.txt"}; !reflect.DeepEqual(keys(cl), want) {
+			t.Errorf("want keys %q; got %q", want, keys(cl))
+		}
+		if n := cl.count("CopyObject") + cl.count("DeleteObjects"); n != 0 {
+			t.Errorf("want no copies or deletes; got %d", n)
+		}
+
+		if err := fsys.Rename("missing.txt", "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+
+	t.Run("copy error", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("dir/a.txt", nil)
+		cl.put("dir/b.txt", nil)
+		fsys := s3fs.New(cl, "test")
+
+		copies := 0
+		cl.hook = func(op string, in interface{}) error {
+			if op == "CopyObject" {
+				if copies++; copies == 2 {
+					return apiError(http.StatusForbidden, "AccessDenied")
+				}
+			}
+			return nil
+		}
+
+		if err := fsys.Rename("dir", "other"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if n := cl.count("DeleteObjects"); n != 0 {
+			t.Errorf("want no DeleteObjects; got %d", n)
+		}
+		for _, key := range []string{"dir/a.txt", "dir/b.txt"} {
+			if _, ok := cl.get(key); !ok {
+				t.Errorf("expected %s to be kept", key)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", nil)
+		fsys := s3fs.New(cl, "test")
+
+		if err := fsys.Rename("missing", "other"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+		for _, names := range [][2]string{{".", "other"}, {"file.txt", "."}, {"file.txt", "../up"}} {
+			err := fsys.Rename(names[0], names[1])
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) || pathErr.Op != "rename" || !errors.Is(err, fs.ErrInvalid) {
+				t.Errorf("%q: want a rename error matching %v; got %v", names, fs.ErrInvalid, err)
+			}
+		}
+	})
+}
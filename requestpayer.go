@@ -0,0 +1,13 @@
+package s3fs
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithRequestPayer makes the filesystem read from and write to Requester
+// Pays buckets: every request it makes that supports it confirms that the
+// requester, not the bucket owner, is charged for it. Without it S3
+// rejects the requests to such buckets with 403.
+func WithRequestPayer(fsys *S3FS) {
+	fsys.requestPayer = types.RequestPayerRequester
+}
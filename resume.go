@@ -0,0 +1,32 @@
+package s3fs
+
+// maxReadResumes is the number of times in a row a read is resumed with
+// WithResumableReads without making progress.
+const maxReadResumes = 3
+
+// WithResumableReads makes a file whose download fails midway, e.g. because
+// the connection was reset, request the remaining bytes with a ranged
+// GetObject and continue, so the reader sees an uninterrupted stream. A
+// read is resumed up to 3 times in a row without progress before the error
+// is returned.
+//
+// The resumed request is conditional on the ETag of the object, so a
+// changed object fails with ErrFileChanged instead of mixing contents.
+func WithResumableReads(fsys *S3FS) { fsys.resumableReads = true }
+
+// canResume reports whether a failed read of the body can be resumed.
+func (f *file) canResume() bool {
+	return f.fsys.resumableReads && f.resumes < maxReadResumes && f.context().Err() == nil
+}
+
+// resume replaces the failed body of the file with one starting at the
+// current offset.
+func (f *file) resume() error {
+	f.ReadCloser.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return f.openAt(f.offset, stat.Size())
+}
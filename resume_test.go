@@ -0,0 +1,86 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestResumableReads(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	read := func(cl *memClient, opts ...s3fs.Option) ([]byte, error) {
+		f, err := s3fs.New(cl, "test", opts...).Open("data.bin")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	t.Run("resumed", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.bin", content)
+		cl.brokenBodies = 2
+
+		data, err := read(cl, s3fs.WithResumableReads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Errorf("want %d bytes of content; got %d", len(content), len(data))
+		}
+
+		ranges := 0
+		for _, in := range cl.getInputs() {
+			if in.Range != nil {
+				ranges++
+			}
+		}
+		if ranges != 2 {
+			t.Errorf("want 2 resuming GetObjects; got %d", ranges)
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.bin", content)
+		cl.brokenBodies = 100
+
+		if _, err := read(cl, s3fs.WithResumableReads); err == nil {
+			t.Error("expected the read to fail eventually")
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		cl := newMemClient()
+		o := cl.put("data.bin", content)
+		o.etag = `"new"`
+		cl.brokenBodies = 1
+
+		f, err := s3fs.New(cl, "test", s3fs.WithResumableReads).Open("data.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		// the object changes after it was opened.
+		cl.put("data.bin", content)
+		if _, err := io.ReadAll(f); !errors.Is(err, s3fs.ErrFileChanged) {
+			t.Errorf("want %v; got %v", s3fs.ErrFileChanged, err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("data.bin", content)
+		cl.brokenBodies = 1
+
+		if _, err := read(cl); err == nil {
+			t.Error("expected the read to fail")
+		}
+	})
+}
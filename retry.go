@@ -0,0 +1,83 @@
+package s3fs
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// WithRetry makes s3fs retry transient failures on top of the retries of
+// the client, with at most maxAttempts attempts per operation and a
+// jittered exponential backoff starting at baseDelay.
+//
+// The HeadObjects and listings of Stat and the GetObjects of Open,
+// ReadFile and of reading, seeking and resuming files are retried when
+// they fail with a 5xx status, throttling or RequestTimeout; missing
+// objects and denied requests never are. The ranged GetObjects of
+// WithConcurrentDownload are left to manager.Downloader. The backoff stops early when the
+// context of the request is done. Uploads that fail with ErrBadDigest are
+// retried once.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(fsys *S3FS) {
+		if maxAttempts < 1 {
+			maxAttempts = 1
+		}
+		fsys.retry = &retrier{
+			maxAttempts: maxAttempts,
+			baseDelay:   baseDelay,
+		}
+	}
+}
+
+type retrier struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// wait sleeps before the attempt following attempt (starting at 1), or
+// returns ctx's error if ctx is done first.
+func (r *retrier) wait(ctx context.Context, attempt int) error {
+	d := r.baseDelay << (attempt - 1)
+	if d > 0 {
+		// spread the delay over [d/2, 3d/2).
+		d = time.Duration(rand.Int63n(int64(d))) + d/2
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryRead runs the read fn until it succeeds, fails with an error not
+// worth retrying, or was attempted as many times as WithRetry allows. It
+// runs fn once without WithRetry.
+func (f *S3FS) retryRead(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || f.retry == nil || attempt >= f.retry.maxAttempts || !isRetryable(err) {
+			return err
+		}
+		if err := f.retry.wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// isRetryable reports whether a request that failed with err may succeed
+// when it is made again.
+func isRetryable(err error) bool {
+	if isNotFoundErr(err) || isNoSuchBucket(err) || IsPermission(err) {
+		return false
+	}
+	if IsThrottled(err) || errorCode(err) == "RequestTimeout" {
+		return true
+	}
+	return httpStatusCode(err) >= http.StatusInternalServerError
+}
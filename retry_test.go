@@ -0,0 +1,127 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRetryReads(t *testing.T) {
+	// failing makes the first n calls of op fail with err.
+	failing := func(cl *memClient, op string, n int, err error) {
+		cl.hook = func(o string, in interface{}) error {
+			if o == op && n > 0 {
+				n--
+				return err
+			}
+			return nil
+		}
+	}
+
+	for _, f := range []struct {
+		desc string
+		err  error
+	}{
+		{desc: "5xx", err: apiError(http.StatusServiceUnavailable, "ServiceUnavailable")},
+		{desc: "slow down", err: apiError(http.StatusServiceUnavailable, "SlowDown")},
+		{desc: "request timeout", err: apiError(http.StatusBadRequest, "RequestTimeout")},
+	} {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			cl := newMemClient()
+			cl.put("file.txt", []byte("content"))
+			fsys := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+
+			failing(cl, "HeadObject", 2, f.err)
+			if _, err := fsys.Stat("file.txt"); err != nil {
+				t.Fatal(err)
+			}
+			if n := cl.count("HeadObject"); n != 3 {
+				t.Errorf("want 3 HeadObjects; got %d", n)
+			}
+
+			failing(cl, "GetObject", 2, f.err)
+			file, err := fsys.Open("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "content" {
+				t.Errorf("want content; got %q", data)
+			}
+		})
+	}
+
+	t.Run("ReadFile", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		failing(cl, "GetObject", 1, apiError(http.StatusServiceUnavailable, "SlowDown"))
+
+		data, err := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond)).ReadFile("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "content" {
+			t.Errorf("want content; got %q", data)
+		}
+		if n := cl.count("GetObject"); n != 2 {
+			t.Errorf("want 2 GetObjects; got %d", n)
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		for _, err := range []error{
+			apiError(http.StatusForbidden, "AccessDenied"),
+			apiError(http.StatusNotFound, "NoSuchKey"),
+		} {
+			cl := newMemClient()
+			cl.put("file.txt", []byte("content"))
+			failing(cl, "GetObject", 2, err)
+
+			s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond)).ReadFile("file.txt")
+			if n := cl.count("GetObject"); n != 1 {
+				t.Errorf("%v: want a single GetObject; got %d", err, n)
+			}
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		failing(cl, "HeadObject", 10, apiError(http.StatusInternalServerError, "InternalError"))
+
+		if _, err := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond)).Stat("file.txt"); err == nil {
+			t.Error("expected Stat to fail")
+		}
+		if n := cl.count("HeadObject"); n != 3 {
+			t.Errorf("want 3 HeadObjects; got %d", n)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+		failing(cl, "HeadObject", 10, apiError(http.StatusInternalServerError, "InternalError"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := s3fs.New(cl, "test", s3fs.WithRetry(10, time.Second)).StatContext(ctx, "file.txt")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+		if d := time.Since(start); d > time.Second/2 {
+			t.Errorf("want the backoff to stop at the deadline; took %v", d)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ReadDirReverse is like ReadDir, but returns the entries in descending
+// order of their names, e.g. to get date prefixed keys newest first.
+//
+// S3 only lists keys in ascending order, so the whole directory is listed
+// before the first entry is known, whatever the number of entries the
+// caller needs; RecentByKey keeps only the last n objects in memory.
+func (f *S3FS) ReadDirReverse(name string) ([]fs.DirEntry, error) {
+	des, err := f.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(des)-1; i < j; i, j = i+1, j-1 {
+		des[i], des[j] = des[j], des[i]
+	}
+	return des, nil
+}
+
+// RecentByKey returns the n objects with the greatest keys starting with
+// prefix, greatest first. prefix is matched like in ListModifiedSince, and
+// the Name of the returned FileInfo is the object's full key.
+//
+// All the keys below prefix are listed, as S3 only lists in ascending
+// order, but no more than n objects are kept in memory. Directory markers
+// are skipped.
+func (f *S3FS) RecentByKey(prefix string, n int) ([]fs.FileInfo, error) {
+	if n <= 0 {
+		return []fs.FileInfo{}, nil
+	}
+
+	// last holds the last n objects listed, as a ring starting at next.
+	var (
+		last = make([]fs.FileInfo, 0, n)
+		next int
+	)
+	err := f.listKeys(context.TODO(), "list", prefix, func(o types.Object) {
+		if isDirMarker(o) {
+			return
+		}
+
+		fi := keyInfo{
+			fileInfo: fileInfo{
+				name:    aws.ToString(o.Key),
+				size:    o.Size,
+				modTime: derefTime(o.LastModified),
+				eTag:    aws.ToString(o.ETag),
+			},
+		}
+		if len(last) < n {
+			last = append(last, fi)
+			return
+		}
+		last[next] = fi
+		next = (next + 1) % n
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fis := make([]fs.FileInfo, 0, len(last))
+	for i := len(last) - 1; i >= 0; i-- {
+		fis = append(fis, last[(next+i)%len(last)])
+	}
+	return fis, nil
+}
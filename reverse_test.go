@@ -0,0 +1,61 @@
+package s3fs_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestReadDirReverse(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"logs/2021-01-01.log",
+		"logs/2021-01-03.log",
+		"logs/2021-01-02.log",
+		"logs/2020/old.log",
+		"logs/2021-01-04.log",
+		"logs/2021-01-03/",
+	} {
+		cl.put(key, nil)
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	des, err := fsys.ReadDirReverse("logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+	want := []string{"2021-01-04.log", "2021-01-03.log", "2021-01-03", "2021-01-02.log", "2021-01-01.log", "2020"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("want %q; got %q", want, names)
+	}
+
+	t.Run("recent by key", func(t *testing.T) {
+		for _, f := range []struct {
+			n    int
+			want []string
+		}{
+			{n: 2, want: []string{"logs/2021-01-04.log", "logs/2021-01-03.log"}},
+			{n: 10, want: []string{"logs/2021-01-04.log", "logs/2021-01-03.log", "logs/2021-01-02.log", "logs/2021-01-01.log", "logs/2020/old.log"}},
+			{n: 0, want: nil},
+		} {
+			fis, err := fsys.RecentByKey("logs/", f.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var keys []string
+			for _, fi := range fis {
+				keys = append(keys, fi.Name())
+			}
+			if !reflect.DeepEqual(keys, f.want) {
+				t.Errorf("n=%d: want %q; got %q", f.n, f.want, keys)
+			}
+		}
+	})
+}
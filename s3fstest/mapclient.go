@@ -0,0 +1,482 @@
+// Package s3fstest implements an in-memory S3 client, to test code that
+// uses s3fs without a S3 server.
+package s3fstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/matthewp/s3fs"
+)
+
+var _ s3fs.S3Client = (*MapClient)(nil)
+
+// maxKeys is the most keys a ListObjectsV2 page has, as with S3.
+const maxKeys = 1000
+
+// MapClient is an in-memory s3fs.S3Client of a single bucket, whose objects
+// are a map of keys to contents. Requests for any bucket are served from
+// that map.
+//
+// It answers like S3 does, with the same error types and HTTP status
+// codes, e.g. NoSuchKey and 404 for a missing object, so that s3fs handles
+// its errors as those of S3. It supports:
+//
+//   - ListObjectsV2 with Prefix, Delimiter, StartAfter, MaxKeys and
+//     continuation tokens
+//   - HeadObject and GetObject, with Range, IfMatch and IfNoneMatch
+//   - PutObject, DeleteObjects and multipart uploads
+//
+// A MapClient is safe for concurrent use.
+type MapClient struct {
+	mu      sync.Mutex
+	objects map[string]*object
+	uploads map[string]map[int32][]byte
+	// lastUpload numbers the multipart uploads.
+	lastUpload int
+}
+
+type object struct {
+	data         []byte
+	eTag         string
+	lastModified time.Time
+	contentType  *string
+	metadata     map[string]string
+}
+
+// NewMapClient returns a MapClient holding a copy of objects.
+func NewMapClient(objects map[string][]byte) *MapClient {
+	c := &MapClient{
+		objects: make(map[string]*object, len(objects)),
+		uploads: make(map[string]map[int32][]byte),
+	}
+	for key, data := range objects {
+		c.putLocked(key, data)
+	}
+	return c
+}
+
+// Put stores a copy of data under key, replacing the object that has it.
+func (c *MapClient) Put(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(key, data)
+}
+
+func (c *MapClient) putLocked(key string, data []byte) *object {
+	sum := md5.Sum(data)
+	o := &object{
+		data:         append([]byte(nil), data...),
+		eTag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+	c.objects[key] = o
+	return o
+}
+
+// Get returns a copy of the content of the object key, and whether it
+// exists.
+func (c *MapClient) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), o.data...), true
+}
+
+// Keys returns the sorted keys of the objects.
+func (c *MapClient) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.keysLocked()
+}
+
+func (c *MapClient) keysLocked() []string {
+	keys := make([]string, 0, len(c.objects))
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *MapClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := aws.ToString(in.Prefix)
+	delim := aws.ToString(in.Delimiter)
+	after := aws.ToString(in.StartAfter)
+	if in.ContinuationToken != nil {
+		after = *in.ContinuationToken
+	}
+
+	limit := int(in.MaxKeys)
+	if limit <= 0 || limit > maxKeys {
+		limit = maxKeys
+	}
+
+	out := &s3.ListObjectsV2Output{
+		Name:              in.Bucket,
+		Prefix:            in.Prefix,
+		Delimiter:         in.Delimiter,
+		StartAfter:        in.StartAfter,
+		ContinuationToken: in.ContinuationToken,
+		MaxKeys:           int32(limit),
+	}
+
+	// next is the continuation token of the page after the last entry.
+	var last, next string
+	for _, key := range c.keysLocked() {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+
+		entry, isPrefix := key, false
+		if delim != "" {
+			if i := strings.Index(key[len(prefix):], delim); i >= 0 {
+				entry, isPrefix = key[:len(prefix)+i+len(delim)], true
+			}
+		}
+		if isPrefix && entry == last {
+			continue
+		}
+
+		if len(out.Contents)+len(out.CommonPrefixes) == limit {
+			out.IsTruncated = true
+			out.NextContinuationToken = aws.String(next)
+			break
+		}
+
+		last, next = entry, entry
+		if isPrefix {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(entry)})
+			// the keys below the prefix are skipped by the next page.
+			next = entry + "\xff"
+			continue
+		}
+
+		o := c.objects[key]
+		out.Contents = append(out.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         int64(len(o.data)),
+			ETag:         aws.String(o.eTag),
+			LastModified: aws.Time(o.lastModified),
+			StorageClass: types.ObjectStorageClassStandard,
+		})
+	}
+
+	out.KeyCount = int32(len(out.Contents) + len(out.CommonPrefixes))
+	return out, nil
+}
+
+func (c *MapClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[aws.ToString(in.Key)]
+	if !ok {
+		// HEAD responses have no body, so S3 only reports a 404.
+		return nil, responseError(http.StatusNotFound, &types.NotFound{})
+	}
+	if err := checkConditions(o, in.IfMatch, in.IfNoneMatch, true); err != nil {
+		return nil, err
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentLength: int64(len(o.data)),
+		ContentType:   o.contentType,
+		ETag:          aws.String(o.eTag),
+		LastModified:  aws.Time(o.lastModified),
+		Metadata:      o.metadata,
+		AcceptRanges:  aws.String("bytes"),
+	}, nil
+}
+
+func (c *MapClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchKey{})
+	}
+	if err := checkConditions(o, in.IfMatch, in.IfNoneMatch, false); err != nil {
+		return nil, err
+	}
+
+	out := &s3.GetObjectOutput{
+		ContentType:  o.contentType,
+		ETag:         aws.String(o.eTag),
+		LastModified: aws.Time(o.lastModified),
+		Metadata:     o.metadata,
+		AcceptRanges: aws.String("bytes"),
+	}
+
+	data := o.data
+	if in.Range != nil {
+		size := int64(len(data))
+		start, end, err := parseRange(*in.Range, size)
+		if err != nil {
+			return nil, err
+		}
+		data = data[start : end+1]
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+
+	out.ContentLength = int64(len(data))
+	// objects are replaced, never modified, so the body needs no lock.
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	return out, nil
+}
+
+// checkConditions returns the error of a request for o with the
+// conditional headers ifMatch and ifNoneMatch.
+func checkConditions(o *object, ifMatch, ifNoneMatch *string, head bool) error {
+	if ifMatch != nil && *ifMatch != o.eTag && *ifMatch != "*" {
+		if head {
+			return responseError(http.StatusPreconditionFailed, nil)
+		}
+		return apiError(http.StatusPreconditionFailed, "PreconditionFailed")
+	}
+	if ifNoneMatch != nil && (*ifNoneMatch == o.eTag || *ifNoneMatch == "*") {
+		// 304 responses have no body.
+		return responseError(http.StatusNotModified, nil)
+	}
+	return nil
+}
+
+func (c *MapClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if in.Body != nil {
+		var err error
+		if data, err = io.ReadAll(in.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := c.putLocked(aws.ToString(in.Key), data)
+	o.contentType = in.ContentType
+	o.metadata = in.Metadata
+	return &s3.PutObjectOutput{ETag: aws.String(o.eTag)}, nil
+}
+
+func (c *MapClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if in.Delete == nil || len(in.Delete.Objects) == 0 {
+		return nil, apiError(http.StatusBadRequest, "MalformedXML")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := &s3.DeleteObjectsOutput{}
+	for _, id := range in.Delete.Objects {
+		// deleting a missing key succeeds, as with S3.
+		delete(c.objects, aws.ToString(id.Key))
+		if !in.Delete.Quiet {
+			out.Deleted = append(out.Deleted, types.DeletedObject{Key: id.Key})
+		}
+	}
+	return out, nil
+}
+
+func (c *MapClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (c *MapClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastUpload++
+	id := strconv.Itoa(c.lastUpload)
+	c.uploads[id] = make(map[int32][]byte)
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   in.Bucket,
+		Key:      in.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+func (c *MapClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if in.Body != nil {
+		var err error
+		if data, err = io.ReadAll(in.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parts, ok := c.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+	parts[in.PartNumber] = data
+
+	sum := md5.Sum(data)
+	return &s3.UploadPartOutput{ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`)}, nil
+}
+
+func (c *MapClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := aws.ToString(in.UploadId)
+	parts, ok := c.uploads[id]
+	if !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+
+	var data []byte
+	if in.MultipartUpload != nil {
+		for _, p := range in.MultipartUpload.Parts {
+			part, ok := parts[p.PartNumber]
+			if !ok {
+				return nil, apiError(http.StatusBadRequest, "InvalidPart")
+			}
+			data = append(data, part...)
+		}
+	}
+	delete(c.uploads, id)
+
+	o := c.putLocked(aws.ToString(in.Key), data)
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket: in.Bucket,
+		Key:    in.Key,
+		ETag:   aws.String(o.eTag),
+	}, nil
+}
+
+func (c *MapClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := aws.ToString(in.UploadId)
+	if _, ok := c.uploads[id]; !ok {
+		return nil, responseError(http.StatusNotFound, &types.NoSuchUpload{})
+	}
+	delete(c.uploads, id)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// parseRange parses a range header against an object of size bytes and
+// returns the first and last bytes of the range.
+func parseRange(r string, size int64) (start, end int64, err error) {
+	invalid := apiError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+
+	spec := strings.TrimPrefix(r, "bytes=")
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok || spec == r {
+		return 0, 0, invalid
+	}
+
+	switch {
+	case first == "":
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, invalid
+		}
+		start, end = size-n, size-1
+		if start < 0 {
+			start = 0
+		}
+	default:
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return 0, 0, invalid
+		}
+		end = size - 1
+		if last != "" {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+				return 0, 0, invalid
+			}
+			if end > size-1 {
+				end = size - 1
+			}
+		}
+	}
+
+	if start >= size || start > end {
+		return 0, 0, invalid
+	}
+	return start, end, nil
+}
+
+// responseError wraps err the way the SDK does for failed responses.
+func responseError(status int, err error) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      err,
+		},
+	}
+}
+
+// apiError returns the error of a response with status whose body has the
+// error code.
+func apiError(status int, code string) error {
+	return responseError(status, &smithy.GenericAPIError{Code: code, Message: code})
+}
@@ -0,0 +1,111 @@
+package s3fstest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matthewp/s3fs"
+	"github.com/matthewp/s3fs/s3fstest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func newClient() *s3fstest.MapClient {
+	return s3fstest.NewMapClient(map[string][]byte{
+		"a.txt":         []byte("a"),
+		"dir/b.txt":     []byte("b"),
+		"dir/sub/c.txt": []byte("c"),
+		"dir/sub/d.txt": []byte("d"),
+		"dir-e.txt":     []byte("e"),
+	})
+}
+
+func TestMapClientFS(t *testing.T) {
+	for _, opts := range [][]s3fs.Option{nil, {s3fs.WithMaxKeys(1)}} {
+		fsys := s3fs.New(newClient(), "test", opts...)
+		if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d.txt", "dir-e.txt"); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestMapClientNotFound(t *testing.T) {
+	cl := newClient()
+	fsys := s3fs.New(cl, "test")
+
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("open: want %v; got %v", fs.ErrNotExist, err)
+	}
+	if _, err := fsys.Stat("dir/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat: want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	_, err := cl.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("missing.txt")})
+	var nsk *types.NoSuchKey
+	if !errors.As(err, &nsk) {
+		t.Errorf("GetObject: want NoSuchKey; got %v", err)
+	}
+
+	_, err = cl.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("test"), Key: aws.String("missing.txt")})
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusNotFound {
+		t.Errorf("HeadObject: want a 404; got %v", err)
+	}
+}
+
+func TestMapClientRange(t *testing.T) {
+	cl := s3fstest.NewMapClient(map[string][]byte{"file": []byte("0123456789")})
+
+	for r, want := range map[string]string{
+		"bytes=2-4":   "234",
+		"bytes=7-":    "789",
+		"bytes=8-100": "89",
+		"bytes=-3":    "789",
+	} {
+		out, err := cl.GetObject(context.Background(), &s3.GetObjectInput{Key: aws.String("file"), Range: aws.String(r)})
+		if err != nil {
+			t.Fatalf("%s: %v", r, err)
+		}
+		data, err := io.ReadAll(out.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want || out.ContentLength != int64(len(want)) || out.ContentRange == nil {
+			t.Errorf("%s: want %q; got %q of length %d", r, want, data, out.ContentLength)
+		}
+	}
+
+	_, err := cl.GetObject(context.Background(), &s3.GetObjectInput{Key: aws.String("file"), Range: aws.String("bytes=10-")})
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("want a 416; got %v", err)
+	}
+}
+
+func TestMapClientWrite(t *testing.T) {
+	cl := newClient()
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.WriteFile("new.txt", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := cl.Get("new.txt"); !ok || string(data) != "new" {
+		t.Errorf("want new.txt to be written; got %q", data)
+	}
+
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt", "dir-e.txt", "new.txt"}; !reflect.DeepEqual(cl.Keys(), want) {
+		t.Errorf("want keys %v; got %v", want, cl.Keys())
+	}
+}
@@ -0,0 +1,208 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// NewSnapshot returns a new filesystem for bucket whose directory structure
+// is frozen: all the keys below prefix are listed once upfront and Stat,
+// ReadDir and Open resolve names against that listing only. The content of
+// objects is still read from S3. This keeps long running batch jobs from
+// seeing the bucket change under them.
+//
+// Objects added after the snapshot are invisible, and objects deleted since
+// keep showing up but fail to open. Keys outside of prefix are not part of
+// the snapshot; an empty prefix snapshots the whole bucket.
+func NewSnapshot(cl S3Client, bucket, prefix string, opts ...Option) (*S3FS, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" && !fs.ValidPath(prefix) {
+		return nil, &fs.PathError{
+			Op:   "snapshot",
+			Path: prefix,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	fsys := New(cl, bucket, opts...)
+	snap, err := fsys.takeSnapshot(context.TODO(), prefix)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "snapshot",
+			Path: prefix,
+			Err:  err,
+		}
+	}
+	fsys.snapshot = snap
+	return fsys, nil
+}
+
+// snapshot is a frozen listing of a bucket.
+type snapshot struct {
+	files map[string]*fileInfo
+	dirs  map[string][]fs.DirEntry
+}
+
+func (f *S3FS) takeSnapshot(ctx context.Context, prefix string) (*snapshot, error) {
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	s := &snapshot{files: make(map[string]*fileInfo)}
+	children := map[string]map[string]fs.DirEntry{".": {}}
+
+	// addDir adds the directory name along with its parents.
+	var addDir func(name string)
+	addDir = func(name string) {
+		if _, ok := children[name]; ok || name == "." {
+			return
+		}
+		children[name] = make(map[string]fs.DirEntry)
+
+		parent := path.Dir(name)
+		addDir(parent)
+		children[parent][path.Base(name)+"/"] = dirEntry{
+			fileInfo: fileInfo{
+				name: path.Base(name),
+				mode: fs.ModeDir,
+				key:  name,
+			},
+		}
+	}
+
+	var (
+		token *string
+		last  time.Time
+	)
+	for {
+		if err := f.paceList(ctx, last); err != nil {
+			return nil, err
+		}
+		last = time.Now()
+
+		out, err := f.cl.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &f.bucket,
+			RequestPayer:      f.requestPayer,
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+		}, f.optFns...)
+		if err != nil {
+			return nil, err
+		}
+		f.auditList(prefix, out)
+
+		for _, o := range out.Contents {
+			key := aws.ToString(o.Key)
+
+			// directory markers only make their directory exist.
+			if strings.HasSuffix(key, "/") {
+				if name := strings.TrimSuffix(key, "/"); fs.ValidPath(name) {
+					addDir(name)
+				}
+				continue
+			}
+			if !fs.ValidPath(key) {
+				continue
+			}
+
+			fi := &fileInfo{
+				name:    key,
+				size:    o.Size,
+				modTime: derefTime(o.LastModified),
+				eTag:    aws.ToString(o.ETag),
+			}
+			s.files[key] = fi
+
+			parent := path.Dir(key)
+			addDir(parent)
+			children[parent][path.Base(key)] = dirEntry{fileInfo: *fi}
+		}
+
+		if !out.IsTruncated {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+
+	s.dirs = make(map[string][]fs.DirEntry, len(children))
+	for name, entries := range children {
+		des := make([]fs.DirEntry, 0, len(entries))
+		for _, de := range entries {
+			des = append(des, de)
+		}
+		sort.Slice(des, func(i, j int) bool {
+			return des[i].Name() < des[j].Name()
+		})
+		s.dirs[name] = des
+	}
+	return s, nil
+}
+
+// stat returns the FileInfo of name as of the snapshot. Directories are
+// returned as fs.ReadDirFile.
+func (s *snapshot) stat(name string) (fs.FileInfo, error) {
+	if _, ok := s.dirs[name]; ok {
+		return s.dir(name), nil
+	}
+	if fi, ok := s.files[name]; ok {
+		return fi, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (s *snapshot) dir(name string) *snapshotDir {
+	return &snapshotDir{
+		fileInfo: fileInfo{
+			name: name,
+			mode: fs.ModeDir,
+		},
+		entries: s.dirs[name],
+	}
+}
+
+var _ fs.ReadDirFile = (*snapshotDir)(nil)
+
+// snapshotDir is a directory read from a snapshot, or another directory
+// whose entries are all known upfront.
+type snapshotDir struct {
+	fileInfo
+	entries []fs.DirEntry
+}
+
+func (d *snapshotDir) Stat() (fs.FileInfo, error) {
+	return &d.fileInfo, nil
+}
+
+func (d *snapshotDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{
+		Op:   "read",
+		Path: d.name,
+		Err:  ErrIsDir,
+	}
+}
+
+func (d *snapshotDir) Close() error {
+	return nil
+}
+
+func (d *snapshotDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 || n > len(d.entries) {
+		if n > 0 && len(d.entries) == 0 {
+			return []fs.DirEntry{}, io.EOF
+		}
+		n = len(d.entries)
+	}
+
+	des := make([]fs.DirEntry, n)
+	copy(des, d.entries)
+	d.entries = d.entries[n:]
+	return des, nil
+}
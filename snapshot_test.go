@@ -0,0 +1,82 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"data/a.txt",
+		"data/sub/b.txt",
+		"data/empty/",
+		"other/c.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys, err := s3fs.NewSnapshot(cl, "test", "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl.put("data/late.txt", []byte("late"))
+
+	names := func(t *testing.T, name string) (out []string) {
+		t.Helper()
+
+		des, err := fsys.ReadDir(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, de := range des {
+			out = append(out, de.Name())
+		}
+		return out
+	}
+
+	if got, want := names(t, "data"), []string{"a.txt", "empty", "sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+	if got, want := names(t, "."), []string{"data"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	for _, name := range []string{"data/late.txt", "other/c.txt"} {
+		if _, err := fsys.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: want %v; got %v", name, fs.ErrNotExist, err)
+		}
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: want %v; got %v", name, fs.ErrNotExist, err)
+		}
+	}
+
+	f, err := fsys.Open("data/sub/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	if n := cl.count("ListObjectsV2"); n != 1 {
+		t.Errorf("want 1 ListObjectsV2; got %d", n)
+	}
+
+	if err := fstest.TestFS(fsys, "data/a.txt", "data/sub/b.txt"); err != nil {
+		t.Error(err)
+	}
+}
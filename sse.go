@@ -0,0 +1,62 @@
+package s3fs
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithSSECustomerKey makes the filesystem read objects encrypted on the
+// server with a customer-provided key (SSE-C): every GetObject and
+// HeadObject it makes, including those of Stat and of listings with
+// WithListPrefetchMetadata, carries the algorithm, usually "AES256", the
+// key and its MD5. S3 rejects these requests with 400 without the key.
+//
+// Writes and copies are not encrypted with the key. It panics if key is
+// not 256 bits long.
+func WithSSECustomerKey(algorithm string, key []byte) Option {
+	if len(key) != 32 {
+		panic("s3fs: SSE-C key of " + strconv.Itoa(len(key)) + " bytes, want 32")
+	}
+
+	sum := md5.Sum(key)
+	sse := &sseCustomerKey{
+		algorithm: algorithm,
+		key:       base64.StdEncoding.EncodeToString(key),
+		keyMD5:    base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	return func(fsys *S3FS) { fsys.sseCustomerKey = sse }
+}
+
+// sseCustomerKey is an SSE-C key, encoded as S3 expects it.
+type sseCustomerKey struct {
+	algorithm string
+	key       string
+	keyMD5    string
+}
+
+// getInput sets the request payer and the SSE-C key, if any, of in and
+// returns it.
+func (f *S3FS) getInput(in *s3.GetObjectInput) *s3.GetObjectInput {
+	in.RequestPayer = f.requestPayer
+	if k := f.sseCustomerKey; k != nil {
+		in.SSECustomerAlgorithm = &k.algorithm
+		in.SSECustomerKey = &k.key
+		in.SSECustomerKeyMD5 = &k.keyMD5
+	}
+	return in
+}
+
+// headInput sets the request payer and the SSE-C key, if any, of in and
+// returns it.
+func (f *S3FS) headInput(in *s3.HeadObjectInput) *s3.HeadObjectInput {
+	in.RequestPayer = f.requestPayer
+	if k := f.sseCustomerKey; k != nil {
+		in.SSECustomerAlgorithm = &k.algorithm
+		in.SSECustomerKey = &k.key
+		in.SSECustomerKeyMD5 = &k.keyMD5
+	}
+	return in
+}
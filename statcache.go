@@ -0,0 +1,186 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithStatCache caches the FileInfo of objects returned by Stat for ttl,
+// saving the HeadObject of repeated Stats of the same object. Directories
+// are cached as existing too, which MkdirAll relies on to skip the ones it
+// already saw. Objects written or removed through the filesystem are
+// removed from the cache, along with their parent directories.
+func WithStatCache(ttl time.Duration) Option {
+	return func(fsys *S3FS) {
+		if fsys.statCache == nil {
+			fsys.statCache = &statCache{}
+		}
+		fsys.statCache.ttl = ttl
+	}
+}
+
+// WithStatCacheNegativeTTL makes Stat also cache, for ttl, that a name
+// neither is an object nor a directory, saving the HeadObject and
+// ListObjectsV2 of repeated Stats of missing names. Keep ttl short: objects
+// written by others are only seen once it passed, while writes through the
+// filesystem invalidate the name and its parent directories right away. It
+// has no effect without WithStatCache.
+func WithStatCacheNegativeTTL(ttl time.Duration) Option {
+	return func(fsys *S3FS) {
+		if fsys.statCache == nil {
+			fsys.statCache = &statCache{}
+		}
+		fsys.statCache.negativeTTL = ttl
+	}
+}
+
+// InvalidateStat removes name from the stat cache, e.g. after it was
+// written by another client. It is a no-op without WithStatCache.
+func (f *S3FS) InvalidateStat(name string) {
+	if f.statCache != nil {
+		f.statCache.invalidate(name)
+	}
+}
+
+// ClearStatCache empties the stat cache.
+func (f *S3FS) ClearStatCache() {
+	if f.statCache != nil {
+		f.statCache.clear()
+	}
+}
+
+// WithStatCacheETagCheck makes cached FileInfo be confirmed with a HeadObject
+// before it is used. If the ETag did not change, the cached FileInfo,
+// including what Sys returns, is reused; otherwise it is replaced. Stat then
+// detects changed objects while still reusing what was assembled for them.
+// It has no effect without WithStatCache.
+func WithStatCacheETagCheck(fsys *S3FS) {
+	if fsys.statCache == nil {
+		fsys.statCache = &statCache{}
+	}
+	fsys.statCache.checkETag = true
+}
+
+type statEntry struct {
+	// fi is nil if the name does not exist.
+	fi      *fileInfo
+	expires time.Time
+}
+
+// statCache caches the FileInfo of objects by name.
+type statCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	checkETag   bool
+
+	mu      sync.Mutex
+	entries map[string]statEntry
+}
+
+func (c *statCache) get(name string) (*fileInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, name)
+		return nil, false
+	}
+	return e.fi, true
+}
+
+func (c *statCache) put(name string, fi *fileInfo) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]statEntry)
+	}
+	c.entries[name] = statEntry{
+		fi:      fi,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// putMissing caches that name does not exist.
+func (c *statCache) putMissing(name string) {
+	if c.ttl <= 0 || c.negativeTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]statEntry)
+	}
+	c.entries[name] = statEntry{expires: time.Now().Add(c.negativeTTL)}
+}
+
+// putDir caches that the directory name exists.
+func (c *statCache) putDir(name string) {
+	c.put(name, &fileInfo{
+		name: name,
+		mode: fs.ModeDir,
+	})
+}
+
+// invalidate removes name from the cache, and its parent directories if
+// they are cached as missing or as directories: writing name creates them,
+// and removing it can remove them.
+func (c *statCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, name)
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if e, ok := c.entries[dir]; ok && (e.fi == nil || e.fi.IsDir()) {
+			delete(c.entries, dir)
+		}
+	}
+}
+
+func (c *statCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// cachedStat returns the cached FileInfo of the object name, which is nil
+// with ok true if name is cached as missing. With WithStatCacheETagCheck
+// the FileInfo is confirmed or refreshed with a HeadObject first; if that
+// fails the entry is dropped and ok is false.
+func (f *S3FS) cachedStat(ctx context.Context, name string) (fi *fileInfo, ok bool) {
+	fi, ok = f.statCache.get(name)
+	if !ok || fi == nil || fi.IsDir() || !f.statCache.checkETag {
+		return fi, ok
+	}
+
+	head, err := f.cl.HeadObject(ctx, f.headInput(&s3.HeadObjectInput{
+		Bucket: &f.bucket,
+		Key:    aws.String(name),
+	}), f.optFns...)
+	if err != nil {
+		f.statCache.invalidate(name)
+		return nil, false
+	}
+
+	if aws.ToString(head.ETag) != fi.eTag {
+		fi = headFileInfo(name, head)
+		f.statCache.put(name, fi)
+	}
+	return fi, true
+}
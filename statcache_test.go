@@ -0,0 +1,174 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestStatCache(t *testing.T) {
+	cacheControl := func(t *testing.T, fsys *s3fs.S3FS) string {
+		t.Helper()
+
+		fi, err := fsys.Stat("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := fi.Sys().(*s3fs.ObjectInfo)
+		if info == nil {
+			t.Fatal("expected Sys to be *s3fs.ObjectInfo")
+		}
+		return info.CacheControl
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("v1")).cacheControl = "v1"
+
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour))
+		cacheControl(t, fsys)
+
+		cl.put("file.txt", []byte("v2")).cacheControl = "v2"
+		if got := cacheControl(t, fsys); got != "v1" {
+			t.Errorf("want the cached v1; got %s", got)
+		}
+		if n := cl.count("HeadObject"); n != 1 {
+			t.Errorf("want 1 HeadObject; got %d", n)
+		}
+
+		if err := fsys.WriteFile("file.txt", []byte("v3")); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := fsys.Stat("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, _ := fi.Sys().(*s3fs.ObjectInfo); fi.Size() != 2 || info == nil || info.CacheControl != "" {
+			t.Errorf("want the written object; got size %d and %#v", fi.Size(), fi.Sys())
+		}
+	})
+
+	t.Run("etag check", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("v1")).cacheControl = "v1"
+
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour), s3fs.WithStatCacheETagCheck)
+		cacheControl(t, fsys)
+
+		// the same ETag reuses the cached FileInfo.
+		cl.objects["file.txt"].cacheControl = "changed without new etag"
+		if got := cacheControl(t, fsys); got != "v1" {
+			t.Errorf("want the cached v1; got %s", got)
+		}
+
+		cl.put("file.txt", []byte("v2")).cacheControl = "v2"
+		if got := cacheControl(t, fsys); got != "v2" {
+			t.Errorf("want the refreshed v2; got %s", got)
+		}
+		if n := cl.count("HeadObject"); n != 3 {
+			t.Errorf("want 3 HeadObjects; got %d", n)
+		}
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour), s3fs.WithStatCacheNegativeTTL(time.Hour))
+
+		for i := 0; i < 3; i++ {
+			for _, name := range []string{"missing.txt", "dir"} {
+				if _, err := fsys.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("want %v; got %v", fs.ErrNotExist, err)
+				}
+			}
+		}
+		if n := cl.count("HeadObject") + cl.count("ListObjectsV2"); n != 4 {
+			t.Errorf("want 4 requests; got %d", n)
+		}
+
+		// writes through the filesystem are seen right away, also by
+		// their parent directories.
+		if err := fsys.WriteFile("dir/missing.txt", nil); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := fsys.Stat("dir"); err != nil || !fi.IsDir() {
+			t.Errorf("want dir to be a directory; got %v", err)
+		}
+
+		// others' writes are seen once invalidated.
+		cl.put("missing.txt", []byte("content"))
+		if _, err := fsys.Stat("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want the cached %v; got %v", fs.ErrNotExist, err)
+		}
+		fsys.InvalidateStat("missing.txt")
+		if _, err := fsys.Stat("missing.txt"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("v1"))
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour))
+
+		fsys.Stat("file.txt")
+		fsys.ClearStatCache()
+		cl.put("file.txt", []byte("v2 is longer"))
+
+		fi, err := fsys.Stat("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != int64(len("v2 is longer")) {
+			t.Errorf("want the new size; got %d", fi.Size())
+		}
+	})
+
+	t.Run("no negative ttl", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour))
+
+		fsys.Stat("file.txt")
+		cl.put("file.txt", nil)
+		if _, err := fsys.Stat("file.txt"); err != nil {
+			t.Errorf("expected missing objects not to be cached; got %v", err)
+		}
+	})
+	t.Run("dir", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("dir/file.txt", []byte("data"))
+
+		fsys := s3fs.New(cl, "test", s3fs.WithStatCache(time.Hour))
+		if fi, err := fsys.Stat("dir"); err != nil || !fi.IsDir() {
+			t.Fatalf("want a dir; got %v, %v", fi, err)
+		}
+
+		des, err := fsys.ReadDir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(des) != 1 || des[0].Name() != "file.txt" {
+			t.Errorf("want file.txt; got %v", des)
+		}
+
+		f, err := fsys.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		var names []string
+		err = fs.WalkDir(fsys, "dir", func(name string, _ fs.DirEntry, err error) error {
+			names = append(names, name)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 2 || names[1] != "dir/file.txt" {
+			t.Errorf("want dir and dir/file.txt; got %v", names)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package s3fs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+var (
+	_ fs.SubFS = (*S3FS)(nil)
+
+	_ fs.StatFS     = (*subFS)(nil)
+	_ fs.ReadDirFS  = (*subFS)(nil)
+	_ fs.ReadFileFS = (*subFS)(nil)
+	_ fs.GlobFS     = (*subFS)(nil)
+	_ fs.SubFS      = (*subFS)(nil)
+)
+
+// Sub implements fs.SubFS. The returned filesystem is rooted at the
+// directory dir: every name is prefixed with dir before it is looked up,
+// and the paths of its errors are relative to dir again. Unlike the one
+// returned by fs.Sub it implements fs.StatFS, so Stat needs no Open.
+func (f *S3FS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{
+			Op:   "sub",
+			Path: dir,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if dir == "." {
+		return f, nil
+	}
+	return &subFS{fsys: f, dir: dir}, nil
+}
+
+// subFS is a S3FS rooted at the directory dir.
+type subFS struct {
+	fsys *S3FS
+	dir  string
+}
+
+// fullName returns the name of name in the parent filesystem.
+func (f *subFS) fullName(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{
+			Op:   op,
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if name == "." {
+		return f.dir, nil
+	}
+	return f.dir + "/" + name, nil
+}
+
+// shortName returns the name of name of the parent filesystem in f.
+func (f *subFS) shortName(name string) (string, bool) {
+	if name == f.dir {
+		return ".", true
+	}
+	if strings.HasPrefix(name, f.dir+"/") {
+		return name[len(f.dir)+1:], true
+	}
+	return "", false
+}
+
+// fixErr makes the path of a *fs.PathError relative to f.
+func (f *subFS) fixErr(err error) error {
+	var perr *fs.PathError
+	if errors.As(err, &perr) {
+		if short, ok := f.shortName(perr.Path); ok {
+			perr.Path = short
+		}
+	}
+	return err
+}
+
+func (f *subFS) Open(name string) (fs.File, error) {
+	full, err := f.fullName("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := f.fsys.Open(full)
+	return file, f.fixErr(err)
+}
+
+func (f *subFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := f.fullName("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.fsys.Stat(full)
+	return fi, f.fixErr(err)
+}
+
+func (f *subFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := f.fullName("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	des, err := f.fsys.ReadDir(full)
+	return des, f.fixErr(err)
+}
+
+func (f *subFS) ReadFile(name string) ([]byte, error) {
+	full, err := f.fullName("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.fsys.ReadFile(full)
+	return data, f.fixErr(err)
+}
+
+func (f *subFS) Glob(pattern string) ([]string, error) {
+	// check the pattern is well-formed.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if pattern == "." {
+		return []string{"."}, nil
+	}
+
+	matches, err := f.fsys.Glob(f.dir + "/" + pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range matches {
+		matches[i], _ = f.shortName(m)
+	}
+	return matches, nil
+}
+
+func (f *subFS) Sub(dir string) (fs.FS, error) {
+	if dir == "." {
+		return f, nil
+	}
+
+	full, err := f.fullName("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &subFS{fsys: f.fsys, dir: full}, nil
+}
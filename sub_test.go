@@ -0,0 +1,88 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestSub(t *testing.T) {
+	cl := newMemClient()
+	cl.put("dir1/a.txt", []byte("a"))
+	cl.put("dir1/dir11/b.txt", []byte("bb"))
+	cl.put("other.txt", []byte("other"))
+
+	fsys, err := s3fs.New(cl, "test").Sub("dir1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fstest.TestFS(fsys, "a.txt", "dir11/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("stat", func(t *testing.T) {
+		fi, err := fs.Stat(fsys, "dir11/b.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Name() != "b.txt" || fi.Size() != 2 {
+			t.Errorf("want b.txt of size 2; got %s of size %d", fi.Name(), fi.Size())
+		}
+	})
+
+	t.Run("readdir", func(t *testing.T) {
+		des, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		if want := []string{"a.txt", "dir11"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("want %q; got %q", want, names)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		sub, err := fs.Sub(fsys, "dir11")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := fs.ReadFile(sub, "b.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "bb" {
+			t.Errorf("want bb; got %q", data)
+		}
+	})
+
+	t.Run("error path", func(t *testing.T) {
+		for _, fn := range []func() error{
+			func() error { _, err := fs.Stat(fsys, "not-exist"); return err },
+			func() error { _, err := fsys.Open("not-exist"); return err },
+		} {
+			var perr *fs.PathError
+			if err := fn(); !errors.As(err, &perr) || !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("want not exist PathError; got %v", err)
+			}
+			if perr.Path != "not-exist" {
+				t.Errorf("want path not-exist; got %s", perr.Path)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := s3fs.New(cl, "test").Sub("../dir1"); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want ErrInvalid; got %v", err)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// taggingAPIClient is implemented by clients that can read object tags.
+// *s3.Client implements it.
+type taggingAPIClient interface {
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+}
+
+var _ taggingAPIClient = (*s3.Client)(nil)
+
+// WithListTags makes directory listings fetch the tags of every listed
+// object, with at most concurrency requests in flight, so that the
+// *ObjectInfo returned by Sys on the entries' FileInfo carries them.
+//
+// This costs a GetObjectTagging per listed object, which is billed as a
+// request and slows listings down considerably; only use it when the tags
+// of most entries are needed. The client must implement GetObjectTagging,
+// as *s3.Client does. A concurrency below 1 means 1.
+func WithListTags(concurrency int) Option {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(fsys *S3FS) { fsys.listTagsConcurrency = concurrency }
+}
+
+// fetchTags fills the tags of des, whose keys are keys, with concurrent
+// GetObjectTaggings.
+func (d *dir) fetchTags(des []fs.DirEntry, keys []string) error {
+	cl, ok := d.fsys.cl.(taggingAPIClient)
+	if !ok {
+		return fmt.Errorf("s3fs: %T does not implement GetObjectTagging", d.fsys.cl)
+	}
+
+	return d.updateEntries(des, keys, d.fsys.listTagsConcurrency, func(key string, info *ObjectInfo) error {
+		out, err := cl.GetObjectTagging(d.context(), &s3.GetObjectTaggingInput{
+			Bucket:       &d.fsys.bucket,
+			RequestPayer: d.fsys.requestPayer,
+			Key:          aws.String(key),
+		}, d.fsys.optFns...)
+		if err != nil {
+			return err
+		}
+
+		info.Tags = make(map[string]string, len(out.TagSet))
+		for _, tag := range out.TagSet {
+			info.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package s3fs_test
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestListTags(t *testing.T) {
+	cl := newMemClient()
+	cl.put("docs/a.txt", []byte("a")).tags = map[string]string{"team": "search", "tier": "hot"}
+	cl.put("docs/b.txt", []byte("b"))
+	cl.put("docs/sub/c.txt", []byte("c")).tags = map[string]string{"team": "ads"}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithListTags(2))
+
+	des, err := fs.ReadDir(fsys, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]string{
+		"a.txt": {"team": "search", "tier": "hot"},
+		"b.txt": {},
+	}
+	for _, de := range des {
+		if de.IsDir() {
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, ok := fi.Sys().(*s3fs.ObjectInfo)
+		if !ok {
+			t.Fatalf("%s: want *ObjectInfo; got %T", de.Name(), fi.Sys())
+		}
+		if !reflect.DeepEqual(info.Tags, want[de.Name()]) {
+			t.Errorf("%s: want tags %v; got %v", de.Name(), want[de.Name()], info.Tags)
+		}
+	}
+
+	// directories have no tags to fetch.
+	if n := cl.count("GetObjectTagging"); n != 2 {
+		t.Errorf("want 2 GetObjectTagging calls; got %d", n)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		des, err := fs.ReadDir(s3fs.New(cl, "test"), "docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fi, err := des[0].Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, ok := fi.Sys().(*s3fs.ObjectInfo); ok && info.Tags != nil {
+			t.Errorf("want no tags; got %v", info.Tags)
+		}
+	})
+}
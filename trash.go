@@ -0,0 +1,199 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// errNoTrash is returned by Restore and EmptyTrash when WithTrashPrefix is
+// not used.
+var errNoTrash = errors.New("s3fs: no trash, see WithTrashPrefix")
+
+// copyAPIClient is implemented by clients that can copy objects.
+// *s3.Client implements it.
+type copyAPIClient interface {
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+var _ copyAPIClient = (*s3.Client)(nil)
+
+// WithTrashPrefix makes Remove, RemoveAll and RemoveIfMatch move objects to
+// the trash prefix instead of deleting them for good: every object is
+// copied to prefix + "/" + its name before it is deleted. Restore moves
+// them back and EmptyTrash deletes them.
+//
+// The client must implement CopyObject, as *s3.Client does, and objects
+// larger than 5 GiB cannot be trashed since CopyObject does not copy them.
+// Objects already in the trash are deleted right away. It panics if
+// prefix is not a valid path.
+func WithTrashPrefix(prefix string) Option {
+	prefix = strings.Trim(prefix, "/")
+	if !fs.ValidPath(prefix) || prefix == "." {
+		panic("s3fs: invalid trash prefix " + strconv.Quote(prefix))
+	}
+	return func(fsys *S3FS) { fsys.trashPrefix = prefix }
+}
+
+// Remove deletes the object name, or moves it to the trash if
+// WithTrashPrefix is used. Directories are removed with RemoveAll.
+func (f *S3FS) Remove(name string) error {
+	if _, err := f.headObject(context.TODO(), name); err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if err := f.removeKeys(context.TODO(), []string{name}); err != nil {
+		return &fs.PathError{
+			Op:   "remove",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if f.statCache != nil {
+		f.statCache.invalidate(name)
+	}
+	return nil
+}
+
+// Restore moves name, and everything under name if it is a directory, back
+// from the trash, replacing objects that were written since. It returns
+// fs.ErrNotExist if name is not in the trash.
+func (f *S3FS) Restore(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if f.trashPrefix == "" {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  errNoTrash,
+		}
+	}
+
+	trashed := f.trashKey(name)
+	var keys []string
+	err := f.listKeys(context.TODO(), "restore", trashed, func(o types.Object) {
+		if key := aws.ToString(o.Key); key == trashed || strings.HasPrefix(key, trashed+"/") {
+			keys = append(keys, key)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  fs.ErrNotExist,
+		}
+	}
+
+	for _, key := range keys {
+		orig := strings.TrimPrefix(key, f.trashPrefix+"/")
+		if err := f.copyObject(context.TODO(), key, orig); err != nil {
+			return &fs.PathError{
+				Op:   "restore",
+				Path: name,
+				Err:  err,
+			}
+		}
+
+		if f.statCache != nil {
+			f.statCache.invalidate(strings.TrimSuffix(orig, "/"))
+		}
+	}
+
+	if err := f.deleteKeys(context.TODO(), keys); err != nil {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// EmptyTrash deletes everything in the trash for good.
+func (f *S3FS) EmptyTrash() error {
+	if f.trashPrefix == "" {
+		return &fs.PathError{
+			Op:   "emptytrash",
+			Path: f.trashPrefix,
+			Err:  errNoTrash,
+		}
+	}
+
+	var keys []string
+	err := f.listKeys(context.TODO(), "emptytrash", f.trashPrefix+"/", func(o types.Object) {
+		keys = append(keys, aws.ToString(o.Key))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := f.deleteKeys(context.TODO(), keys); err != nil {
+		return &fs.PathError{
+			Op:   "emptytrash",
+			Path: f.trashPrefix,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// removeKeys deletes keys, moving them to the trash first if
+// WithTrashPrefix is used. Keys that do not exist are skipped.
+func (f *S3FS) removeKeys(ctx context.Context, keys []string) error {
+	if f.trashPrefix != "" {
+		for _, key := range keys {
+			if key == f.trashPrefix || strings.HasPrefix(key, f.trashPrefix+"/") {
+				continue
+			}
+
+			err := f.copyObject(ctx, key, f.trashKey(key))
+			if err != nil && !isNotFoundErr(err) {
+				return fmt.Errorf("s3fs: move %s to trash: %w", key, err)
+			}
+		}
+	}
+	return f.deleteKeys(ctx, keys)
+}
+
+// trashKey returns the key of name in the trash.
+func (f *S3FS) trashKey(name string) string { return f.trashPrefix + "/" + name }
+
+// copyObject copies the object src to dst.
+func (f *S3FS) copyObject(ctx context.Context, src, dst string) error {
+	cl, ok := f.cl.(copyAPIClient)
+	if !ok {
+		return fmt.Errorf("s3fs: %T does not implement CopyObject", f.cl)
+	}
+
+	_, err := cl.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:               &f.bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(dst),
+		CopySource:           aws.String(copySource(f.bucket, src, "")),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, f.optFns...)
+	return err
+}
@@ -0,0 +1,92 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestTrash(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("file"))
+	cl.put("dir/a.txt", []byte("a"))
+	cl.put("dir/sub/b.txt", []byte("b"))
+
+	keys := func() []string {
+		var keys []string
+		for key := range cl.objects {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	fsys := s3fs.New(cl, "test", s3fs.WithTrashPrefix(".trash/"))
+
+	if err := fsys.Remove("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".trash/dir/a.txt", ".trash/dir/sub/b.txt", ".trash/file.txt"}; !reflect.DeepEqual(keys(), want) {
+		t.Fatalf("want keys %q; got %q", want, keys())
+	}
+
+	if err := fsys.Restore("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fsys.ReadFile("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "file" {
+		t.Errorf("want %q; got %q", "file", data)
+	}
+
+	if err := fsys.Restore("dir/sub"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".trash/dir/a.txt", "dir/sub/b.txt", "file.txt"}; !reflect.DeepEqual(keys(), want) {
+		t.Fatalf("want keys %q; got %q", want, keys())
+	}
+
+	if err := fsys.Restore("dir/sub"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+
+	if err := fsys.EmptyTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dir/sub/b.txt", "file.txt"}; !reflect.DeepEqual(keys(), want) {
+		t.Errorf("want keys %q; got %q", want, keys())
+	}
+
+	if err := fsys.Remove("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestRemoveWithoutTrash(t *testing.T) {
+	cl := newMemClient()
+	cl.put("file.txt", []byte("file"))
+
+	fsys := s3fs.New(cl, "test")
+	if err := fsys.Remove("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.objects) != 0 {
+		t.Errorf("want no objects left; got %d", len(cl.objects))
+	}
+	if n := cl.count("CopyObject"); n != 0 {
+		t.Errorf("want no CopyObject; got %d", n)
+	}
+
+	if err := fsys.Restore("file.txt"); err == nil {
+		t.Error("expected Restore to fail without a trash")
+	}
+}
@@ -0,0 +1,209 @@
+package s3fs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+)
+
+// ReadDirDepth reads the directory name recursively, descending at most
+// maxDepth levels. maxDepth of 1 is equivalent to ReadDir. Directories at
+// the cutoff depth are returned as entries but their contents are not listed.
+//
+// The entries have a RelPath() string method returning their
+// slash-separated path relative to name, e.g. "sub/file.txt", while Name
+// returns the base name as with ReadDir. Entries are sorted in the order
+// fs.WalkDir would visit them: every directory comes right before its
+// contents.
+func (f *S3FS) ReadDirDepth(name string, maxDepth int) ([]fs.DirEntry, error) {
+	if maxDepth < 1 {
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	d, err := f.openDir(context.TODO(), name)
+	if err != nil {
+		return nil, &fs.PathError{
+			Op:   "readdir",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	des, err := d.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []fs.DirEntry
+	if err := f.appendTree(&out, name, "", des, maxDepth-1); err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		out = []fs.DirEntry{}
+	}
+	return out, nil
+}
+
+// appendTree appends des to out, with their paths relative to rel, and
+// recursively lists their subdirectories up to depth more levels.
+func (f *S3FS) appendTree(out *[]fs.DirEntry, name, rel string, des []fs.DirEntry, depth int) error {
+	for _, de := range des {
+		entryRel := path.Join(rel, de.Name())
+		*out = append(*out, relDirEntry{DirEntry: de, rel: entryRel})
+
+		if !de.IsDir() || depth == 0 {
+			continue
+		}
+
+		// the prefix is known to exist, so it can be listed without a stat.
+		subName := path.Join(name, de.Name())
+		children, err := f.knownDir(context.TODO(), subName).ReadDir(-1)
+		if err != nil {
+			return err
+		}
+
+		if err := f.appendTree(out, subName, entryRel, children, depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relDirEntry is a fs.DirEntry that knows its path relative to the
+// directory it was listed from.
+type relDirEntry struct {
+	fs.DirEntry
+	rel string
+}
+
+// RelPath returns the path of the entry relative to the directory given to
+// ReadDirDepth.
+func (de relDirEntry) RelPath() string { return de.rel }
+
+// TreeJSON writes the directory tree rooted at name to w as JSON, descending
+// at most maxDepth levels. Every node is an object with a "name" and a "type"
+// ("dir" or "file"); files carry their "size" and directories their
+// "children", which are omitted for directories at the cutoff depth:
+//
+//	{"name":"dir","type":"dir","children":[{"name":"a.txt","type":"file","size":7}]}
+//
+// The output is written while directories are listed, so the tree is never
+// held in memory as a whole.
+func (f *S3FS) TreeJSON(name string, w io.Writer, maxDepth int) error {
+	if maxDepth < 1 {
+		return &fs.PathError{
+			Op:   "tree",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	d, err := f.openDir(context.TODO(), name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "tree",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := f.writeTreeDir(bw, path.Base(name), name, d, maxDepth); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeTreeDir writes the node of the directory d found at dirPath.
+func (f *S3FS) writeTreeDir(w *bufio.Writer, name, dirPath string, d fs.ReadDirFile, depth int) error {
+	if err := writeTreeNode(w, name, "dir"); err != nil {
+		return err
+	}
+
+	if depth > 0 {
+		if err := f.writeTreeChildren(w, dirPath, d, depth); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.WriteString("}")
+	return err
+}
+
+func (f *S3FS) writeTreeChildren(w *bufio.Writer, dirPath string, d fs.ReadDirFile, depth int) error {
+	if _, err := w.WriteString(`,"children":[`); err != nil {
+		return err
+	}
+
+	first := true
+	for {
+		des, err := d.ReadDir(1000)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		for _, de := range des {
+			if !first {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			first = false
+
+			if de.IsDir() {
+				subPath := path.Join(dirPath, de.Name())
+				sub := f.knownDir(context.TODO(), subPath)
+				if err := f.writeTreeDir(w, de.Name(), subPath, sub, depth-1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			info, err := de.Info()
+			if err != nil {
+				return err
+			}
+
+			if err := writeTreeNode(w, de.Name(), "file"); err != nil {
+				return err
+			}
+			if _, err := w.WriteString(`,"size":` + strconv.FormatInt(info.Size(), 10) + "}"); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) || len(des) == 0 {
+			break
+		}
+	}
+
+	_, err := w.WriteString("]")
+	return err
+}
+
+// writeTreeNode writes the opening of a tree node without closing it.
+func writeTreeNode(w *bufio.Writer, name, typ string) error {
+	b, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.WriteString(`{"name":`); err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = w.WriteString(`,"type":"` + typ + `"`)
+	return err
+}
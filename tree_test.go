@@ -0,0 +1,157 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestReadDirDepth(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{
+		"a.txt",
+		"dir/b.txt",
+		"dir/sub/c.txt",
+		"dir/sub/deep/d.txt",
+		"other/e.txt",
+	} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	names := func(des []fs.DirEntry) (out []string) {
+		for _, de := range des {
+			out = append(out, de.Name())
+		}
+		return out
+	}
+
+	relPaths := func(t *testing.T, des []fs.DirEntry) (out []string) {
+		t.Helper()
+		for _, de := range des {
+			if strings.Contains(de.Name(), "/") {
+				t.Errorf("want a base name; got %q", de.Name())
+			}
+			rp, ok := de.(interface{ RelPath() string })
+			if !ok {
+				t.Fatalf("expected %T to have a RelPath method", de)
+			}
+			out = append(out, rp.RelPath())
+		}
+		return out
+	}
+
+	t.Run("depth 1 equals ReadDir", func(t *testing.T) {
+		des, err := fsys.ReadDirDepth(".", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(names(des), names(want)) {
+			t.Errorf("want %v; got %v", names(want), names(des))
+		}
+	})
+
+	t.Run("depth 2", func(t *testing.T) {
+		des, err := fsys.ReadDirDepth(".", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"a.txt", "dir", "dir/b.txt", "dir/sub", "other", "other/e.txt"}
+		if got := relPaths(t, des); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v; got %v", want, got)
+		}
+
+		for _, de := range des {
+			if de.(interface{ RelPath() string }).RelPath() == "dir/sub" && !de.IsDir() {
+				t.Error("expected dir/sub at the cutoff to be a directory")
+			}
+		}
+	})
+
+	t.Run("subdirectory", func(t *testing.T) {
+		des, err := fsys.ReadDirDepth("dir", 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"b.txt", "sub", "sub/c.txt", "sub/deep", "sub/deep/d.txt"}
+		if got := relPaths(t, des); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %v; got %v", want, got)
+		}
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		_, err := fsys.ReadDirDepth(".", 0)
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+}
+
+func TestTreeJSON(t *testing.T) {
+	cl := newMemClient()
+	for key, content := range map[string]string{
+		"a.txt":              "a",
+		"dir/b.txt":          "bb",
+		"dir/sub/c.txt":      "ccc",
+		"dir/sub/deep/d.txt": "dddd",
+	} {
+		cl.put(key, []byte(content))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	fixtures := []struct {
+		desc  string
+		name  string
+		depth int
+		want  string
+	}{
+		{
+			desc:  "depth 1",
+			name:  ".",
+			depth: 1,
+			want:  `{"name":".","type":"dir","children":[{"name":"a.txt","type":"file","size":1},{"name":"dir","type":"dir"}]}`,
+		},
+		{
+			desc:  "subdirectory depth 2",
+			name:  "dir",
+			depth: 2,
+			want: `{"name":"dir","type":"dir","children":[` +
+				`{"name":"b.txt","type":"file","size":2},` +
+				`{"name":"sub","type":"dir","children":[{"name":"c.txt","type":"file","size":3},{"name":"deep","type":"dir"}]}` +
+				`]}`,
+		},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := fsys.TreeJSON(f.name, &buf, f.depth); err != nil {
+				t.Fatal(err)
+			}
+
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("invalid json: %s", buf.String())
+			}
+			if buf.String() != f.want {
+				t.Errorf("want %s; got %s", f.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// maxUpdateAttempts bounds how many times Update runs its read-modify-write
+// loop before giving up on a contended object.
+const maxUpdateAttempts = 10
+
+// Update replaces the content of the object name with what fn returns for
+// its current content, which is nil if the object does not exist. The new
+// content is written with a conditional PutObject (If-Match with the ETag
+// that was read, or If-None-Match: * for a new object), and the whole loop
+// is run again when another writer changed the object in between, so
+// concurrent Updates do not lose each other's writes. After 10 conflicts
+// Update fails with an error matching ErrPreconditionFailed.
+//
+// fn may be called several times and must not have side effects. An error
+// from fn aborts the update and is returned as is, wrapped in a
+// *fs.PathError.
+//
+// The object is read as ReadFile reads it and written as WriteFile writes
+// it, so fn sees decompressed content with WithAutoDecompress, and what it
+// returns goes through the interceptor of WithWriteInterceptor and is
+// compressed with WithCompressOnWrite. Update fails for objects that would
+// not be written back the way they were read: with
+// WithClientSideDecryption, and for names that are decompressed based on
+// their extension.
+//
+// The conditions are sent as HTTP headers, so only clients built with the
+// s3 package honor them. Update is meant for small objects, such as JSON
+// state files: the object is read in memory and written with a single
+// PutObject.
+func (f *S3FS) Update(name string, fn func(old []byte) ([]byte, error)) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{
+			Op:   "update",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return &fs.PathError{
+			Op:   "update",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	// what fn returns would be written as it is, unlike what was read.
+	var err error
+	switch {
+	case f.decryption != nil:
+		err = errors.New("s3fs: Update cannot encrypt what it writes, as WithClientSideDecryption requires")
+	case f.decompressor(name) != nil:
+		err = fmt.Errorf("s3fs: Update cannot compress %s for the decompressor of its extension", name)
+	}
+	if err != nil {
+		return &fs.PathError{
+			Op:   "update",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err = f.update(context.TODO(), name, fn)
+		if f.statCache != nil {
+			f.statCache.invalidate(name)
+		}
+		if err == nil || !isUpdateConflict(err) {
+			break
+		}
+	}
+	if err != nil {
+		if isUpdateConflict(err) {
+			err = fmt.Errorf("s3fs: %s changed %d times during update: %w: %v", name, maxUpdateAttempts, ErrPreconditionFailed, err)
+		}
+		return &fs.PathError{
+			Op:   "update",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// update runs a single read-modify-write of name.
+func (f *S3FS) update(ctx context.Context, name string, fn func(old []byte) ([]byte, error)) error {
+	old, eTag, err := f.readWithETag(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := fn(old)
+	if err != nil {
+		return err
+	}
+
+	in, body, err := f.encodeContent(name, data)
+	if err != nil {
+		return err
+	}
+	in.Body = bytes.NewReader(body)
+
+	cond := smithyhttp.SetHeaderValue("If-None-Match", "*")
+	if eTag != "" {
+		cond = smithyhttp.SetHeaderValue("If-Match", eTag)
+	}
+
+	optFns := make([]func(*s3.Options), 0, len(f.optFns)+1)
+	optFns = append(optFns, f.optFns...)
+	optFns = append(optFns, func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, cond)
+	})
+
+	_, err = f.cl.PutObject(ctx, in, optFns...)
+	return err
+}
+
+// readWithETag returns the content and ETag of name, read like ReadFile
+// reads it. Both are empty if the object does not exist.
+func (f *S3FS) readWithETag(ctx context.Context, name string) ([]byte, string, error) {
+	data, eTag, err := f.readObjectETag(ctx, name, -1)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", nil
+		}
+		return nil, "", f.bucketErr(err)
+	}
+	if eTag == "" {
+		return nil, "", fmt.Errorf("s3fs: %s has no ETag", name)
+	}
+	return data, eTag, nil
+}
+
+// isUpdateConflict reports whether a conditional write failed because the
+// object changed since it was read.
+func isUpdateConflict(err error) bool {
+	return isPreconditionFailed(err) ||
+		errorCode(err) == "ConditionalRequestConflict" ||
+		httpStatusCode(err) == http.StatusConflict
+}
@@ -0,0 +1,225 @@
+package s3fs_test
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// conditionalServer is a single object store that honors If-Match and
+// If-None-Match on PUT like S3 does.
+type conditionalServer struct {
+	mu        sync.Mutex
+	data      []byte
+	eTag      string
+	conflicts int
+
+	// gets, if set, is done once for each of the first GETs, so that
+	// concurrent readers all read the same version.
+	gets *sync.WaitGroup
+}
+
+func (s *conditionalServer) client() *s3.Client {
+	return s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		UsePathStyle: true,
+		HTTPClient:   httpClientFunc(s.do),
+	})
+}
+
+func (s *conditionalServer) do(r *http.Request) (*http.Response, error) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		data, eTag, gets := s.data, s.eTag, s.gets
+		s.mu.Unlock()
+
+		if gets != nil {
+			gets.Done()
+			gets.Wait()
+		}
+
+		if eTag == "" {
+			return xmlError(http.StatusNotFound, "NoSuchKey"), nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{eTag}},
+			Body:       io.NopCloser(strings.NewReader(string(data))),
+		}, nil
+
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.gets = nil
+
+		ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+		if (ifMatch != "" && ifMatch != s.eTag) || (ifNoneMatch == "*" && s.eTag != "") {
+			s.conflicts++
+			return xmlError(http.StatusPreconditionFailed, "PreconditionFailed"), nil
+		}
+
+		sum := md5.Sum(body)
+		s.data, s.eTag = body, `"`+hex.EncodeToString(sum[:])+`"`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{s.eTag}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	}
+	return xmlError(http.StatusMethodNotAllowed, "MethodNotAllowed"), nil
+}
+
+func xmlError(status int, code string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader("<Error><Code>" + code + "</Code></Error>")),
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	increment := func(old []byte) ([]byte, error) {
+		var state struct{ Count int }
+		if old != nil {
+			if err := json.Unmarshal(old, &state); err != nil {
+				return nil, err
+			}
+		}
+		state.Count++
+		return json.Marshal(state)
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		const writers, updates = 2, 5
+
+		srv := &conditionalServer{gets: &sync.WaitGroup{}}
+		srv.gets.Add(writers)
+		fsys := s3fs.New(srv.client(), "bucket")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*updates)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < updates; j++ {
+					errs <- fsys.Update("state.json", increment)
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if want := `{"Count":10}`; string(srv.data) != want {
+			t.Errorf("want %s; got %s", want, srv.data)
+		}
+		if srv.conflicts == 0 {
+			t.Error("expected the concurrent updates to conflict")
+		}
+	})
+
+	t.Run("fn error", func(t *testing.T) {
+		srv := &conditionalServer{}
+		fsys := s3fs.New(srv.client(), "bucket")
+
+		errFn := errors.New("fn failed")
+		err := fsys.Update("state.json", func([]byte) ([]byte, error) { return nil, errFn })
+		if !errors.Is(err, errFn) {
+			t.Errorf("want %v; got %v", errFn, err)
+		}
+		if srv.eTag != "" {
+			t.Error("expected nothing to be written")
+		}
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		cl := newMemClient()
+		var intercepted []string
+		fsys := s3fs.New(cl, "test",
+			s3fs.WithCompressOnWrite(gzip.DefaultCompression),
+			s3fs.WithAutoDecompress,
+			s3fs.WithWriteInterceptor(func(name string, data []byte) ([]byte, error) {
+				intercepted = append(intercepted, string(data))
+				return data, nil
+			}),
+		)
+		if err := fsys.WriteFile("state.json", []byte(`{"Count":1}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := fsys.Update("state.json", increment); err != nil {
+			t.Fatal(err)
+		}
+
+		o, _ := cl.get("state.json")
+		if o.contentEncoding != "gzip" {
+			t.Errorf("want content encoding gzip; got %q", o.contentEncoding)
+		}
+		if data, err := fsys.ReadFile("state.json"); err != nil || string(data) != `{"Count":2}` {
+			t.Errorf("want %s; got %s (%v)", `{"Count":2}`, data, err)
+		}
+		if want := []string{`{"Count":1}`, `{"Count":2}`}; !reflect.DeepEqual(intercepted, want) {
+			t.Errorf("want the interceptor to see %q; got %q", want, intercepted)
+		}
+	})
+
+	t.Run("extension decompressed", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithExtensionDecompress)
+
+		if err := fsys.Update("state.json.gz", increment); err == nil {
+			t.Error("expected an error")
+		}
+		if n := cl.count("PutObject"); n != 0 {
+			t.Errorf("want no PutObject; got %d", n)
+		}
+	})
+
+	t.Run("too many conflicts", func(t *testing.T) {
+		srv := &conditionalServer{}
+		fsys := s3fs.New(srv.client(), "bucket")
+
+		calls := 0
+		err := fsys.Update("state.json", func(old []byte) ([]byte, error) {
+			// another writer gets in first every time.
+			calls++
+			srv.mu.Lock()
+			srv.data, srv.eTag = []byte(`{"Count":0}`), `"other-`+strconv.Itoa(calls)+`"`
+			srv.mu.Unlock()
+			return increment(old)
+		})
+		if !errors.Is(err, s3fs.ErrPreconditionFailed) {
+			t.Errorf("want %v; got %v", s3fs.ErrPreconditionFailed, err)
+		}
+		if calls != 10 {
+			t.Errorf("want 10 attempts; got %d", calls)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package s3fs
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// NewFromURL returns a new filesystem for the bucket named in rawurl, using
+// a client built from cfg, and the filesystem rooted at the prefix of
+// rawurl, which is the bucket filesystem itself if rawurl has no prefix.
+//
+// rawurl is either a S3 URL ("s3://bucket/optional/prefix") or a S3 ARN
+// ("arn:aws:s3:::bucket/optional/prefix"). A prefix in rawurl cannot be
+// combined with WithPrefix in opts.
+func NewFromURL(cfg aws.Config, rawurl string, opts ...Option) (*S3FS, fs.FS, error) {
+	bucket, prefix, err := parseURL(rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fsys := New(s3.NewFromConfig(cfg), bucket, opts...)
+	if prefix == "" {
+		return fsys, fsys, nil
+	}
+	if fsys.prefix != "" {
+		return nil, nil, fmt.Errorf("s3fs: invalid url %q: the filesystem already has the prefix %q", rawurl, fsys.prefix)
+	}
+
+	root, err := fsys.Sub(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fsys, root, nil
+}
+
+// parseURL splits a S3 URL or ARN into a bucket and a prefix.
+func parseURL(rawurl string) (bucket, prefix string, err error) {
+	const arnPrefix = "arn:aws:s3:::"
+
+	var rest string
+	switch {
+	case strings.HasPrefix(rawurl, arnPrefix):
+		rest = strings.TrimPrefix(rawurl, arnPrefix)
+	default:
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return "", "", fmt.Errorf("s3fs: invalid url %q: %w", rawurl, err)
+		}
+		if u.Scheme != "s3" {
+			return "", "", fmt.Errorf("s3fs: invalid url %q: scheme must be s3", rawurl)
+		}
+		rest = u.Host + u.Path
+	}
+
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("s3fs: invalid url %q: missing bucket", rawurl)
+	}
+	if !validBucketName(bucket) {
+		return "", "", fmt.Errorf("s3fs: invalid url %q: invalid bucket name %q", rawurl, bucket)
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" && !fs.ValidPath(prefix) {
+		return "", "", fmt.Errorf("s3fs: invalid url %q: %w", rawurl, fs.ErrInvalid)
+	}
+	return bucket, prefix, nil
+}
@@ -0,0 +1,190 @@
+package s3fs_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type httpClientFunc func(*http.Request) (*http.Response, error)
+
+func (fn httpClientFunc) Do(r *http.Request) (*http.Response, error) { return fn(r) }
+
+func TestNewFromURL(t *testing.T) {
+	fixtures := []struct {
+		desc string
+		url  string
+		host string
+		path string
+	}{
+		{
+			desc: "bucket only",
+			url:  "s3://my-bucket",
+			host: "my-bucket.",
+			path: "/file.txt",
+		},
+		{
+			desc: "with prefix",
+			url:  "s3://my-bucket/data/v2/",
+			host: "my-bucket.",
+			path: "/data/v2/file.txt",
+		},
+		{
+			desc: "arn",
+			url:  "arn:aws:s3:::my-bucket/data",
+			host: "my-bucket.",
+			path: "/data/file.txt",
+		},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			var req *http.Request
+			cfg := aws.Config{
+				Region:      "us-east-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient: httpClientFunc(func(r *http.Request) (*http.Response, error) {
+					req = r
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header: http.Header{
+							"Content-Length": []string{"7"},
+							"Etag":           []string{`"etag"`},
+						},
+						Body: io.NopCloser(strings.NewReader("")),
+					}, nil
+				}),
+			}
+
+			fsys, root, err := s3fs.NewFromURL(cfg, f.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fi, err := fs.Stat(root, "file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fi.Size() != 7 {
+				t.Errorf("want size 7; got %d", fi.Size())
+			}
+			if !strings.HasPrefix(req.URL.Host, f.host) {
+				t.Errorf("want host to start with %q; got %q", f.host, req.URL.Host)
+			}
+			if req.URL.Path != f.path {
+				t.Errorf("want path %q; got %q", f.path, req.URL.Path)
+			}
+
+			// the bucket filesystem is not rooted at the prefix.
+			if _, err := fsys.Stat(strings.TrimPrefix(f.path, "/")); err != nil {
+				t.Fatal(err)
+			}
+			if req.URL.Path != f.path {
+				t.Errorf("want bucket path %q; got %q", f.path, req.URL.Path)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, u := range []string{"http://bucket/key", "s3://", "s3://bucket/../x", "arn:aws:s3:::", "s3://My_Bucket/key"} {
+			if _, _, err := s3fs.NewFromURL(aws.Config{}, u); err == nil {
+				t.Errorf("%s: expected error", u)
+			}
+		}
+	})
+
+	t.Run("with prefix option", func(t *testing.T) {
+		cfg := aws.Config{Region: "us-east-1", Credentials: aws.AnonymousCredentials{}}
+		if _, _, err := s3fs.NewFromURL(cfg, "s3://my-bucket/data", s3fs.WithPrefix("other")); err == nil {
+			t.Error("expected a url prefix and WithPrefix to be rejected")
+		}
+
+		fsys, root, err := s3fs.NewFromURL(cfg, "s3://my-bucket", s3fs.WithPrefix("other"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != fs.FS(fsys) {
+			t.Error("want the bucket filesystem as root without a url prefix")
+		}
+	})
+}
+
+func TestNewWithEndpoint(t *testing.T) {
+	fixtures := []struct {
+		desc      string
+		pathStyle bool
+		host      string
+		path      string
+	}{
+		{desc: "path style", pathStyle: true, host: "localhost:9000", path: "/my-bucket/file.txt"},
+		{desc: "virtual hosted", host: "my-bucket.localhost:9000", path: "/file.txt"},
+	}
+
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			var req *http.Request
+			client := httpClientFunc(func(r *http.Request) (*http.Response, error) {
+				req = r
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header: http.Header{
+						"Content-Length": []string{"7"},
+						"Etag":           []string{`"etag"`},
+					},
+					Body: io.NopCloser(strings.NewReader("")),
+				}, nil
+			})
+			creds := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+				return aws.Credentials{AccessKeyID: "minio", SecretAccessKey: "secret"}, nil
+			})
+
+			fsys, err := s3fs.NewWithEndpoint("my-bucket", "http://localhost:9000", f.pathStyle, creds,
+				s3fs.WithRequestOptions(func(o *s3.Options) { o.HTTPClient = client }))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fi, err := fsys.Stat("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() != 7 {
+				t.Errorf("want size 7; got %d", fi.Size())
+			}
+
+			if req.URL.Scheme != "http" || req.URL.Host != f.host {
+				t.Errorf("want http://%s; got %s://%s", f.host, req.URL.Scheme, req.URL.Host)
+			}
+			if req.URL.Path != f.path {
+				t.Errorf("want path %q; got %q", f.path, req.URL.Path)
+			}
+			if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "Credential=minio/") {
+				t.Errorf("want request signed with the credentials; got %q", auth)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, endpoint := range []string{"", "localhost:9000", "ftp://localhost", "http://"} {
+			if _, err := s3fs.NewWithEndpoint("my-bucket", endpoint, true, nil); err == nil {
+				t.Errorf("%q: expected error", endpoint)
+			}
+		}
+		for _, bucket := range []string{"", "My_Bucket"} {
+			if _, err := s3fs.NewWithEndpoint(bucket, "http://localhost:9000", true, nil); err == nil {
+				t.Errorf("%q: expected invalid bucket name to be rejected", bucket)
+			}
+		}
+	})
+}
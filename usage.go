@@ -0,0 +1,48 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Usage is the disk usage of a directory.
+type Usage struct {
+	// Objects is the number of objects, not counting directory markers.
+	Objects int64
+	// Bytes is the total size of the objects.
+	Bytes int64
+}
+
+// DiskUsage sums up the sizes of all the objects below the directory name,
+// "." meaning the whole bucket. The keys are listed page by page and ctx is
+// checked before every page; once it is done the totals of the pages read
+// so far are returned together with ctx.Err(), as a best-effort result.
+func (f *S3FS) DiskUsage(ctx context.Context, name string) (Usage, error) {
+	if !fs.ValidPath(name) {
+		return Usage{}, &fs.PathError{
+			Op:   "du",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = "."
+	}
+
+	var u Usage
+	err := f.listKeys(ctx, "du", prefix, func(o types.Object) {
+		if isDirMarker(o) {
+			return
+		}
+		u.Objects++
+		u.Bytes += o.Size
+	})
+	if cerr := ctx.Err(); cerr != nil {
+		return u, cerr
+	}
+	return u, err
+}
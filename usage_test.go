@@ -0,0 +1,72 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestDiskUsage(t *testing.T) {
+	cl := newMemClient()
+	for i := 0; i < 10; i++ {
+		cl.put(fmt.Sprintf("cache/%d/blob", i), make([]byte, 100))
+	}
+	cl.put("cache/marker/", nil)
+	cl.put("other.bin", make([]byte, 1000))
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("total", func(t *testing.T) {
+		u, err := fsys.DiskUsage(context.Background(), "cache")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (s3fs.Usage{Objects: 10, Bytes: 1000}); u != want {
+			t.Errorf("want %+v; got %+v", want, u)
+		}
+
+		if u, err := fsys.DiskUsage(context.Background(), "."); err != nil || u.Bytes != 2000 {
+			t.Errorf("want 2000 bytes in the bucket; got %+v, %v", u, err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var pages int
+		cl.hook = func(op string, in interface{}) error {
+			if in, ok := in.(*s3.ListObjectsV2Input); ok {
+				in.MaxKeys = 2
+				if pages++; pages == 2 {
+					cancel()
+				}
+			}
+			return nil
+		}
+		defer func() { cl.hook = nil }()
+
+		u, err := fsys.DiskUsage(ctx, "cache")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want %v; got %v", context.Canceled, err)
+		}
+		if want := (s3fs.Usage{Objects: 4, Bytes: 400}); u != want {
+			t.Errorf("want the partial usage %+v; got %+v", want, u)
+		}
+		if pages != 2 {
+			t.Errorf("want the scan to stop after 2 pages; got %d", pages)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := fsys.DiskUsage(context.Background(), "../cache"); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("want %v; got %v", fs.ErrInvalid, err)
+		}
+	})
+}
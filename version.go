@@ -0,0 +1,182 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrNoVersion is returned by RestoreVersion when the object has no prior
+// version to restore.
+var ErrNoVersion = errors.New("no version to restore")
+
+// versionAPIClient is implemented by clients that support versioned
+// buckets. *s3.Client implements it.
+type versionAPIClient interface {
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+var _ versionAPIClient = (*s3.Client)(nil)
+
+// OpenVersion opens the version versionID of the object name in a
+// versioned bucket. The file reads that version's content, also when it
+// Seeks, and its Stat reports that version's size and ETag. Open keeps
+// reading the latest version.
+//
+// If the version does not exist the error wraps fs.ErrNotExist.
+func (f *S3FS) OpenVersion(name, versionID string) (fs.File, error) {
+	if !fs.ValidPath(name) || name == "." || versionID == "" {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	fl, err := f.openFileVersion(context.TODO(), name, aws.String(versionID))
+	if err != nil {
+		if isNotFoundErr(err) {
+			err = fs.ErrNotExist
+		}
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if !f.readSeeker {
+		return fileNoSeek{fl}, nil
+	}
+	return fl, nil
+}
+
+// RestoreVersion recovers the deleted object name in a versioned bucket.
+// The latest version of name that is not a delete marker is copied over
+// the current key, which hides the delete marker. If the object is not
+// deleted RestoreVersion does nothing; if it has no prior version
+// ErrNoVersion is returned.
+//
+// The client must implement ListObjectVersions and CopyObject, as
+// *s3.Client does.
+func (f *S3FS) RestoreVersion(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	cl, ok := f.cl.(versionAPIClient)
+	if !ok {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  fmt.Errorf("s3fs: %T does not implement ListObjectVersions and CopyObject", f.cl),
+		}
+	}
+
+	version, err := f.latestVersion(cl, name)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  err,
+		}
+	}
+	if version == nil {
+		// the object is not deleted.
+		return nil
+	}
+
+	_, err = cl.CopyObject(context.TODO(), &s3.CopyObjectInput{
+		Bucket:               &f.bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(name),
+		CopySource:           aws.String(copySource(f.bucket, name, aws.ToString(version.VersionId))),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}, f.optFns...)
+	if err != nil {
+		return &fs.PathError{
+			Op:   "restore",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	if f.statCache != nil {
+		f.statCache.invalidate(name)
+	}
+	return nil
+}
+
+// latestVersion returns the latest version of name that is not a delete
+// marker, or nil if that version is the current one.
+func (f *S3FS) latestVersion(cl versionAPIClient, name string) (*types.ObjectVersion, error) {
+	var (
+		keyMarker, versionMarker *string
+		last                     time.Time
+	)
+	for {
+		if err := f.paceList(context.TODO(), last); err != nil {
+			return nil, err
+		}
+		last = time.Now()
+
+		out, err := cl.ListObjectVersions(context.TODO(), &s3.ListObjectVersionsInput{
+			Bucket:          &f.bucket,
+			Prefix:          aws.String(name),
+			KeyMarker:       keyMarker,
+			VersionIdMarker: versionMarker,
+		}, f.optFns...)
+		if err != nil {
+			return nil, err
+		}
+
+		// versions of a key are listed newest first.
+		for i := range out.Versions {
+			v := &out.Versions[i]
+			if aws.ToString(v.Key) != name {
+				continue
+			}
+			if v.IsLatest {
+				return nil, nil
+			}
+			return v, nil
+		}
+
+		if !out.IsTruncated {
+			return nil, ErrNoVersion
+		}
+		keyMarker, versionMarker = out.NextKeyMarker, out.NextVersionIdMarker
+	}
+}
+
+// copySource returns the CopySource of the version of key in bucket.
+func copySource(bucket, key, versionID string) string {
+	src := bucket + "/" + strings.ReplaceAll(url.PathEscape(key), "%2F", "/")
+	if versionID != "" {
+		src += "?versionId=" + url.QueryEscape(versionID)
+	}
+	return src
+}
@@ -0,0 +1,131 @@
+package s3fs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestRestoreVersion(t *testing.T) {
+	t.Run("delete marker", func(t *testing.T) {
+		cl := newMemClient()
+		cl.versions["file.txt"] = []memVersion{
+			{id: "v3", deleteMarker: true},
+			{id: "v2", data: []byte("second")},
+			{id: "v1", data: []byte("first")},
+		}
+		cl.versions["file.txt.bak"] = []memVersion{{id: "b1", data: []byte("backup")}}
+
+		fsys := s3fs.New(cl, "test")
+		if err := fsys.RestoreVersion("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := fsys.ReadFile("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "second" {
+			t.Errorf("want %q; got %q", "second", data)
+		}
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		cl := newMemClient()
+		cl.versions["file.txt"] = []memVersion{{id: "v1", data: []byte("first")}}
+
+		if err := s3fs.New(cl, "test").RestoreVersion("file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.count("CopyObject"); n != 0 {
+			t.Errorf("want no CopyObject; got %d", n)
+		}
+	})
+
+	t.Run("no prior version", func(t *testing.T) {
+		cl := newMemClient()
+		cl.versions["file.txt"] = []memVersion{{id: "v1", deleteMarker: true}}
+
+		err := s3fs.New(cl, "test").RestoreVersion("file.txt")
+		if !errors.Is(err, s3fs.ErrNoVersion) {
+			t.Errorf("want %v; got %v", s3fs.ErrNoVersion, err)
+		}
+	})
+
+	t.Run("unsupported client", func(t *testing.T) {
+		fsys := s3fs.New(struct{ s3fs.S3Client }{newMemClient()}, "test")
+		err := fsys.RestoreVersion("file.txt")
+		if err == nil || !strings.Contains(err.Error(), "ListObjectVersions") {
+			t.Errorf("want error naming ListObjectVersions; got %v", err)
+		}
+	})
+}
+
+func TestOpenVersion(t *testing.T) {
+	cl := newMemClient()
+	cl.versions["file.txt"] = []memVersion{
+		{id: "v2", data: []byte("second version")},
+		{id: "v1", data: []byte("first")},
+	}
+	cl.put("file.txt", []byte("second version")).versionID = "v2"
+
+	fsys := s3fs.New(cl, "test", s3fs.WithReadSeeker)
+
+	f, err := fsys.OpenVersion("file.txt", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 5 {
+		t.Errorf("want size 5; got %d", fi.Size())
+	}
+	if info, ok := fi.Sys().(*s3fs.ObjectInfo); !ok || info.VersionID != "v1" {
+		t.Errorf("want version v1; got %v", fi.Sys())
+	}
+
+	if _, err := f.(io.Seeker).Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "rst" {
+		t.Errorf("want %q; got %q", "rst", data)
+	}
+
+	t.Run("latest", func(t *testing.T) {
+		data, err := fsys.ReadFile("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "second version" {
+			t.Errorf("want %q; got %q", "second version", data)
+		}
+
+		fi, err := fsys.Stat("file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, ok := fi.Sys().(*s3fs.ObjectInfo); !ok || info.VersionID != "v2" {
+			t.Errorf("want version v2; got %v", fi.Sys())
+		}
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := fsys.OpenVersion("file.txt", "v9")
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want a *fs.PathError matching %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// WaitForObject polls the object name with HeadObject every pollInterval
+// until it exists and returns its FileInfo. It is meant for pipelines
+// waiting for an upstream producer to drop a file.
+//
+// If ctx is done first, the error wraps ctx's error. Errors other than the
+// object not existing end the wait too.
+func (f *S3FS) WaitForObject(ctx context.Context, name string, pollInterval time.Duration) (fs.FileInfo, error) {
+	if pollInterval <= 0 {
+		return nil, &fs.PathError{
+			Op:   "wait",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		head, err := f.headObject(ctx, name)
+		switch {
+		case err == nil:
+			return headFileInfo(name, head), nil
+		case ctx.Err() != nil:
+			err = ctx.Err()
+		case errors.Is(err, fs.ErrNotExist):
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-t.C:
+				continue
+			}
+		}
+
+		return nil, &fs.PathError{
+			Op:   "wait",
+			Path: name,
+			Err:  err,
+		}
+	}
+}
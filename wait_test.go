@@ -0,0 +1,48 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWaitForObject(t *testing.T) {
+	t.Run("appears", func(t *testing.T) {
+		cl := newMemClient()
+		cl.hook = func(op string, in interface{}) error {
+			// the object shows up on the third poll.
+			if op == "HeadObject" && cl.count("HeadObject") == 3 {
+				cl.put("ready.txt", []byte("done"))
+			}
+			return nil
+		}
+
+		fsys := s3fs.New(cl, "test")
+		fi, err := fsys.WaitForObject(context.Background(), "ready.txt", time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fi.Name() != "ready.txt" || fi.Size() != 4 {
+			t.Errorf("want ready.txt of size 4; got %s of size %d", fi.Name(), fi.Size())
+		}
+		if n := cl.count("HeadObject"); n != 3 {
+			t.Errorf("want 3 HeadObjects; got %d", n)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		fsys := s3fs.New(newMemClient(), "test")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := fsys.WaitForObject(ctx, "never.txt", time.Millisecond)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want %v; got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WalkContext walks the file tree rooted at root like fs.WalkDir, calling fn
+// for each file or directory in the tree, including root.
+//
+// ctx is checked before every listing page and before every call of fn; once
+// it is done the walk stops and ctx.Err() is returned. Listings are paced if
+// WithListPacing is used.
+func (f *S3FS) WalkContext(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := f.StatContext(ctx, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = f.walkDir(ctx, root, fs.FileInfoToDirEntry(info), fn)
+	}
+
+	if errors.Is(err, fs.SkipDir) || isSkipAll(err) {
+		return nil
+	}
+	return err
+}
+
+func (f *S3FS) walkDir(ctx context.Context, name string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			// successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	des, err := f.knownDir(ctx, name).ReadDir(-1)
+	if err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+
+		// second call, to report the ReadDir error.
+		if err = fn(name, d, err); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, de := range des {
+		if err := f.walkDir(ctx, path.Join(name, de.Name()), de, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root like fs.WalkDir, but with the
+// listings of WalkFlat: all the objects below root are listed at once,
+// without a listing per directory.
+func (f *S3FS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return f.WalkFlat(context.Background(), root, fn)
+}
+
+// WalkFlat is like WalkContext, but lists all the objects below root at
+// once with listings without delimiter, instead of listing every directory
+// on its own. Each page holds up to 1000 keys, whatever their depth, and
+// the entries fn receives carry the size and modification time of the
+// listing, so Info needs no HeadObject.
+//
+// The whole tree is held in memory before fn is first called. Directories
+// are visited in the same order as fs.WalkDir visits them, and fn may
+// return fs.SkipDir or, with Go 1.20 or later, fs.SkipAll.
+func (f *S3FS) WalkFlat(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// a snapshot has to be walked through its own listing.
+	if f.snapshot != nil {
+		return f.WalkContext(ctx, root, fn)
+	}
+
+	prefix, keyPrefix := root+"/", root+"/"
+	if root == "." {
+		prefix, keyPrefix = ".", ""
+	}
+
+	var (
+		tree = make(map[string][]fs.DirEntry)
+		dirs = make(map[string]bool)
+	)
+	err := f.listKeys(ctx, "walk", prefix, func(o types.Object) {
+		rel := strings.TrimPrefix(aws.ToString(o.Key), keyPrefix)
+		addTreeEntry(tree, dirs, keyPrefix, rel, o)
+	})
+	if err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		return fn(root, nil, err)
+	}
+
+	var d fs.DirEntry
+	if root == "." || len(dirs)+len(tree) > 0 {
+		d = dirEntry{fileInfo: fileInfo{name: root, mode: fs.ModeDir}}
+	} else {
+		// root is a file, or an empty directory of a marker.
+		info, err := f.StatContext(ctx, root)
+		if err != nil {
+			return fn(root, nil, err)
+		}
+		d = fs.FileInfoToDirEntry(info)
+	}
+
+	for _, des := range tree {
+		sort.Slice(des, func(i, j int) bool { return des[i].Name() < des[j].Name() })
+	}
+
+	err = walkTree(ctx, root, ".", d, tree, fn)
+	if errors.Is(err, fs.SkipDir) || isSkipAll(err) {
+		return nil
+	}
+	return err
+}
+
+// addTreeEntry adds the object o, whose key relative to keyPrefix, the key
+// prefix of the walked root, is rel, to tree, which maps every directory
+// relative to the root to its entries. The directories of rel missing from
+// dirs are added as needed.
+func addTreeEntry(tree map[string][]fs.DirEntry, dirs map[string]bool, keyPrefix, rel string, o types.Object) {
+	isMarker := strings.HasSuffix(rel, "/")
+	rel = strings.TrimSuffix(rel, "/")
+	if rel == "" || !fs.ValidPath(rel) {
+		return
+	}
+
+	// add the missing parent directories, from the innermost one up.
+	for dir := rel; ; {
+		parent := path.Dir(dir)
+		if dir != rel || isMarker {
+			if dirs[dir] {
+				break
+			}
+			dirs[dir] = true
+			tree[parent] = append(tree[parent], dirEntry{fileInfo: fileInfo{name: dir, mode: fs.ModeDir, key: keyPrefix + dir}})
+		} else {
+			tree[parent] = append(tree[parent], dirEntry{fileInfo: fileInfo{
+				name:    dir,
+				size:    o.Size,
+				modTime: derefTime(o.LastModified),
+				eTag:    aws.ToString(o.ETag),
+				key:     aws.ToString(o.Key),
+			}})
+		}
+
+		if parent == "." {
+			break
+		}
+		dir = parent
+	}
+}
+
+// walkTree walks the entries of tree below rel, the directory name relative
+// to the walked root, like walkDir walks a listed directory.
+func walkTree(ctx context.Context, name, rel string, d fs.DirEntry, tree map[string][]fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := fn(name, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			// successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	for _, de := range tree[rel] {
+		if err := walkTree(ctx, path.Join(name, de.Name()), path.Join(rel, de.Name()), de, tree, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build !go1.20
+
+package s3fs
+
+// isSkipAll reports false: fs.SkipAll was added in Go 1.20.
+func isSkipAll(err error) bool { return false }
@@ -0,0 +1,9 @@
+//go:build go1.20
+
+package s3fs
+
+import "io/fs"
+
+// isSkipAll reports whether err is fs.SkipAll, which stops a walk without
+// failing it.
+func isSkipAll(err error) bool { return err == fs.SkipAll }
@@ -0,0 +1,37 @@
+//go:build go1.20
+
+package s3fs_test
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWalkDirSkipAll(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/c.txt", "z.txt"} {
+		cl.put(key, []byte("content"))
+	}
+
+	var got []string
+	err := s3fs.New(cl, "test").WalkDir(".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, name)
+		if name == "dir/b.txt" {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{".", "a.txt", "dir", "dir/b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
@@ -0,0 +1,244 @@
+package s3fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWalkContext(t *testing.T) {
+	cl := newMemClient()
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "z.txt"} {
+		cl.put(key, []byte("content"))
+	}
+
+	fsys := s3fs.New(cl, "test")
+
+	t.Run("walk", func(t *testing.T) {
+		var got []string
+		err := fsys.WalkContext(context.Background(), ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want []string
+		if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			want = append(want, name)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+	})
+
+	t.Run("cancel in fn", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var n int
+		err := fsys.WalkContext(ctx, ".", func(name string, d fs.DirEntry, err error) error {
+			n++
+			if n == 2 {
+				cancel()
+			}
+			return err
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+		if n != 2 {
+			t.Errorf("want fn to be called 2 times; got %d", n)
+		}
+	})
+
+	t.Run("cancel between pages", func(t *testing.T) {
+		cl := newMemClient()
+		for i := 0; i < 2500; i++ {
+			cl.put(fmt.Sprintf("file%04d", i), nil)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cl.hook = func(op string, in interface{}) error {
+			if op == "ListObjectsV2" {
+				cancel()
+			}
+			return nil
+		}
+
+		var n int
+		err := s3fs.New(cl, "test").WalkContext(ctx, ".", func(string, fs.DirEntry, error) error {
+			n++
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+		if c := cl.count("ListObjectsV2"); c != 1 {
+			t.Errorf("want 1 ListObjectsV2; got %d", c)
+		}
+		if n != 1 {
+			t.Errorf("want fn to be called for the root only; got %d calls", n)
+		}
+	})
+}
+
+func TestWalkFlat(t *testing.T) {
+	cl := newMemClient()
+	for i := 0; i < 100; i++ {
+		cl.put(fmt.Sprintf("data/%d/%02d.json", i%4, i), []byte("content"))
+	}
+	cl.put("data-old.txt", []byte("old"))
+	cl.put("top.txt", []byte("top"))
+
+	fsys := s3fs.New(cl, "test")
+
+	walk := func(t *testing.T, root string, skip string) (names []string) {
+		t.Helper()
+
+		err := fsys.WalkFlat(context.Background(), root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && fi.Size() == 0 {
+				t.Errorf("%s: want the size from the listing", name)
+			}
+
+			if name == skip {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	walkDir := func(t *testing.T, root string, skip string) (names []string) {
+		t.Helper()
+
+		if err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+			if name == skip {
+				return fs.SkipDir
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	t.Run("single listing", func(t *testing.T) {
+		cl.inputs = nil
+
+		got := walk(t, ".", "")
+		if len(got) != 100+8 {
+			t.Errorf("want 108 entries; got %d", len(got))
+		}
+
+		if n := cl.count("ListObjectsV2"); n != 1 || len(cl.inputs) != 1 {
+			t.Errorf("want a single ListObjectsV2 and no other call; got %d of %d calls", n, len(cl.inputs))
+		}
+
+		if want := walkDir(t, ".", ""); !reflect.DeepEqual(got, want) {
+			t.Errorf("want the order of fs.WalkDir %q; got %q", want, got)
+		}
+	})
+
+	for _, f := range []struct {
+		desc, root, skip string
+	}{
+		{desc: "subdirectory", root: "data"},
+		{desc: "skip dir", root: ".", skip: "data/1"},
+		{desc: "skip file", root: "data/2", skip: "data/2/02.json"},
+		{desc: "file", root: "top.txt"},
+	} {
+		f := f
+		t.Run(f.desc, func(t *testing.T) {
+			if got, want := walk(t, f.root, f.skip), walkDir(t, f.root, f.skip); !reflect.DeepEqual(got, want) {
+				t.Errorf("want %q; got %q", want, got)
+			}
+		})
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		err := fsys.WalkFlat(context.Background(), "missing", func(name string, d fs.DirEntry, err error) error {
+			return err
+		})
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want ErrNotExist; got %v", err)
+		}
+	})
+
+	t.Run("stat with ctx", func(t *testing.T) {
+		cl := newMemClient()
+		cl.put("file.txt", []byte("content"))
+
+		// the listing succeeds, the Stat of the root file must see ctx done.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cl.hook = func(op string, in interface{}) error {
+			if op == "ListObjectsV2" {
+				cancel()
+			}
+			return nil
+		}
+
+		var calls int
+		err := s3fs.New(cl, "test").WalkFlat(ctx, "file.txt", func(name string, d fs.DirEntry, err error) error {
+			calls++
+			return err
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v; got %v", context.Canceled, err)
+		}
+		if calls != 1 {
+			t.Errorf("want fn to be called with the Stat error; got %d calls", calls)
+		}
+	})
+
+	t.Run("walk dir", func(t *testing.T) {
+		before := cl.count("ListObjectsV2")
+
+		var got []string
+		err := fsys.WalkDir("data", func(name string, d fs.DirEntry, err error) error {
+			got = append(got, name)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := cl.count("ListObjectsV2") - before; n != 1 {
+			t.Errorf("want a single ListObjectsV2; got %d", n)
+		}
+		if want := walkDir(t, "data", ""); !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q; got %q", want, got)
+		}
+	})
+}
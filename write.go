@@ -0,0 +1,263 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// metaUncompressedSize is the user metadata key the size of objects
+// compressed with WithCompressOnWrite is stored under.
+const metaUncompressedSize = "s3fs-uncompressed-size"
+
+// WithCompressOnWrite makes Create and WriteFile gzip the written data at
+// the given compression level (see compress/gzip) and store it with
+// Content-Encoding: gzip. The uncompressed size is stored in the object's
+// metadata, and Stat reports it when the object is read decompressed, with
+// WithAutoDecompress. It panics if level is not a valid gzip level.
+func WithCompressOnWrite(level int) Option {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic("s3fs: " + err.Error())
+	}
+	return func(fsys *S3FS) {
+		fsys.compressOnWrite = true
+		fsys.compressLevel = level
+	}
+}
+
+// WithWriteInterceptor sets a function that is called with the content of
+// every object written with Create, WriteFile or Update before it is
+// uploaded. The returned data is uploaded instead, e.g. to inject a
+// header, and an error aborts the write: nothing is uploaded and Close (or
+// WriteFile, or Update) returns the error wrapped in a *fs.PathError.
+// Validation, such as checking that JSON objects are valid, can be
+// enforced for the content written this way.
+//
+// Only content passed to the filesystem is intercepted: copies of
+// existing objects, as made by Rename, CopyRange, CopyTo, the trash and
+// RestoreVersion, and the empty markers of Mkdir and MkdirAll are not.
+//
+// Create buffers the whole content until Close, so fn sees the complete
+// content on all paths. With WithCompressOnWrite fn sees the data before
+// it is compressed.
+func WithWriteInterceptor(fn func(name string, data []byte) ([]byte, error)) Option {
+	return func(fsys *S3FS) { fsys.writeInterceptor = fn }
+}
+
+// Create creates the object name and returns a writer for its content.
+// The data is buffered and uploaded when the writer is closed, replacing
+// any existing object. Errors of the upload are returned by Close.
+func (f *S3FS) Create(name string) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  fs.ErrInvalid,
+		}
+	}
+
+	if err := f.validateKey(name); err != nil {
+		return nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	w := &writer{
+		fsys: f,
+		name: name,
+	}
+	w.w = &w.buf
+
+	// with an interceptor, the data is compressed once it was intercepted.
+	if f.compressOnWrite && f.writeInterceptor == nil {
+		// the level was validated by WithCompressOnWrite.
+		w.gz, _ = gzip.NewWriterLevel(&w.buf, f.compressLevel)
+		w.w = w.gz
+	}
+	return w, nil
+}
+
+// WriteFile writes data to the object name, replacing any existing object.
+func (f *S3FS) WriteFile(name string, data []byte) error {
+	w, err := f.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// writer buffers the content of an object until it is closed.
+type writer struct {
+	fsys *S3FS
+	name string
+
+	buf bytes.Buffer
+	gz  *gzip.Writer
+	w   io.Writer
+	// size is the number of bytes written before compression.
+	size   int64
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, &fs.PathError{
+			Op:   "write",
+			Path: w.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+
+	n, err := w.w.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *writer) Close() error {
+	if w.closed {
+		return &fs.PathError{
+			Op:   "close",
+			Path: w.name,
+			Err:  fs.ErrClosed,
+		}
+	}
+	w.closed = true
+
+	in, body := w.fsys.putObjectInput(w.name), w.buf.Bytes()
+	switch {
+	case w.fsys.writeInterceptor != nil:
+		// the data was buffered as it was written, see Create.
+		var err error
+		in, body, err = w.fsys.encodeContent(w.name, body)
+		if err != nil {
+			return &fs.PathError{
+				Op:   "close",
+				Path: w.name,
+				Err:  err,
+			}
+		}
+	case w.gz != nil:
+		if err := w.gz.Close(); err != nil {
+			return &fs.PathError{
+				Op:   "close",
+				Path: w.name,
+				Err:  err,
+			}
+		}
+		setCompressed(in, w.size)
+		body = w.buf.Bytes()
+	}
+
+	err := w.fsys.uploadBytes(context.TODO(), in, body)
+	if w.fsys.statCache != nil {
+		w.fsys.statCache.invalidate(w.name)
+	}
+	if err != nil {
+		return &fs.PathError{
+			Op:   "close",
+			Path: w.name,
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// putObjectInput returns the input of a PutObject of name with the storage
+// class and server-side encryption of the filesystem.
+func (f *S3FS) putObjectInput(name string) *s3.PutObjectInput {
+	return &s3.PutObjectInput{
+		Bucket:               &f.bucket,
+		RequestPayer:         f.requestPayer,
+		Key:                  aws.String(name),
+		StorageClass:         f.storageClass,
+		ServerSideEncryption: f.sse,
+		SSEKMSKeyId:          f.sseKMSKeyID,
+	}
+}
+
+// encodeContent returns the input and the body of a PutObject writing data
+// to name the way Create does: data is passed to the interceptor set with
+// WithWriteInterceptor, then compressed if WithCompressOnWrite is used.
+func (f *S3FS) encodeContent(name string, data []byte) (*s3.PutObjectInput, []byte, error) {
+	in := f.putObjectInput(name)
+	if f.writeInterceptor != nil {
+		var err error
+		if data, err = f.writeInterceptor(name, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !f.compressOnWrite {
+		return in, data, nil
+	}
+
+	var buf bytes.Buffer
+	// the level was validated by WithCompressOnWrite.
+	zw, _ := gzip.NewWriterLevel(&buf, f.compressLevel)
+	if _, err := zw.Write(data); err != nil {
+		return nil, nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, err
+	}
+	setCompressed(in, int64(len(data)))
+	return in, buf.Bytes(), nil
+}
+
+// setCompressed marks in as the upload of gzip compressed content of size
+// bytes.
+func setCompressed(in *s3.PutObjectInput, size int64) {
+	in.ContentEncoding = aws.String("gzip")
+	in.Metadata = map[string]string{
+		metaUncompressedSize: strconv.FormatInt(size, 10),
+	}
+}
+
+// uploadBytes uploads data as the body of in. With WithRetry a bad digest
+// is retried once.
+func (f *S3FS) uploadBytes(ctx context.Context, in *s3.PutObjectInput, data []byte) error {
+	attempts := 1
+	if f.retry != nil && f.retry.maxAttempts > 1 {
+		attempts = 2
+	}
+
+	for attempt := 1; ; attempt++ {
+		in.Body = bytes.NewReader(data)
+
+		err := f.upload(ctx, in)
+		if err == nil || !isBadDigest(err) {
+			return err
+		}
+
+		if attempt == attempts {
+			return badDigestError{err}
+		}
+		if err := f.retry.wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// upload uploads in with the upload manager, which switches to a multipart
+// upload for large bodies.
+func (f *S3FS) upload(ctx context.Context, in *s3.PutObjectInput) error {
+	u := manager.NewUploader(f.cl, func(u *manager.Uploader) {
+		u.ClientOptions = append(u.ClientOptions, f.optFns...)
+	})
+
+	_, err := u.Upload(ctx, in)
+	return err
+}
@@ -0,0 +1,239 @@
+package s3fs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestWriteFile(t *testing.T) {
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test")
+
+	if err := fsys.WriteFile("dir/file.txt", []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fsys.ReadFile("dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "content" {
+		t.Errorf("want %q; got %q", "content", data)
+	}
+
+	for _, name := range []string{".", "dir/", "/abs", "../up"} {
+		if err := fsys.WriteFile(name, nil); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%s: want %v; got %v", name, fs.ErrInvalid, err)
+		}
+	}
+}
+
+func TestCompressOnWrite(t *testing.T) {
+	content := strings.Repeat("compressible text ", 1000)
+
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test", s3fs.WithCompressOnWrite(gzip.BestCompression))
+
+	w, err := fsys.Create("text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in several chunks like a streaming producer would.
+	for i := 0; i < len(content); i += 1000 {
+		if _, err := io.WriteString(w, content[i:i+1000]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	o, ok := cl.get("text.txt")
+	if !ok {
+		t.Fatal("expected the object to exist")
+	}
+	if o.contentEncoding != "gzip" {
+		t.Errorf("want content encoding gzip; got %q", o.contentEncoding)
+	}
+	if got := o.metadata["s3fs-uncompressed-size"]; got != strconv.Itoa(len(content)) {
+		t.Errorf("want uncompressed size %d; got %s", len(content), got)
+	}
+	if len(o.data) >= len(content) {
+		t.Errorf("expected the stored object to be compressed; got %d bytes", len(o.data))
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(o.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Error("round trip did not yield the original content")
+	}
+
+	fi, err := fsys.Stat("text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(o.data)) {
+		t.Errorf("want the stored size %d without decompression; got %d", len(o.data), fi.Size())
+	}
+
+	fi, err = s3fs.New(cl, "test", s3fs.WithAutoDecompress).Stat("text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(content)) {
+		t.Errorf("want the uncompressed size %d; got %d", len(content), fi.Size())
+	}
+
+	t.Run("invalid level", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected WithCompressOnWrite to panic")
+			}
+		}()
+		s3fs.WithCompressOnWrite(42)
+	})
+}
+
+func TestWriteBadDigest(t *testing.T) {
+	newClient := func(failures int) *memClient {
+		cl := newMemClient()
+		cl.hook = func(op string, in interface{}) error {
+			if op == "PutObject" && cl.count("PutObject") <= failures {
+				return apiError(http.StatusBadRequest, "BadDigest")
+			}
+			return nil
+		}
+		return cl
+	}
+
+	t.Run("classified", func(t *testing.T) {
+		cl := newClient(1)
+		err := s3fs.New(cl, "test").WriteFile("file.txt", []byte("content"))
+		if !errors.Is(err, s3fs.ErrBadDigest) {
+			t.Errorf("want %v; got %v", s3fs.ErrBadDigest, err)
+		}
+		if s3fs.IsPermission(err) || s3fs.IsNotExist(err) {
+			t.Errorf("did not expect a permission or not exist error; got %v", err)
+		}
+		if n := cl.count("PutObject"); n != 1 {
+			t.Errorf("want 1 PutObject; got %d", n)
+		}
+	})
+
+	t.Run("retried once", func(t *testing.T) {
+		cl := newClient(1)
+		fsys := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond))
+		if err := fsys.WriteFile("file.txt", []byte("content")); err != nil {
+			t.Fatal(err)
+		}
+
+		o, ok := cl.get("file.txt")
+		if !ok || string(o.data) != "content" {
+			t.Error("expected the retried upload to store the content")
+		}
+		if n := cl.count("PutObject"); n != 2 {
+			t.Errorf("want 2 PutObjects; got %d", n)
+		}
+
+		cl = newClient(2)
+		err := s3fs.New(cl, "test", s3fs.WithRetry(3, time.Millisecond)).WriteFile("file.txt", nil)
+		if !errors.Is(err, s3fs.ErrBadDigest) {
+			t.Errorf("want %v; got %v", s3fs.ErrBadDigest, err)
+		}
+		if n := cl.count("PutObject"); n != 2 {
+			t.Errorf("want 2 PutObjects; got %d", n)
+		}
+	})
+}
+
+func TestWriteInterceptor(t *testing.T) {
+	errInvalidJSON := errors.New("invalid JSON")
+
+	cl := newMemClient()
+	fsys := s3fs.New(cl, "test", s3fs.WithWriteInterceptor(func(name string, data []byte) ([]byte, error) {
+		if strings.HasSuffix(name, ".json") {
+			if !json.Valid(data) {
+				return nil, errInvalidJSON
+			}
+			return data, nil
+		}
+		return append([]byte("header\n"), data...), nil
+	}))
+
+	if err := fsys.WriteFile("bad.json", []byte("{")); !errors.Is(err, errInvalidJSON) {
+		t.Errorf("want %v; got %v", errInvalidJSON, err)
+	}
+	if _, ok := cl.get("bad.json"); ok {
+		t.Error("expected bad.json not to be written")
+	}
+	if n := cl.count("PutObject"); n != 0 {
+		t.Errorf("want no PutObject; got %d", n)
+	}
+
+	if err := fsys.WriteFile("good.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := fsys.Create("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "con")
+	io.WriteString(w, "tent")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"good.json": "{}", "file.txt": "header\ncontent"} {
+		o, ok := cl.get(name)
+		if !ok {
+			t.Errorf("expected %s to be written", name)
+			continue
+		}
+		if string(o.data) != want {
+			t.Errorf("%s: want %q; got %q", name, want, o.data)
+		}
+	}
+
+	t.Run("compressed", func(t *testing.T) {
+		cl := newMemClient()
+		fsys := s3fs.New(cl, "test", s3fs.WithCompressOnWrite(gzip.DefaultCompression),
+			s3fs.WithWriteInterceptor(func(name string, data []byte) ([]byte, error) {
+				return bytes.ToUpper(data), nil
+			}))
+
+		if err := fsys.WriteFile("file.txt", []byte("content")); err != nil {
+			t.Fatal(err)
+		}
+
+		o, _ := cl.get("file.txt")
+		if got := o.metadata["s3fs-uncompressed-size"]; got != "7" {
+			t.Errorf("want uncompressed size 7; got %s", got)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(o.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(zr)
+		if string(data) != "CONTENT" {
+			t.Errorf("want %q; got %q", "CONTENT", data)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package s3fs
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+)
+
+// OpenZip opens the zip archive stored in the object name. The size is taken
+// from a HeadObject and the archive is then read with ranged GetObjects, so
+// only the central directory and the entries that are opened are
+// downloaded. The ranges are cached and aligned if WithRangeCache and
+// WithPartAlignedReads are used.
+//
+// The returned func releases the archive and should be called once the
+// zip.Reader is no longer used.
+func (f *S3FS) OpenZip(name string) (*zip.Reader, func() error, error) {
+	head, err := f.headObject(context.TODO(), name)
+	if err != nil {
+		return nil, nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+
+	fi := headFileInfo(name, head)
+
+	zf := &file{
+		fsys:       f,
+		name:       name,
+		ReadCloser: io.NopCloser(eofReader{}),
+		stat:       func() (fs.FileInfo, error) { return fi, nil },
+		eTag:       fi.eTag,
+		rangeCache: f.rangeCache,
+	}
+	if f.partSize > 0 {
+		zf.window = &partWindow{}
+	}
+
+	zr, err := zip.NewReader(objectReaderAt{zf}, fi.size)
+	if err != nil {
+		return nil, nil, &fs.PathError{
+			Op:   "open",
+			Path: name,
+			Err:  err,
+		}
+	}
+	return zr, zf.Close, nil
+}
+
+// objectReaderAt reads a file with independent ranged requests. Unlike
+// file.ReadAt it never moves the file's offset, so it is safe for
+// concurrent use.
+type objectReaderAt struct{ f *file }
+
+func (r objectReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	return r.f.readAt(p, offset)
+}
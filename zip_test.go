@@ -0,0 +1,66 @@
+package s3fs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/matthewp/s3fs"
+)
+
+func TestOpenZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"a.txt":     "hello",
+		"dir/b.txt": "world",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := newMemClient()
+	cl.put("data.zip", buf.Bytes())
+
+	fsys := s3fs.New(cl, "test")
+	zr, done, err := fsys.OpenZip("data.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	if len(zr.File) != 2 {
+		t.Fatalf("want 2 entries; got %d", len(zr.File))
+	}
+
+	data, err := fs.ReadFile(zr, "dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "world" {
+		t.Errorf("want %q; got %q", "world", data)
+	}
+
+	for _, in := range cl.getInputs() {
+		if in.Range == nil {
+			t.Error("expected only ranged GetObjects")
+		}
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		if _, _, err := fsys.OpenZip("missing.zip"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("want %v; got %v", fs.ErrNotExist, err)
+		}
+	})
+}